@@ -0,0 +1,6 @@
+package adaptor
+
+import "errors"
+
+// ErrSecretRange is returned when a signing key is zero or not below N.
+var ErrSecretRange = errors.New("Secret out of range\n")
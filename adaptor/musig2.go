@@ -0,0 +1,282 @@
+package adaptor
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	vc "volley/curve"
+)
+
+// MuSig2 for the Schnorr signatures of this package. n signers with keys
+// P_i jointly sign for the aggregate key X = sum a_i*P_i in two rounds:
+//
+//  1. Every signer runs NonceGen and sends its PublicNonce (R_i1, R_i2).
+//  2. With the aggregate nonce (R_1, R_2), every signer computes
+//     R = R_1 + b*R_2 (+ Y for a pre-signature), e = H(msg || R.x) and the
+//     partial signature s_i = k_i1 + b*k_i2 - e*a_i*x_i.
+//
+// The sum of the s_i is a signature (e, s) under X, checked by SchnorrVerify,
+// or with an adaptor point Y a pre-signature checked by
+// SchnorrPreVerifyAdaptor and completed by adding the witness y to s.
+
+// ErrNonceReused is returned when a SecretNonce is used a second time, which
+// would leak the signing key.
+var ErrNonceReused = errors.New("Secret nonce already used\n")
+
+// hashToScalar hashes tag and the parts to an integer modulo N.
+func hashToScalar(h hash.Hash, tag string, parts ...[]byte) *big.Int {
+	h.Reset()
+	h.Write([]byte(tag))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	k := new(big.Int).SetBytes(h.Sum(nil))
+	return k.Mod(k, fastCurve.Params().N)
+}
+
+func encodePoint(p vc.FastPoint) []byte {
+	x, y := p.Back()
+	data := make([]byte, 2*bnLength)
+	x.FillBytes(data[:bnLength])
+	y.FillBytes(data[bnLength:])
+	return data
+}
+
+// KeyAggContext holds the keys of the signers, in a fixed order, and their
+// aggregate.
+type KeyAggContext struct {
+	Keys      []vc.FastPoint
+	Aggregate vc.FastPoint
+	coefs     []*big.Int
+}
+
+// AggregateKeys returns the aggregate of keys, which must be distinct. All
+// signers have to pass the keys in the same order.
+func AggregateKeys(keys []vc.FastPoint, h hash.Hash) (*KeyAggContext, error) {
+	if len(keys) < 2 {
+		return nil, fmt.Errorf("At least two keys are needed\n")
+	}
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		if key.IsZero() {
+			return nil, fmt.Errorf("Key %d is infinity\n", i)
+		}
+		encoded[i] = encodePoint(key)
+		for j := 0; j < i; j++ {
+			if keys[j].Equal(key) {
+				return nil, fmt.Errorf("Key %d repeats key %d\n", i, j)
+			}
+		}
+	}
+	var list []byte
+	for _, e := range encoded {
+		list = append(list, e...)
+	}
+	listHash := hashToScalar(h, "MuSig/KeyAgg list", list).Bytes()
+
+	ctx := &KeyAggContext{
+		Keys:      keys,
+		Aggregate: fastCurve.NewPoint(),
+		coefs:     make([]*big.Int, len(keys)),
+	}
+	tmp := fastCurve.NewPoint()
+	for i, key := range keys {
+		ctx.coefs[i] = hashToScalar(h, "MuSig/KeyAgg coefficient", listHash, encoded[i])
+		fastCurve.FastScalarMult(tmp, key, ctx.coefs[i].Bytes())
+		fastCurve.FastPointAdd(ctx.Aggregate, ctx.Aggregate, tmp)
+	}
+	if ctx.Aggregate.IsZero() {
+		return nil, fmt.Errorf("Aggregate key is infinity\n")
+	}
+	return ctx, nil
+}
+
+// index returns the position of public among the keys.
+func (ctx *KeyAggContext) index(public vc.FastPoint) (int, error) {
+	for i, key := range ctx.Keys {
+		if key.Equal(public) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Key is not one of the signers\n")
+}
+
+// PublicNonce is the first round message of a signer, and the sum of them
+// all once aggregated.
+type PublicNonce struct {
+	R1, R2 vc.FastPoint
+}
+
+// SecretNonce holds the nonces behind a PublicNonce. It is wiped when used
+// and must never be stored or copied.
+type SecretNonce struct {
+	k1, k2 *big.Int
+	public vc.FastPoint
+}
+
+// NonceGen draws the nonces of the signer with the given key for one
+// signing session. random must be a good source, MuSig2 nonces can't be
+// derived deterministically; the key and msg are mixed in as a safeguard.
+func NonceGen(secret *big.Int, public vc.FastPoint, msg []byte, random io.Reader, h hash.Hash) (*SecretNonce,
+	*PublicNonce, error) {
+	N := fastCurve.Params().N
+	if secret.Sign() <= 0 || secret.Cmp(N) >= 0 {
+		return nil, nil, ErrSecretRange
+	}
+	if random == nil {
+		return nil, nil, fmt.Errorf("MuSig2 nonces need a random source\n")
+	}
+	seed := make([]byte, 32)
+	if _, err := io.ReadFull(random, seed); err != nil {
+		return nil, nil, err
+	}
+	key := make([]byte, bnLength)
+	secret.FillBytes(key)
+
+	sec := &SecretNonce{public: public}
+	sec.k1 = hashToScalar(h, "MuSig/nonce", seed, key, encodePoint(public), msg, []byte{0})
+	sec.k2 = hashToScalar(h, "MuSig/nonce", seed, key, encodePoint(public), msg, []byte{1})
+	if sec.k1.Sign() == 0 || sec.k2.Sign() == 0 {
+		return nil, nil, fmt.Errorf("Zero nonce\n")
+	}
+	pub := &PublicNonce{
+		R1: fastCurve.FastBaseScalarSecret(sec.k1.Bytes()),
+		R2: fastCurve.FastBaseScalarSecret(sec.k2.Bytes()),
+	}
+	return sec, pub, nil
+}
+
+// AggregateNonces sums the public nonces of all signers.
+func AggregateNonces(nonces []*PublicNonce) (*PublicNonce, error) {
+	agg := &PublicNonce{R1: fastCurve.NewPoint(), R2: fastCurve.NewPoint()}
+	for _, n := range nonces {
+		fastCurve.FastPointAdd(agg.R1, agg.R1, n.R1)
+		fastCurve.FastPointAdd(agg.R2, agg.R2, n.R2)
+	}
+	if agg.R1.IsZero() || agg.R2.IsZero() {
+		return nil, fmt.Errorf("Aggregate nonce is infinity\n")
+	}
+	return agg, nil
+}
+
+// Session is the second round of signing msg under ctx. It is a
+// pre-signature session when an adaptor point Y is given.
+type Session struct {
+	ctx   *KeyAggContext
+	nonce *PublicNonce
+	b     *big.Int
+	e     *big.Int
+}
+
+// NewSession computes the nonce coefficient b, the final nonce R and the
+// challenge e. y is the adaptor point, or nil for a plain signature.
+func NewSession(ctx *KeyAggContext, aggNonce *PublicNonce, msg []byte, y vc.FastPoint, h hash.Hash) (*Session,
+	error) {
+	parts := [][]byte{encodePoint(ctx.Aggregate), encodePoint(aggNonce.R1), encodePoint(aggNonce.R2)}
+	if y != nil {
+		parts = append(parts, encodePoint(y))
+	}
+	parts = append(parts, msg)
+	b := hashToScalar(h, "MuSig/noncecoef", parts...)
+
+	r := fastCurve.NewPoint()
+	fastCurve.FastScalarMult(r, aggNonce.R2, b.Bytes())
+	fastCurve.FastPointAdd(r, r, aggNonce.R1)
+	if y != nil {
+		fastCurve.FastPointAdd(r, r, y)
+	}
+	if r.IsZero() {
+		return nil, fmt.Errorf("Final nonce is infinity\n")
+	}
+	return &Session{
+		ctx:   ctx,
+		nonce: aggNonce,
+		b:     b,
+		e:     challenge(msg, r, h),
+	}, nil
+}
+
+// Sign returns the partial signature of the signer of secNonce, whose key
+// is secret. secNonce is wiped and can't be used again.
+func (s *Session) Sign(secNonce *SecretNonce, secret *big.Int) (*big.Int, error) {
+	if secNonce.k1 == nil {
+		return nil, ErrNonceReused
+	}
+	k1, k2 := secNonce.k1, secNonce.k2
+	secNonce.k1, secNonce.k2 = nil, nil
+
+	N := fastCurve.Params().N
+	if secret.Sign() <= 0 || secret.Cmp(N) >= 0 {
+		return nil, ErrSecretRange
+	}
+	i, err := s.ctx.index(secNonce.public)
+	if err != nil {
+		return nil, err
+	}
+	if !fastCurve.FastBaseScalarSecret(secret.Bytes()).Equal(secNonce.public) {
+		return nil, fmt.Errorf("Secret doesn't match the key of the nonce\n")
+	}
+
+	// k1 + b*k2 - e*a_i*x_i
+	partial := new(big.Int).Mul(s.b, k2)
+	partial.Add(partial, k1)
+	ex := new(big.Int).Mul(s.e, s.ctx.coefs[i])
+	ex.Mul(ex, secret)
+	partial.Sub(partial, ex)
+	return partial.Mod(partial, N), nil
+}
+
+// VerifyPartial checks the partial signature of the signer with key public
+// and first round message nonce, so a bad signer can be named.
+func (s *Session) VerifyPartial(partial *big.Int, nonce *PublicNonce, public vc.FastPoint) bool {
+	if partial.Sign() < 0 || partial.Cmp(fastCurve.Params().N) >= 0 {
+		return false
+	}
+	i, err := s.ctx.index(public)
+	if err != nil {
+		return false
+	}
+	// s_i*G + e*a_i*P_i == R_i1 + b*R_i2
+	ea := new(big.Int).Mul(s.e, s.ctx.coefs[i])
+	ea.Mod(ea, fastCurve.Params().N)
+	left := fastCurve.FastBaseScalar(partial.Bytes())
+	tmp := fastCurve.NewPoint()
+	fastCurve.FastScalarMult(tmp, public, ea.Bytes())
+	fastCurve.FastPointAdd(left, left, tmp)
+
+	right := fastCurve.NewPoint()
+	fastCurve.FastScalarMult(right, nonce.R2, s.b.Bytes())
+	fastCurve.FastPointAdd(right, right, nonce.R1)
+	return left.Equal(right)
+}
+
+// Aggregate sums the partial signatures. The result is a signature under
+// the aggregate key, or a pre-signature for the adaptor point of the
+// session.
+func (s *Session) Aggregate(partials []*big.Int) *Signature {
+	sum := new(big.Int)
+	for _, partial := range partials {
+		sum.Add(sum, partial)
+	}
+	return &Signature{
+		E: new(big.Int).Set(s.e),
+		S: sum.Mod(sum, fastCurve.Params().N),
+	}
+}
+
+// Adapt completes a pre-signature with the witness y of its adaptor point.
+func Adapt(pre *Signature, y *big.Int) *Signature {
+	s := new(big.Int).Add(pre.S, y)
+	return &Signature{
+		E: new(big.Int).Set(pre.E),
+		S: s.Mod(s, fastCurve.Params().N),
+	}
+}
+
+// Extract returns the witness y that turned pre into sig.
+func Extract(sig, pre *Signature) *big.Int {
+	y := new(big.Int).Sub(sig.S, pre.S)
+	return y.Mod(y, fastCurve.Params().N)
+}
@@ -0,0 +1,132 @@
+package adaptor_test
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"math/big"
+	"testing"
+	"volley/adaptor"
+	vc "volley/curve"
+	"volley/secp256k1"
+	"volley/sm2"
+	"volley/sm3"
+)
+
+type musigSigner struct {
+	secret   *big.Int
+	public   vc.FastPoint
+	secNonce *adaptor.SecretNonce
+	pubNonce *adaptor.PublicNonce
+}
+
+// musigRound runs both rounds for the signers and returns the aggregate
+// signature, a pre-signature if y is not nil.
+func musigRound(t *testing.T, signers []*musigSigner, ctx *adaptor.KeyAggContext, msg []byte, y vc.FastPoint,
+	newHash func() hash.Hash) *adaptor.Signature {
+	nonces := make([]*adaptor.PublicNonce, len(signers))
+	for i, s := range signers {
+		var err error
+		s.secNonce, s.pubNonce, err = adaptor.NonceGen(s.secret, s.public, msg, rand.Reader, newHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonces[i] = s.pubNonce
+	}
+	aggNonce, err := adaptor.AggregateNonces(nonces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := adaptor.NewSession(ctx, aggNonce, msg, y, newHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	partials := make([]*big.Int, len(signers))
+	for i, s := range signers {
+		partials[i], err = session.Sign(s.secNonce, s.secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !session.VerifyPartial(partials[i], s.pubNonce, s.public) {
+			t.Fatal("partial signature not verified")
+		}
+	}
+	if session.VerifyPartial(partials[0], signers[1].pubNonce, signers[1].public) {
+		t.Fatal("partial signature verified for the other signer")
+	}
+	if _, err = session.Sign(signers[0].secNonce, signers[0].secret); !errors.Is(err, adaptor.ErrNonceReused) {
+		t.Fatal("secret nonce used twice:", err)
+	}
+	return session.Aggregate(partials)
+}
+
+func TestMuSig2(t *testing.T) {
+	curves := []struct {
+		fastCurve vc.FastCurve
+		newHash   func() hash.Hash
+	}{
+		{secp256k1.FastCurve(), sha256.New},
+		{sm2.FastCurve(), sm3.New},
+	}
+	defer adaptor.SetCurve(secp256k1.FastCurve())
+	for _, c := range curves {
+		fastCurve := c.fastCurve
+		adaptor.SetCurve(fastCurve)
+		N := fastCurve.Params().N
+
+		signers := make([]*musigSigner, 2)
+		keys := make([]vc.FastPoint, 2)
+		for i := range signers {
+			secret, err := rand.Int(rand.Reader, N)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signers[i] = &musigSigner{secret: secret, public: fastCurve.FastBaseScalar(secret.Bytes())}
+			keys[i] = signers[i].public
+		}
+		ctx, err := adaptor.AggregateKeys(keys, c.newHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		swapped, err := adaptor.AggregateKeys([]vc.FastPoint{keys[1], keys[0]}, c.newHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swapped.Aggregate.Equal(ctx.Aggregate) {
+			t.Fatal("key order doesn't change the aggregate")
+		}
+		if _, err = adaptor.AggregateKeys([]vc.FastPoint{keys[0], keys[0]}, c.newHash()); err == nil {
+			t.Fatal("aggregated a repeated key")
+		}
+
+		msg := []byte("2-of-2 spend")
+		sig := musigRound(t, signers, ctx, msg, nil, c.newHash)
+		if !adaptor.SchnorrVerify(sig, msg, ctx.Aggregate, c.newHash()) {
+			t.Fatal("aggregate signature not verified")
+		}
+		if adaptor.SchnorrVerify(sig, msg, keys[0], c.newHash()) {
+			t.Fatal("aggregate signature verified under a single key")
+		}
+
+		witness, err := rand.Int(rand.Reader, N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		yPoint := fastCurve.FastBaseScalar(witness.Bytes())
+		pre := musigRound(t, signers, ctx, msg, yPoint, c.newHash)
+		if !adaptor.SchnorrPreVerifyAdaptor(pre, msg, yPoint, ctx.Aggregate, c.newHash()) {
+			t.Fatal("aggregate pre-signature not verified")
+		}
+		if adaptor.SchnorrVerify(pre, msg, ctx.Aggregate, c.newHash()) {
+			t.Fatal("pre-signature verified as a signature")
+		}
+		full := adaptor.Adapt(pre, witness)
+		if !adaptor.SchnorrVerify(full, msg, ctx.Aggregate, c.newHash()) {
+			t.Fatal("completed pre-signature not verified")
+		}
+		if adaptor.Extract(full, pre).Cmp(witness) != 0 {
+			t.Fatal("extracted the wrong witness")
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package adaptor
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
 	"hash"
 	"io"
 	"math/big"
 	vc "volley/curve"
+	"volley/rfc6979"
 )
 
 type Signature struct {
@@ -21,30 +22,19 @@ type Point struct {
 func SchnorrSignAdaptor(msg []byte, yPoint vc.FastPoint, secret *big.Int, h hash.Hash, random io.Reader) (*Signature,
 	error) {
 	N := fastCurve.Params().N
-	k, err := rand.Int(random, N)
+	if secret.Sign() <= 0 || secret.Cmp(N) >= 0 {
+		return nil, ErrSecretRange
+	}
+	k, err := nonce(msg, yPoint, secret, h, random)
 	if err != nil {
 		return nil, err
 	}
 
-	rPoint := fastCurve.FastBaseScalar(k.Bytes())
+	rPoint := fastCurve.FastBaseScalarSecret(k.Bytes())
 	//yPoint := fastCurve.NewPoint()
 	//yPoint.From(y.X, y.Y)
 	fastCurve.FastPointAdd(rPoint, rPoint, yPoint)
-	rx, _ := rPoint.Back()
-	data := make([]byte, len(msg)+bnLength)
-
-	copy(data, msg)
-	rx.FillBytes(data[len(msg):])
-	h.Reset()
-	h.Write(data)
-	digest := h.Sum(nil)
-
-	shiftSize := h.Size()*8 - fastCurve.Params().BitSize
-	e := new(big.Int).SetBytes(digest)
-	if shiftSize > 0 {
-		e.Rsh(e, uint(shiftSize))
-	}
-	e.Mod(e, N)
+	e := challenge(msg, rPoint, h)
 	s := new(big.Int).Mul(e, secret)
 	s.Mod(s, N)
 	s.Sub(N, s)
@@ -57,41 +47,56 @@ func SchnorrSignAdaptor(msg []byte, yPoint vc.FastPoint, secret *big.Int, h hash
 	}, nil
 }
 
-func SchnorrPreVerifyAdaptor(sig *Signature, msg []byte, y vc.FastPoint, public vc.FastPoint, h hash.Hash) bool {
-	N := fastCurve.Params().N
-	if sig.S.Sign() == 0 {
-		return false
-	}
-	if sig.E.Cmp(N) >= 0 || sig.S.Cmp(N) >= 0 {
-		return false
+// nonce derives k as in RFC 6979 with HMAC-SHA256, from the secret and the
+// digest of msg and Y, so signing one message against two different Y never
+// reuses k. Unless random is nil, 32 bytes read from it are mixed in as
+// additional data.
+func nonce(msg []byte, yPoint vc.FastPoint, secret *big.Int, h hash.Hash, random io.Reader) (*big.Int, error) {
+	var extra []byte
+	if random != nil {
+		extra = make([]byte, 32)
+		if _, err := io.ReadFull(random, extra); err != nil {
+			return nil, err
+		}
 	}
-	rPoint := fastCurve.FastBaseScalar(sig.S.Bytes())
-	tmp := fastCurve.NewPoint()
-	//tmp.From(public.X, public.Y)
-	fastCurve.FastScalarMult(tmp, public, sig.E.Bytes())
-	fastCurve.FastPointAdd(rPoint, rPoint, tmp)
-	//tmp.From(y.X, y.Y)
-	fastCurve.FastPointAdd(rPoint, rPoint, y)
-	rx, _ := rPoint.Back()
-
-	data := make([]byte, len(msg)+bnLength)
-
+	yx, yy := yPoint.Back()
+	data := make([]byte, len(msg)+2*bnLength)
 	copy(data, msg)
-	rx.FillBytes(data[len(msg):])
+	yx.FillBytes(data[len(msg) : len(msg)+bnLength])
+	yy.FillBytes(data[len(msg)+bnLength:])
 	h.Reset()
 	h.Write(data)
-	digest := h.Sum(nil)
+	return rfc6979.Nonce(sha256.New, fastCurve.Params().N, secret, h.Sum(nil), extra), nil
+}
 
-	shiftSize := h.Size()*8 - fastCurve.Params().BitSize
-	e := new(big.Int).SetBytes(digest)
-	if shiftSize > 0 {
-		e.Rsh(e, uint(shiftSize))
-	}
-	e.Mod(e, N)
-	return e.Cmp(sig.E) == 0
+func SchnorrPreVerifyAdaptor(sig *Signature, msg []byte, y vc.FastPoint, public vc.FastPoint, h hash.Hash) bool {
+	return verify(sig, msg, y, func(r vc.FastPoint, e []byte) {
+		fastCurve.FastScalarMult(r, public, e)
+	}, h)
+}
+
+// SchnorrPreVerifyAdaptorPrecomputed is SchnorrPreVerifyAdaptor with the public
+// key given by its precomputed tables.
+func SchnorrPreVerifyAdaptorPrecomputed(sig *Signature, msg []byte, y vc.FastPoint, public vc.Precomputed,
+	h hash.Hash) bool {
+	return verify(sig, msg, y, public.ScalarMult, h)
 }
 
 func SchnorrVerify(sig *Signature, msg []byte, public vc.FastPoint, h hash.Hash) bool {
+	return verify(sig, msg, nil, func(r vc.FastPoint, e []byte) {
+		fastCurve.FastScalarMult(r, public, e)
+	}, h)
+}
+
+// SchnorrVerifyPrecomputed is SchnorrVerify with the public key given by its
+// precomputed tables.
+func SchnorrVerifyPrecomputed(sig *Signature, msg []byte, public vc.Precomputed, h hash.Hash) bool {
+	return verify(sig, msg, nil, public.ScalarMult, h)
+}
+
+// verify checks that hashing msg with the x coordinate of s*G + e*P + Y gives
+// e back. publicMult computes e*P, and y is nil for plain signatures.
+func verify(sig *Signature, msg []byte, y vc.FastPoint, publicMult func(vc.FastPoint, []byte), h hash.Hash) bool {
 	N := fastCurve.Params().N
 	if sig.S.Sign() == 0 {
 		return false
@@ -101,14 +106,19 @@ func SchnorrVerify(sig *Signature, msg []byte, public vc.FastPoint, h hash.Hash)
 	}
 	rPoint := fastCurve.FastBaseScalar(sig.S.Bytes())
 	tmp := fastCurve.NewPoint()
-	//tmp.From(public.X, public.Y)
-	fastCurve.FastScalarMult(tmp, public, sig.E.Bytes())
+	publicMult(tmp, sig.E.Bytes())
 	fastCurve.FastPointAdd(rPoint, rPoint, tmp)
+	if y != nil {
+		fastCurve.FastPointAdd(rPoint, rPoint, y)
+	}
+	return challenge(msg, rPoint, h).Cmp(sig.E) == 0
+}
 
+// challenge hashes msg and the x coordinate of R to e, keeping the leftmost
+// bits of the digest as in FIPS 186-4.
+func challenge(msg []byte, rPoint vc.FastPoint, h hash.Hash) *big.Int {
 	rx, _ := rPoint.Back()
-
 	data := make([]byte, len(msg)+bnLength)
-
 	copy(data, msg)
 	rx.FillBytes(data[len(msg):])
 	h.Reset()
@@ -120,6 +130,5 @@ func SchnorrVerify(sig *Signature, msg []byte, public vc.FastPoint, h hash.Hash)
 	if shiftSize > 0 {
 		e.Rsh(e, uint(shiftSize))
 	}
-	e.Mod(e, N)
-	return e.Cmp(sig.E) == 0
+	return e.Mod(e, fastCurve.Params().N)
 }
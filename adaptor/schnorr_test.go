@@ -3,9 +3,12 @@ package adaptor_test
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"math/big"
 	"testing"
 	"volley/adaptor"
 	"volley/secp256k1"
+	"volley/sm2"
+	"volley/sm3"
 )
 
 func TestAdaptorSig(t *testing.T) {
@@ -43,3 +46,84 @@ func TestAdaptorSig(t *testing.T) {
 		}
 	}
 }
+
+func TestAdaptorSigSM2(t *testing.T) {
+	fastCurve := sm2.FastCurve()
+	adaptor.SetCurve(fastCurve)
+	defer adaptor.SetCurve(secp256k1.FastCurve())
+
+	for i := 0; i < 1000; i++ {
+		msg := make([]byte, 61)
+		rand.Read(msg)
+
+		secret, err := rand.Int(rand.Reader, fastCurve.Params().N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public := fastCurve.FastBaseScalar(secret.Bytes())
+		d, err := rand.Int(rand.Reader, fastCurve.Params().N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		yPoint := fastCurve.FastBaseScalar(d.Bytes())
+
+		sig, err := adaptor.SchnorrSignAdaptor(msg, yPoint, secret, sm3.New(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !adaptor.SchnorrPreVerifyAdaptor(sig, msg, yPoint, public, sm3.New()) {
+			t.Fatal("Not verified")
+		}
+		msg[0] ^= 1
+		if adaptor.SchnorrPreVerifyAdaptor(sig, msg, yPoint, public, sm3.New()) {
+			t.Fatal("Verified a different message")
+		}
+	}
+}
+
+func TestAdaptorSigDeterministic(t *testing.T) {
+	adaptor.SetCurve(secp256k1.FastCurve())
+	fastCurve := secp256k1.FastCurve()
+	msg := []byte("transaction")
+	secret := big.NewInt(7)
+	public := fastCurve.FastBaseScalar(secret.Bytes())
+	y1 := fastCurve.FastBaseScalar([]byte{1})
+	y2 := fastCurve.FastBaseScalar([]byte{2})
+
+	sig1, err := adaptor.SchnorrSignAdaptor(msg, y1, secret, sha256.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := adaptor.SchnorrSignAdaptor(msg, y1, secret, sha256.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig1.E.Cmp(again.E) != 0 || sig1.S.Cmp(again.S) != 0 {
+		t.Fatal("Signatures without randomness differ")
+	}
+	if !adaptor.SchnorrPreVerifyAdaptor(sig1, msg, y1, public, sha256.New()) {
+		t.Fatal("Not verified")
+	}
+
+	// a nonce shared between two Y would reveal the secret from s1 - s2
+	sig2, err := adaptor.SchnorrSignAdaptor(msg, y2, secret, sha256.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	N := fastCurve.Params().N
+	k1 := new(big.Int).Mul(sig1.E, secret)
+	k1.Add(k1, sig1.S).Mod(k1, N)
+	k2 := new(big.Int).Mul(sig2.E, secret)
+	k2.Add(k2, sig2.S).Mod(k2, N)
+	if k1.Cmp(k2) == 0 {
+		t.Fatal("Nonce reused for another Y")
+	}
+
+	hedged, err := adaptor.SchnorrSignAdaptor(msg, y1, secret, sha256.New(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hedged.S.Cmp(sig1.S) == 0 || !adaptor.SchnorrPreVerifyAdaptor(hedged, msg, y1, public, sha256.New()) {
+		t.Fatal("Bad hedged signature")
+	}
+}
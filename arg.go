@@ -9,8 +9,10 @@ import (
 
 var exitError error = fmt.Errorf("Exit\n")
 
-func ParseArgument(args []string) (setup bool, steps []bool, threadNum, index int, err error) {
+func ParseArgument(args []string) (setup bool, steps []bool, threadNum, index int, mmap, cachePrecomputed bool, curveName string,
+	passphrase, keydPath, serveKeyd string, err error) {
 	steps = make([]bool, 6)
+	curveName = "secp256k1"
 	stepSet := false
 	threadNum = 0
 	index = 0
@@ -20,6 +22,38 @@ func ParseArgument(args []string) (setup bool, steps []bool, threadNum, index in
 			switch os.Args[i] {
 			case "--setup":
 				setup = true
+			case "--mmap":
+				mmap = true
+			case "--cache-precomputed":
+				cachePrecomputed = true
+			case "--curve":
+				if len(os.Args) < i+2 || (args[i+1] != "secp256k1" && args[i+1] != "sm2") {
+					fmt.Println("Invalid curve for --curve, expecting secp256k1 or sm2")
+					err = exitError
+					return
+				}
+				i++
+				curveName = args[i]
+			case "--passphrase":
+				if len(os.Args) < i+2 {
+					fmt.Println("Missing source for --passphrase, expecting prompt, env:NAME or file:PATH")
+					err = exitError
+					return
+				}
+				i++
+				passphrase = args[i]
+			case "--keyd", "--serve-keyd":
+				if len(os.Args) < i+2 {
+					fmt.Printf("Missing socket path for %s\n", args[i])
+					err = exitError
+					return
+				}
+				if args[i] == "--keyd" {
+					keydPath = args[i+1]
+				} else {
+					serveKeyd = args[i+1]
+				}
+				i++
 			case "--index":
 				if len(os.Args) < i+2 {
 					fmt.Println("Invalid number for --index")
@@ -99,6 +133,11 @@ func ParseArgument(args []string) (setup bool, steps []bool, threadNum, index in
 			steps[i] = true
 		}
 	}
+	if serveKeyd != "" && (setup || stepSet || keydPath != "") {
+		fmt.Println("--serve-keyd runs on its own")
+		err = exitError
+		return
+	}
 	if threadNum == 0 {
 		threadNum = 4
 	}
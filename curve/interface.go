@@ -11,15 +11,35 @@ type FastPoint interface {
 	CopyFrom(FastPoint)
 	IsZero() bool
 	Neg()
+	Equal(FastPoint) bool
+	Double()
+	Sub(FastPoint)
+	SetZero()
+	MarshalCompressed() []byte
+	UnmarshalCompressed([]byte) error
 	GenTable(bool)
 	ExportTable(bool) []byte
 	ImportTable([]byte, bool)
+	MapTable([]byte, bool)
 }
 
+// FastBn is an element of the scalar field. The arithmetic methods set the
+// receiver to the result and return it, like big.Int; operands may alias it.
 type FastBn interface {
 	Back() []byte
 	From(*big.Int)
 	CopyFrom(FastBn)
+	Big() *big.Int
+	SetInt64(int64) FastBn
+	SetBytes([]byte) FastBn
+	Add(FastBn, FastBn) FastBn
+	Sub(FastBn, FastBn) FastBn
+	Neg(FastBn) FastBn
+	Mul(FastBn, FastBn) FastBn
+	Square(FastBn) FastBn
+	Inverse(FastBn) FastBn
+	IsZero() bool
+	Equal(FastBn) bool
 }
 
 type FastCurve interface {
@@ -29,8 +49,32 @@ type FastCurve interface {
 	FastPointAdd(FastPoint, FastPoint, FastPoint)
 	FastBaseScalar([]byte) FastPoint
 	FastScalarMult(FastPoint, FastPoint, []byte)
+	// The Secret variants run in constant time with respect to the scalar
+	// and must be used for keys, nonces and witnesses.
+	FastBaseScalarSecret([]byte) FastPoint
+	FastScalarMultSecret(FastPoint, FastPoint, []byte)
 	FastPolynomial(FastPoint, []FastPoint, [][]byte)
 	Inverse(*big.Int) *big.Int
 	FastOrderMul(FastBn, FastBn, FastBn)
+	BatchInverse([]FastBn)
 	FasterPolynomial(FastPoint, []FastPoint, [][]byte, bool)
+	MultiScalarMult(FastPoint, []FastPoint, [][]byte)
+	BatchNormalize([]FastPoint)
+	BatchBack([]FastPoint) ([]*big.Int, []*big.Int)
+}
+
+// Precomputed is a fixed point with tables that make multiplying it by
+// arbitrary scalars cheaper, typically a long-lived public key. Backends that
+// can build them implement Precomputer.
+type Precomputed interface {
+	Point() FastPoint
+	ScalarMult(FastPoint, []byte)
+	MarshalBinary() ([]byte, error)
+}
+
+// Precomputer builds the tables of a point. UnmarshalPrecomputed must check
+// every entry of the tables it decodes, they may come from untrusted files.
+type Precomputer interface {
+	NewPrecomputed(p FastPoint, window int) (Precomputed, error)
+	UnmarshalPrecomputed([]byte) (Precomputed, error)
 }
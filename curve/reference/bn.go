@@ -0,0 +1,83 @@
+package reference
+
+import (
+	"math/big"
+	vc "volley/curve"
+)
+
+// Bn is an element of the scalar field, always kept reduced modulo N.
+type Bn struct {
+	c *curve
+	n *big.Int
+}
+
+func (bn *Bn) reduce() vc.FastBn {
+	bn.n.Mod(bn.n, bn.c.Params().N)
+	return bn
+}
+
+func (bn *Bn) Back() []byte {
+	data := make([]byte, bn.c.orderLen)
+	return bn.n.FillBytes(data)
+}
+
+func (bn *Bn) From(n *big.Int) {
+	bn.n = new(big.Int).Set(n)
+	bn.reduce()
+}
+
+func (bn *Bn) CopyFrom(b vc.FastBn) {
+	bn.n = new(big.Int).Set(b.(*Bn).n)
+}
+
+func (bn *Bn) Big() *big.Int {
+	return new(big.Int).Set(bn.n)
+}
+
+func (bn *Bn) SetInt64(v int64) vc.FastBn {
+	bn.n = big.NewInt(v)
+	return bn.reduce()
+}
+
+func (bn *Bn) SetBytes(data []byte) vc.FastBn {
+	bn.n = new(big.Int).SetBytes(data)
+	return bn.reduce()
+}
+
+func (bn *Bn) Add(a, b vc.FastBn) vc.FastBn {
+	bn.n = new(big.Int).Add(a.(*Bn).n, b.(*Bn).n)
+	return bn.reduce()
+}
+
+func (bn *Bn) Sub(a, b vc.FastBn) vc.FastBn {
+	bn.n = new(big.Int).Sub(a.(*Bn).n, b.(*Bn).n)
+	return bn.reduce()
+}
+
+func (bn *Bn) Neg(a vc.FastBn) vc.FastBn {
+	bn.n = new(big.Int).Neg(a.(*Bn).n)
+	return bn.reduce()
+}
+
+func (bn *Bn) Mul(a, b vc.FastBn) vc.FastBn {
+	bn.n = new(big.Int).Mul(a.(*Bn).n, b.(*Bn).n)
+	return bn.reduce()
+}
+
+func (bn *Bn) Square(a vc.FastBn) vc.FastBn {
+	return bn.Mul(a, a)
+}
+
+// Inverse sets bn to a^-1 mod N. The inverse of zero is zero.
+func (bn *Bn) Inverse(a vc.FastBn) vc.FastBn {
+	bn.n = bn.c.Inverse(a.(*Bn).n)
+	return bn
+}
+
+func (bn *Bn) IsZero() bool {
+	return bn.n.Sign() == 0
+}
+
+func (bn *Bn) Equal(b vc.FastBn) bool {
+	return bn.n.Cmp(b.(*Bn).n) == 0
+}
@@ -0,0 +1,129 @@
+package reference
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// koblitz is y^2 = x^3 + b over math/big in affine coordinates. The generic
+// CurveParams methods of crypto/elliptic assume a = -3, so secp256k1 needs
+// its own formulas. The point at infinity is (0, 0) as in crypto/elliptic.
+type koblitz struct {
+	params *elliptic.CurveParams
+}
+
+var secp256k1Params = &elliptic.CurveParams{
+	Name:    "secp256k1",
+	BitSize: 256,
+}
+
+func init() {
+	secp256k1Params.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	secp256k1Params.N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+	secp256k1Params.B = big.NewInt(7)
+	secp256k1Params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	secp256k1Params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+}
+
+// Secp256k1 returns a math/big implementation of secp256k1 as an
+// elliptic.Curve.
+func Secp256k1() elliptic.Curve {
+	return &koblitz{params: secp256k1Params}
+}
+
+func (c *koblitz) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+func (c *koblitz) IsOnCurve(x, y *big.Int) bool {
+	P := c.params.P
+	if x.Sign() < 0 || x.Cmp(P) >= 0 || y.Sign() < 0 || y.Cmp(P) >= 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Mod(lhs, P)
+	return lhs.Cmp(c.rhs(x)) == 0
+}
+
+// rhs returns x^3 + b mod P.
+func (c *koblitz) rhs(x *big.Int) *big.Int {
+	r := new(big.Int).Mul(x, x)
+	r.Mul(r, x)
+	r.Add(r, c.params.B)
+	return r.Mod(r, c.params.P)
+}
+
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+func (c *koblitz) Add(x1, y1, x2, y2 *big.Int) (x, y *big.Int) {
+	P := c.params.P
+	if isInfinity(x1, y1) {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2)
+	}
+	if isInfinity(x2, y2) {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1)
+	}
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return c.Double(x1, y1)
+		}
+		return new(big.Int), new(big.Int)
+	}
+	// lambda = (y2 - y1) / (x2 - x1)
+	num := new(big.Int).Sub(y2, y1)
+	den := new(big.Int).Sub(x2, x1)
+	den.Mod(den, P)
+	den.ModInverse(den, P)
+	lambda := num.Mul(num, den)
+	lambda.Mod(lambda, P)
+	return c.finish(lambda, x1, y1, x2)
+}
+
+func (c *koblitz) Double(x1, y1 *big.Int) (x, y *big.Int) {
+	P := c.params.P
+	if isInfinity(x1, y1) || y1.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	// lambda = 3x^2 / 2y
+	num := new(big.Int).Mul(x1, x1)
+	num.Mul(num, big.NewInt(3))
+	den := new(big.Int).Lsh(y1, 1)
+	den.ModInverse(den, P)
+	lambda := num.Mul(num, den)
+	lambda.Mod(lambda, P)
+	return c.finish(lambda, x1, y1, x1)
+}
+
+// finish returns x3 = lambda^2 - x1 - x2, y3 = lambda(x1 - x3) - y1.
+func (c *koblitz) finish(lambda, x1, y1, x2 *big.Int) (x, y *big.Int) {
+	P := c.params.P
+	x = new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, x1)
+	x.Sub(x, x2)
+	x.Mod(x, P)
+	y = new(big.Int).Sub(x1, x)
+	y.Mul(y, lambda)
+	y.Sub(y, y1)
+	y.Mod(y, P)
+	return x, y
+}
+
+// ScalarMult is plain double-and-add from the most significant bit.
+func (c *koblitz) ScalarMult(x1, y1 *big.Int, k []byte) (x, y *big.Int) {
+	x, y = new(big.Int), new(big.Int)
+	for _, b := range k {
+		for i := 7; i >= 0; i-- {
+			x, y = c.Double(x, y)
+			if b>>uint(i)&1 == 1 {
+				x, y = c.Add(x, y, x1, y1)
+			}
+		}
+	}
+	return x, y
+}
+
+func (c *koblitz) ScalarBaseMult(k []byte) (x, y *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
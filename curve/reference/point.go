@@ -0,0 +1,174 @@
+package reference
+
+import (
+	"fmt"
+	"math/big"
+	vc "volley/curve"
+)
+
+// Point is an affine point. Tables hold the odd multiples P, 3P, ..., 15P
+// like the secp256k1 backend, encoded as plain big-endian coordinates.
+type Point struct {
+	c     *curve
+	x, y  *big.Int
+	zero  bool
+	table []*Point
+}
+
+func (pt *Point) set(x, y *big.Int, zero bool) {
+	pt.zero = zero
+	if zero {
+		pt.x, pt.y = new(big.Int), new(big.Int)
+	} else {
+		pt.x, pt.y = new(big.Int).Set(x), new(big.Int).Set(y)
+	}
+	pt.table = nil
+}
+
+func (pt *Point) Back() (*big.Int, *big.Int) {
+	return new(big.Int).Set(pt.x), new(big.Int).Set(pt.y)
+}
+
+func (pt *Point) From(x, y *big.Int) {
+	pt.set(x, y, false)
+}
+
+func (pt *Point) CopyFrom(p vc.FastPoint) {
+	q := p.(*Point)
+	pt.set(q.x, q.y, q.zero)
+	pt.table = q.table
+}
+
+func (pt *Point) IsZero() bool {
+	return pt.zero
+}
+
+func (pt *Point) Neg() {
+	if !pt.zero {
+		pt.set(pt.x, new(big.Int).Sub(pt.c.Params().P, pt.y), false)
+	}
+	pt.table = nil
+}
+
+func (pt *Point) Equal(p vc.FastPoint) bool {
+	q := p.(*Point)
+	if pt.zero || q.zero {
+		return pt.zero == q.zero
+	}
+	return pt.x.Cmp(q.x) == 0 && pt.y.Cmp(q.y) == 0
+}
+
+func (pt *Point) Double() {
+	pt.c.FastPointAdd(pt, pt, pt)
+}
+
+func (pt *Point) Sub(p vc.FastPoint) {
+	neg := pt.c.NewPoint()
+	neg.CopyFrom(p)
+	neg.Neg()
+	pt.c.FastPointAdd(pt, pt, neg)
+}
+
+func (pt *Point) SetZero() {
+	pt.set(nil, nil, true)
+}
+
+// MarshalCompressed uses the SEC1 encoding, a single 0x00 for infinity.
+func (pt *Point) MarshalCompressed() []byte {
+	if pt.zero {
+		return []byte{0}
+	}
+	data := make([]byte, 1+pt.c.fieldLen)
+	data[0] = 0x02 | byte(pt.y.Bit(0))
+	pt.x.FillBytes(data[1:])
+	return data
+}
+
+func (pt *Point) UnmarshalCompressed(data []byte) error {
+	if len(data) == 1 && data[0] == 0 {
+		pt.SetZero()
+		return nil
+	}
+	if len(data) != 1+pt.c.fieldLen {
+		return fmt.Errorf("Invalid compressed point length %d\n", len(data))
+	}
+	if data[0] != 0x02 && data[0] != 0x03 {
+		return fmt.Errorf("Invalid compressed point prefix 0x%02x\n", data[0])
+	}
+	P := pt.c.Params().P
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(P) >= 0 {
+		return fmt.Errorf("Compressed point x not below P\n")
+	}
+	// y^2 = x^3 + ax + b
+	y := new(big.Int).Mul(x, x)
+	y.Mul(y, x)
+	y.Add(y, new(big.Int).Mul(pt.c.a, x))
+	y.Add(y, pt.c.Params().B)
+	y.Mod(y, P)
+	if y.ModSqrt(y, P) == nil {
+		return fmt.Errorf("Compressed point not on curve\n")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(P, y)
+	}
+	pt.set(x, y, false)
+	return nil
+}
+
+// GenTable computes the table; affine makes no difference here.
+func (pt *Point) GenTable(affine bool) {
+	table := make([]*Point, 8)
+	twice := pt.c.NewPoint().(*Point)
+	twice.CopyFrom(pt)
+	twice.Double()
+	table[0] = pt.c.NewPoint().(*Point)
+	table[0].set(pt.x, pt.y, pt.zero)
+	for i := 1; i < 8; i++ {
+		table[i] = pt.c.NewPoint().(*Point)
+		pt.c.FastPointAdd(table[i], table[i-1], twice)
+	}
+	pt.table = table
+}
+
+// ExportTable writes X || Y for every entry, followed by Z = 1 unless affine
+// is set, so the sizes match the secp256k1 backend.
+func (pt *Point) ExportTable(affine bool) []byte {
+	if pt.table == nil {
+		pt.GenTable(affine)
+	}
+	size := pt.entrySize(affine)
+	data := make([]byte, 8*size)
+	for i, p := range pt.table {
+		p.x.FillBytes(data[i*size : i*size+pt.c.fieldLen])
+		p.y.FillBytes(data[i*size+pt.c.fieldLen : i*size+2*pt.c.fieldLen])
+		if !affine {
+			data[(i+1)*size-1] = 1
+		}
+	}
+	return data
+}
+
+func (pt *Point) ImportTable(precomputes []byte, affine bool) {
+	size := pt.entrySize(affine)
+	table := make([]*Point, 8)
+	for i := range table {
+		entry := precomputes[i*size : (i+1)*size]
+		table[i] = pt.c.NewPoint().(*Point)
+		table[i].set(new(big.Int).SetBytes(entry[:pt.c.fieldLen]),
+			new(big.Int).SetBytes(entry[pt.c.fieldLen:2*pt.c.fieldLen]), false)
+	}
+	pt.table = table
+}
+
+// MapTable decodes the table right away, there is no lazy decoding here.
+func (pt *Point) MapTable(precomputes []byte, affine bool) {
+	pt.ImportTable(precomputes, affine)
+}
+
+func (pt *Point) entrySize(affine bool) int {
+	if affine {
+		return 2 * pt.c.fieldLen
+	}
+	return 3 * pt.c.fieldLen
+}
@@ -0,0 +1,170 @@
+// Package reference implements curve.FastCurve with plain math/big
+// arithmetic on top of any elliptic.Curve. It is slow and makes no attempt at
+// constant time; its only purpose is to be obviously correct, so that the
+// optimized backends and the protocol can be tested against it.
+package reference
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	vc "volley/curve"
+)
+
+type curve struct {
+	elliptic.Curve
+	// a is the coefficient of x in the curve equation, crypto/elliptic curves
+	// all use -3.
+	a        *big.Int
+	fieldLen int
+	orderLen int
+}
+
+// New wraps c. Curves other than the one returned by Secp256k1 are assumed
+// to follow crypto/elliptic and have a = -3.
+func New(c elliptic.Curve) vc.FastCurve {
+	params := c.Params()
+	a := big.NewInt(-3)
+	if _, ok := c.(*koblitz); ok {
+		a = big.NewInt(0)
+	}
+	return &curve{
+		Curve:    c,
+		a:        a,
+		fieldLen: (params.P.BitLen() + 7) / 8,
+		orderLen: (params.N.BitLen() + 7) / 8,
+	}
+}
+
+// reduce returns k mod N.
+func (c *curve) reduce(k []byte) *big.Int {
+	n := new(big.Int).SetBytes(k)
+	return n.Mod(n, c.Params().N)
+}
+
+func (c *curve) NewBn() vc.FastBn {
+	return &Bn{c: c, n: big.NewInt(1)}
+}
+
+func (c *curve) NewPoint() vc.FastPoint {
+	return &Point{c: c, x: new(big.Int), y: new(big.Int), zero: true}
+}
+
+func (c *curve) FastPointAdd(r, p1, p2 vc.FastPoint) {
+	a := p1.(*Point)
+	b := p2.(*Point)
+	res := r.(*Point)
+	switch {
+	case a.zero:
+		res.set(b.x, b.y, b.zero)
+	case b.zero:
+		res.set(a.x, a.y, a.zero)
+	default:
+		x, y := c.add(a.x, a.y, b.x, b.y)
+		res.set(x, y, isInfinity(x, y))
+	}
+}
+
+// add handles the cases crypto/elliptic leaves undefined for its affine
+// formulas: equal points and opposite points.
+func (c *curve) add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return c.Double(x1, y1)
+		}
+		return new(big.Int), new(big.Int)
+	}
+	return c.Add(x1, y1, x2, y2)
+}
+
+func (c *curve) scalarMult(p *Point, k []byte) *Point {
+	r := c.NewPoint().(*Point)
+	kk := c.reduce(k)
+	if p.zero || kk.Sign() == 0 {
+		return r
+	}
+	x, y := c.ScalarMult(p.x, p.y, kk.Bytes())
+	r.set(x, y, isInfinity(x, y))
+	return r
+}
+
+func (c *curve) generator() *Point {
+	r := c.NewPoint().(*Point)
+	r.set(c.Params().Gx, c.Params().Gy, false)
+	return r
+}
+
+func (c *curve) FastBaseScalar(k []byte) vc.FastPoint {
+	return c.scalarMult(c.generator(), k)
+}
+
+func (c *curve) FastScalarMult(r, p vc.FastPoint, k []byte) {
+	r.CopyFrom(c.scalarMult(p.(*Point), k))
+}
+
+// FastBaseScalarSecret is FastBaseScalar, nothing here runs in constant time.
+func (c *curve) FastBaseScalarSecret(k []byte) vc.FastPoint {
+	return c.FastBaseScalar(k)
+}
+
+// FastScalarMultSecret is FastScalarMult, nothing here runs in constant time.
+func (c *curve) FastScalarMultSecret(r, p vc.FastPoint, k []byte) {
+	c.FastScalarMult(r, p, k)
+}
+
+func (c *curve) FastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	sum := c.NewPoint()
+	for i := range scalarList {
+		c.FastPointAdd(sum, sum, c.scalarMult(pList[i].(*Point), scalarList[i]))
+	}
+	result.CopyFrom(sum)
+}
+
+// FasterPolynomial takes every point from its table when it has one, which
+// checks that tables survive export and import.
+func (c *curve) FasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte, affine bool) {
+	sum := c.NewPoint()
+	for i := range scalarList {
+		p := pList[i].(*Point)
+		if p.table != nil {
+			p = p.table[0]
+		}
+		c.FastPointAdd(sum, sum, c.scalarMult(p, scalarList[i]))
+	}
+	result.CopyFrom(sum)
+}
+
+func (c *curve) MultiScalarMult(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	c.FastPolynomial(result, pList, scalarList)
+}
+
+// Inverse returns k^-1 mod N, or zero if k has no inverse.
+func (c *curve) Inverse(k *big.Int) *big.Int {
+	r := new(big.Int).ModInverse(k, c.Params().N)
+	if r == nil {
+		return new(big.Int)
+	}
+	return r
+}
+
+func (c *curve) FastOrderMul(r, a, b vc.FastBn) {
+	r.Mul(a, b)
+}
+
+func (c *curve) BatchInverse(list []vc.FastBn) {
+	for _, bn := range list {
+		bn.Inverse(bn)
+	}
+}
+
+// BatchNormalize has nothing to do, points are kept in affine coordinates.
+func (c *curve) BatchNormalize(pList []vc.FastPoint) {
+}
+
+func (c *curve) BatchBack(pList []vc.FastPoint) ([]*big.Int, []*big.Int) {
+	xs := make([]*big.Int, len(pList))
+	ys := make([]*big.Int, len(pList))
+	for i, p := range pList {
+		xs[i], ys[i] = p.Back()
+	}
+	return xs, ys
+}
@@ -0,0 +1,271 @@
+package reference_test
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+	vc "volley/curve"
+	"volley/curve/reference"
+	"volley/secp256k1"
+	"volley/sm2"
+)
+
+// pair holds the same point in the optimized backend and in the reference.
+type pair struct {
+	fast, ref vc.FastPoint
+}
+
+// backends pairs every optimized curve with its reference.
+var backends = []struct {
+	name      string
+	fast, ref vc.FastCurve
+}{
+	{"secp256k1", secp256k1.FastCurve(), reference.New(reference.Secp256k1())},
+	{"sm2", sm2.FastCurve(), reference.New(sm2.FastCurve().Params())},
+}
+
+func forEachBackend(t *testing.T, f func(t *testing.T, fast, ref vc.FastCurve)) {
+	secp256k1.InitNAFTables(9)
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			f(t, b.fast, b.ref)
+		})
+	}
+}
+
+func randomScalar(t testing.TB, c vc.FastCurve) []byte {
+	k, err := rand.Int(rand.Reader, c.Params().N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.Bytes()
+}
+
+func randomPair(t testing.TB, fast, ref vc.FastCurve) pair {
+	k := randomScalar(t, fast)
+	return pair{fast.FastBaseScalar(k), ref.FastBaseScalar(k)}
+}
+
+func check(t *testing.T, name string, got, want vc.FastPoint) {
+	t.Helper()
+	if got.IsZero() || want.IsZero() {
+		if got.IsZero() != want.IsZero() {
+			t.Fatalf("%s: infinity mismatch", name)
+		}
+		return
+	}
+	gx, gy := got.Back()
+	wx, wy := want.Back()
+	if gx.Cmp(wx) != 0 || gy.Cmp(wy) != 0 {
+		t.Fatalf("%s: (%x, %x) != (%x, %x)", name, gx, gy, wx, wy)
+	}
+}
+
+func checkPair(t *testing.T, name string, p pair) {
+	t.Helper()
+	check(t, name, p.fast, p.ref)
+}
+
+func TestReferenceCurve(t *testing.T) {
+	forEachBackend(t, testReferenceCurve)
+}
+
+func testReferenceCurve(t *testing.T, fast, ref vc.FastCurve) {
+	N := fast.Params().N
+	for i := 0; i < 20; i++ {
+		a, b := randomPair(t, fast, ref), randomPair(t, fast, ref)
+		k := randomScalar(t, fast)
+		checkPair(t, "FastBaseScalar", a)
+
+		sum := pair{fast.NewPoint(), ref.NewPoint()}
+		fast.FastPointAdd(sum.fast, a.fast, b.fast)
+		ref.FastPointAdd(sum.ref, a.ref, b.ref)
+		checkPair(t, "FastPointAdd", sum)
+
+		mult := pair{fast.NewPoint(), ref.NewPoint()}
+		fast.FastScalarMult(mult.fast, a.fast, k)
+		ref.FastScalarMult(mult.ref, a.ref, k)
+		checkPair(t, "FastScalarMult", mult)
+		fast.FastScalarMultSecret(mult.fast, a.fast, k)
+		ref.FastScalarMultSecret(mult.ref, a.ref, k)
+		checkPair(t, "FastScalarMultSecret", mult)
+		checkPair(t, "FastBaseScalarSecret", pair{fast.FastBaseScalarSecret(k), ref.FastBaseScalarSecret(k)})
+
+		// scalars at and above N reduce
+		big := new(big.Int).Add(N, new(big.Int).SetBytes(k)).Bytes()
+		checkPair(t, "FastBaseScalar k+N", pair{fast.FastBaseScalar(big), ref.FastBaseScalar(k)})
+		checkPair(t, "FastBaseScalar N", pair{fast.FastBaseScalar(N.Bytes()), ref.FastBaseScalar(N.Bytes())})
+
+		for _, p := range []pair{a, sum} {
+			p.fast.Double()
+			p.ref.Double()
+			checkPair(t, "Double", p)
+			p.fast.Sub(b.fast)
+			p.ref.Sub(b.ref)
+			checkPair(t, "Sub", p)
+			p.fast.Neg()
+			p.ref.Neg()
+			checkPair(t, "Neg", p)
+			if !p.ref.Equal(p.ref) || p.ref.Equal(b.ref) {
+				t.Fatal("Equal")
+			}
+			c := p.fast.MarshalCompressed()
+			if !bytes.Equal(c, p.ref.MarshalCompressed()) {
+				t.Fatal("MarshalCompressed")
+			}
+			q := ref.NewPoint()
+			if err := q.UnmarshalCompressed(c); err != nil || !q.Equal(p.ref) {
+				t.Fatalf("UnmarshalCompressed: %v", err)
+			}
+		}
+	}
+
+	// infinity and P - P
+	a := randomPair(t, fast, ref)
+	zero := pair{fast.NewPoint(), ref.NewPoint()}
+	zero.fast.CopyFrom(a.fast)
+	zero.ref.CopyFrom(a.ref)
+	zero.fast.Sub(a.fast)
+	zero.ref.Sub(a.ref)
+	checkPair(t, "P-P", zero)
+	if !zero.ref.IsZero() || !bytes.Equal(zero.ref.MarshalCompressed(), zero.fast.MarshalCompressed()) {
+		t.Fatal("P-P is not infinity")
+	}
+	fast.FastPointAdd(zero.fast, zero.fast, a.fast)
+	ref.FastPointAdd(zero.ref, zero.ref, a.ref)
+	checkPair(t, "O+P", zero)
+}
+
+func TestReferencePolynomial(t *testing.T) {
+	forEachBackend(t, testReferencePolynomial)
+}
+
+func testReferencePolynomial(t *testing.T, fast, ref vc.FastCurve) {
+	for _, n := range []int{1, 2, 5, 17, 70} {
+		var fList, rList []vc.FastPoint
+		var kList [][]byte
+		for i := 0; i < n; i++ {
+			p := randomPair(t, fast, ref)
+			fList = append(fList, p.fast)
+			rList = append(rList, p.ref)
+			kList = append(kList, randomScalar(t, fast))
+		}
+		got := pair{fast.NewPoint(), ref.NewPoint()}
+		fast.FastPolynomial(got.fast, fList, kList)
+		ref.FastPolynomial(got.ref, rList, kList)
+		checkPair(t, "FastPolynomial", got)
+		fast.MultiScalarMult(got.fast, fList, kList)
+		ref.MultiScalarMult(got.ref, rList, kList)
+		checkPair(t, "MultiScalarMult", got)
+
+		for _, affine := range []bool{false, true} {
+			// tables go through export and a fresh import on both sides
+			fTables := make([]vc.FastPoint, n)
+			rTables := make([]vc.FastPoint, n)
+			for i := range fList {
+				data := fList[i].ExportTable(affine)
+				if len(data) != len(rList[i].ExportTable(affine)) {
+					t.Fatal("table sizes differ")
+				}
+				fTables[i] = fast.NewPoint()
+				fTables[i].CopyFrom(fList[i])
+				fTables[i].ImportTable(data, affine)
+				rTables[i] = ref.NewPoint()
+				rTables[i].MapTable(rList[i].ExportTable(affine), affine)
+			}
+			fast.FasterPolynomial(got.fast, fTables, kList, affine)
+			ref.FasterPolynomial(got.ref, rTables, kList, affine)
+			checkPair(t, "FasterPolynomial", got)
+		}
+
+		fx, fy := fast.BatchBack(fList)
+		fast.BatchNormalize(fList)
+		rx, ry := ref.BatchBack(rList)
+		ref.BatchNormalize(rList)
+		for i := range fList {
+			if fx[i].Cmp(rx[i]) != 0 || fy[i].Cmp(ry[i]) != 0 {
+				t.Fatal("BatchBack")
+			}
+			check(t, "BatchNormalize", fList[i], rList[i])
+		}
+	}
+}
+
+func TestReferenceBn(t *testing.T) {
+	forEachBackend(t, testReferenceBn)
+}
+
+func testReferenceBn(t *testing.T, fast, ref vc.FastCurve) {
+	N := fast.Params().N
+	equal := func(name string, f, r vc.FastBn) {
+		t.Helper()
+		if !bytes.Equal(f.Back(), r.Back()) || f.Big().Cmp(r.Big()) != 0 {
+			t.Fatalf("%s: %x != %x", name, f.Big(), r.Big())
+		}
+	}
+	if !bytes.Equal(fast.NewBn().Back(), ref.NewBn().Back()) {
+		t.Fatal("NewBn")
+	}
+	for i := 0; i < 100; i++ {
+		a, b := randomScalar(t, fast), randomScalar(t, fast)
+		fa, fb := fast.NewBn().SetBytes(a), fast.NewBn().SetBytes(b)
+		ra, rb := ref.NewBn().SetBytes(a), ref.NewBn().SetBytes(b)
+		equal("SetBytes", fa, ra)
+		equal("Add", fast.NewBn().Add(fa, fb), ref.NewBn().Add(ra, rb))
+		equal("Sub", fast.NewBn().Sub(fa, fb), ref.NewBn().Sub(ra, rb))
+		equal("Neg", fast.NewBn().Neg(fa), ref.NewBn().Neg(ra))
+		equal("Mul", fast.NewBn().Mul(fa, fb), ref.NewBn().Mul(ra, rb))
+		equal("Square", fast.NewBn().Square(fa), ref.NewBn().Square(ra))
+		equal("Inverse", fast.NewBn().Inverse(fa), ref.NewBn().Inverse(ra))
+		fr, rr := fast.NewBn(), ref.NewBn()
+		fast.FastOrderMul(fr, fa, fb)
+		ref.FastOrderMul(rr, ra, rb)
+		equal("FastOrderMul", fr, rr)
+		equal("SetInt64", fast.NewBn().SetInt64(int64(i)-50), ref.NewBn().SetInt64(int64(i)-50))
+
+		v := new(big.Int).SetBytes(a)
+		v.Add(v, N)
+		fr.From(v)
+		rr.From(v)
+		equal("From", fr, rr)
+		if ra.Equal(rb) || !ra.Equal(ra) || ra.IsZero() {
+			t.Fatal("Equal")
+		}
+		k := new(big.Int).SetBytes(a)
+		if fast.Inverse(k).Cmp(ref.Inverse(k)) != 0 {
+			t.Fatal("Inverse")
+		}
+	}
+
+	var fList, rList []vc.FastBn
+	for i := 0; i < 10; i++ {
+		a := randomScalar(t, fast)
+		fList = append(fList, fast.NewBn().SetBytes(a))
+		rList = append(rList, ref.NewBn().SetBytes(a))
+	}
+	fast.BatchInverse(fList)
+	ref.BatchInverse(rList)
+	for i := range fList {
+		equal("BatchInverse", fList[i], rList[i])
+	}
+}
+
+func TestReferenceP256(t *testing.T) {
+	c := reference.New(elliptic.P256())
+	k := randomScalar(t, c)
+	p := c.FastBaseScalar(k)
+	x, y := elliptic.P256().ScalarBaseMult(new(big.Int).Mod(new(big.Int).SetBytes(k), c.Params().N).Bytes())
+	px, py := p.Back()
+	if px.Cmp(x) != 0 || py.Cmp(y) != 0 {
+		t.Fatal("FastBaseScalar")
+	}
+	q := c.NewPoint()
+	if err := q.UnmarshalCompressed(p.MarshalCompressed()); err != nil || !q.Equal(p) {
+		t.Fatalf("UnmarshalCompressed: %v", err)
+	}
+	if !reference.Secp256k1().IsOnCurve(secp256k1.FastCurve().Params().Gx, secp256k1.FastCurve().Params().Gy) {
+		t.Fatal("generator is not on the curve")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"volley/keyd"
+	"volley/protocol"
+)
+
+// serveKeys loads the tumbler's secret keys and answers requests on the Unix
+// socket path until interrupted.
+func serveKeys(path, privatePath, rlwePrivatePath string) error {
+	key, err := protocol.LoadPrivateKey(privatePath)
+	if err != nil {
+		return err
+	}
+	rlweKey, err := protocol.LoadRLWEPrivateKey(rlwePrivatePath)
+	if err != nil {
+		return err
+	}
+	l, err := keyd.Listen(path)
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		l.Close()
+	}()
+	fmt.Printf("Serving the keys of Tumbler on %s\n", path)
+	return keyd.Serve(l, protocol.NewLocalSigner(key), protocol.NewLocalDecrypter(rlweKey))
+}
+
+// initTumbler loads the tumbler, with its secret keys read from files or,
+// if keydPath is set, held by the daemon listening there.
+func initTumbler(keydPath, genPath, prePath, secretPath, alicePath, bobPath, rlwePrivate,
+	rlwePublic string) (*protocol.Tumbler, error) {
+	tumbler := new(protocol.Tumbler)
+	if keydPath == "" {
+		return tumbler, tumbler.Init(genPath, prePath, secretPath, alicePath, bobPath, rlwePrivate, rlwePublic)
+	}
+	client, err := keyd.Dial(keydPath)
+	if err != nil {
+		return nil, err
+	}
+	return tumbler, tumbler.InitWithKeys(genPath, prePath, client, client, alicePath, bobPath, rlwePublic)
+}
@@ -0,0 +1,283 @@
+// Package keyd keeps the tumbler's secret keys in a separate process and
+// serves signing and decryption requests over a Unix socket, the way an HSM
+// would.
+//
+// Every request is an operation byte followed by a 4-byte big-endian length
+// and the payload. Every response is a status byte, 0 for success and 1 for
+// an error message, followed by a length and the payload in the same way.
+package keyd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"volley/adaptor"
+	vc "volley/curve"
+	"volley/lpr"
+	"volley/protocol"
+)
+
+const (
+	opPublic  = 0
+	opSign    = 1
+	opDecrypt = 2
+
+	statusOK    = 0
+	statusError = 1
+
+	// maxFrame bounds a payload, 64 LWE ciphertexts of dimension 1024 need
+	// about 260 KiB
+	maxFrame = 1 << 22
+)
+
+func writeFrame(w *bufio.Writer, kind byte, payload []byte) error {
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxFrame {
+		return 0, nil, fmt.Errorf("Frame of %d bytes is too long\n", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func marshalCiphertexts(cipherList []*lpr.LWECiphertext) []byte {
+	dim := 0
+	if len(cipherList) > 0 {
+		dim = len(cipherList[0].A)
+	}
+	data := make([]byte, 8+4*len(cipherList)*(1+dim))
+	binary.BigEndian.PutUint32(data[0:], uint32(len(cipherList)))
+	binary.BigEndian.PutUint32(data[4:], uint32(dim))
+	offset := 8
+	for _, cipher := range cipherList {
+		binary.BigEndian.PutUint32(data[offset:], uint32(cipher.B))
+		offset += 4
+		for _, a := range cipher.A {
+			binary.BigEndian.PutUint32(data[offset:], uint32(a))
+			offset += 4
+		}
+	}
+	return data
+}
+
+func unmarshalCiphertexts(data []byte) ([]*lpr.LWECiphertext, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("LWE ciphertext list too short\n")
+	}
+	count := int(binary.BigEndian.Uint32(data[0:]))
+	dim := int(binary.BigEndian.Uint32(data[4:]))
+	if count > maxFrame/4 || dim > maxFrame/4 || len(data) != 8+4*count*(1+dim) {
+		return nil, fmt.Errorf("LWE ciphertext list length error\n")
+	}
+	cipherList := make([]*lpr.LWECiphertext, count)
+	offset := 8
+	for i := range cipherList {
+		cipher := &lpr.LWECiphertext{
+			B: int32(binary.BigEndian.Uint32(data[offset:])),
+			A: make([]int32, dim),
+		}
+		offset += 4
+		for j := range cipher.A {
+			cipher.A[j] = int32(binary.BigEndian.Uint32(data[offset:]))
+			offset += 4
+		}
+		cipherList[i] = cipher
+	}
+	return cipherList, nil
+}
+
+// Serve answers requests on connections accepted from l until l is closed.
+// Signatures are randomized with crypto/rand.
+func Serve(l net.Listener, signer protocol.Signer, decrypter protocol.Decrypter) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go serveConn(conn, signer, decrypter)
+	}
+}
+
+func serveConn(conn net.Conn, signer protocol.Signer, decrypter protocol.Decrypter) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		op, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		result, err := handle(op, payload, signer, decrypter)
+		if err != nil {
+			err = writeFrame(w, statusError, []byte(err.Error()))
+		} else {
+			err = writeFrame(w, statusOK, result)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func handle(op byte, payload []byte, signer protocol.Signer, decrypter protocol.Decrypter) ([]byte, error) {
+	switch op {
+	case opPublic:
+		return signer.Public().MarshalCompressed(), nil
+	case opSign:
+		if len(payload) < 33 {
+			return nil, fmt.Errorf("Sign request too short\n")
+		}
+		y, err := protocol.GetPointCompressed(payload[:33])
+		if err != nil {
+			return nil, err
+		}
+		sig, err := signer.SignAdaptor(payload[33:], y, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, 64)
+		sig.E.FillBytes(data[:32])
+		sig.S.FillBytes(data[32:])
+		return data, nil
+	case opDecrypt:
+		cipherList, err := unmarshalCiphertexts(payload)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := decrypter.DecryptLWE(cipherList)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, 4*len(plaintext))
+		for i, v := range plaintext {
+			binary.BigEndian.PutUint32(data[4*i:], uint32(v))
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("Unknown operation %d\n", op)
+	}
+}
+
+// Client talks to a key daemon. It implements protocol.Signer and
+// protocol.Decrypter and may be used from several goroutines.
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	w      *bufio.Writer
+	public vc.FastPoint
+}
+
+// Dial connects to the daemon listening on the Unix socket path and fetches
+// its public key.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+	data, err := c.call(opPublic, nil)
+	if err == nil {
+		c.public, err = protocol.GetPointCompressed(data)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) call(op byte, payload []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeFrame(c.w, op, payload); err != nil {
+		return nil, err
+	}
+	status, result, err := readFrame(c.r)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusOK {
+		return nil, fmt.Errorf("Key daemon: %s", result)
+	}
+	return result, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) Public() vc.FastPoint {
+	return c.public
+}
+
+// SignAdaptor asks the daemon for the signature. random is not used, the
+// daemon draws its own randomness.
+func (c *Client) SignAdaptor(msg []byte, y vc.FastPoint, random io.Reader) (*adaptor.Signature, error) {
+	payload := make([]byte, 33+len(msg))
+	copy(payload, y.MarshalCompressed())
+	copy(payload[33:], msg)
+	data, err := c.call(opSign, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 64 {
+		return nil, fmt.Errorf("Key daemon returned a signature of %d bytes\n", len(data))
+	}
+	return &adaptor.Signature{
+		E: new(big.Int).SetBytes(data[:32]),
+		S: new(big.Int).SetBytes(data[32:]),
+	}, nil
+}
+
+func (c *Client) DecryptLWE(cipherList []*lpr.LWECiphertext) ([]int32, error) {
+	for _, cipher := range cipherList {
+		if len(cipher.A) != len(cipherList[0].A) {
+			return nil, fmt.Errorf("LWE ciphertexts differ in dimension\n")
+		}
+	}
+	data, err := c.call(opDecrypt, marshalCiphertexts(cipherList))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 4*len(cipherList) {
+		return nil, fmt.Errorf("Key daemon returned %d bytes for %d plaintexts\n", len(data), len(cipherList))
+	}
+	plaintext := make([]int32, len(cipherList))
+	for i := range plaintext {
+		plaintext[i] = int32(binary.BigEndian.Uint32(data[4*i:]))
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,109 @@
+package keyd_test
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"volley/adaptor"
+	"volley/keyd"
+	"volley/lpr"
+	"volley/protocol"
+	"volley/secp256k1"
+)
+
+func TestDaemon(t *testing.T) {
+	fastCurve := secp256k1.FastCurve()
+	protocol.SetCurve(fastCurve)
+	adaptor.SetCurve(fastCurve)
+
+	secret, err := rand.Int(rand.Reader, fastCurve.Params().N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := protocol.NewPrivateKey(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rlweKey, err := lpr.GenSecret(protocol.D, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rlwePublic, err := lpr.GenPublicKey(rlweKey, protocol.Q, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypter := protocol.NewLocalDecrypter(rlweKey)
+
+	path := filepath.Join(t.TempDir(), "keyd.sock")
+	l, err := keyd.Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("socket has mode %v", info.Mode().Perm())
+	}
+	done := make(chan error)
+	go func() {
+		done <- keyd.Serve(l, protocol.NewLocalSigner(key), decrypter)
+	}()
+	defer func() {
+		l.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	client, err := keyd.Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if !client.Public().Equal(key.Public) {
+		t.Fatal("daemon returned another public key")
+	}
+
+	plain, err := lpr.GenerateRq(protocol.D, protocol.T/2, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("tx signed by the daemon")
+	y, _ := protocol.CalculateY(plain[:64])
+	sig, err := client.SignAdaptor(msg, y, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !adaptor.SchnorrPreVerifyAdaptor(sig, msg, y, key.Public, protocol.NewHash()) {
+		t.Fatal("adaptor signature from the daemon not verified")
+	}
+
+	cipher, _, err := lpr.Encrypt(rlwePublic, &lpr.Plaintext{Data: plain}, protocol.Q, protocol.T, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherList := make([]*lpr.LWECiphertext, 64)
+	for i := range cipherList {
+		cipherList[i] = lpr.Extract(cipher, protocol.Q, i)
+	}
+	got, err := client.DecryptLWE(cipherList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		if got[i] != plain[i] {
+			t.Fatalf("plaintext %d: got %d, want %d", i, got[i], plain[i])
+		}
+	}
+
+	// errors of the daemon are reported and leave the connection usable
+	cipherList[0].A = cipherList[0].A[:1]
+	if _, err = client.DecryptLWE(cipherList[:1]); err == nil {
+		t.Fatal("decrypted a ciphertext of the wrong dimension")
+	}
+	if _, err = client.SignAdaptor(msg, y, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package keyd
+
+import "net"
+
+// Listen creates the Unix socket path. There is no umask here, so keep the
+// socket in a directory only the owner can enter.
+func Listen(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package keyd
+
+import (
+	"net"
+	"sync"
+	"syscall"
+)
+
+// umaskLock serializes Listen calls, the umask belongs to the whole process.
+var umaskLock sync.Mutex
+
+// Listen creates the Unix socket path with mode 0600, so only the owner may
+// ask for signatures. The socket is created under umask 0177 rather than
+// chmod'ed afterwards, which would leave a window where others can connect.
+func Listen(path string) (net.Listener, error) {
+	umaskLock.Lock()
+	defer umaskLock.Unlock()
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,172 @@
+// Package keystore encrypts secret keys at rest under a passphrase. A
+// keystore is a small JSON document holding versioned metadata, the scrypt
+// parameters and the AES-256-GCM ciphertext of the key.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version is the keystore format written by Encrypt.
+const Version = 1
+
+// Params are the scrypt cost parameters.
+type Params struct {
+	N int
+	R int
+	P int
+}
+
+// DefaultParams costs about 32 MiB and a tenth of a second per derivation.
+var DefaultParams = Params{N: 1 << 15, R: 8, P: 1}
+
+// Bounds on the scrypt parameters accepted by Decrypt. ROMix allocates
+// 128*r*N bytes and runs p times over them, so a crafted keystore can't make
+// Decrypt allocate more than 1 GiB or mix more than 4 GiB.
+const (
+	maxN      = 1 << 20
+	maxMemory = 1 << 30
+	maxWork   = 1 << 32
+)
+
+// ErrPassphrase is returned by Decrypt when the passphrase is wrong or the
+// keystore was modified.
+var ErrPassphrase = errors.New("Wrong passphrase or corrupted keystore\n")
+
+type kdfParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  string `json:"salt"`
+	DKLen int    `json:"dklen"`
+}
+
+type keystoreFile struct {
+	Version    int       `json:"version"`
+	KDF        string    `json:"kdf"`
+	KDFParams  kdfParams `json:"kdfparams"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext,omitempty"`
+}
+
+// additionalData authenticates every field but the ciphertext.
+func (f keystoreFile) additionalData() ([]byte, error) {
+	f.Ciphertext = ""
+	return json.Marshal(f)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext under passphrase, drawing the salt and the nonce
+// from random.
+func Encrypt(plaintext, passphrase []byte, params Params, random io.Reader) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, err
+	}
+	key, err := scrypt(passphrase, salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(random, nonce); err != nil {
+		return nil, err
+	}
+	f := keystoreFile{
+		Version: Version,
+		KDF:     "scrypt",
+		KDFParams: kdfParams{
+			N:     params.N,
+			R:     params.R,
+			P:     params.P,
+			Salt:  hex.EncodeToString(salt),
+			DKLen: 32,
+		},
+		Cipher: "aes-256-gcm",
+		Nonce:  hex.EncodeToString(nonce),
+	}
+	ad, err := f.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	f.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, plaintext, ad))
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// Decrypt opens a keystore written by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	var f keystoreFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("Unsupported keystore version %d\n", f.Version)
+	}
+	if f.KDF != "scrypt" || f.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("Unsupported keystore algorithms %s and %s\n", f.KDF, f.Cipher)
+	}
+	p := f.KDFParams
+	if p.N <= 0 || p.N > maxN || p.R <= 0 || p.R > 32 || p.P <= 0 || p.P > 16 || p.DKLen != 32 {
+		return nil, fmt.Errorf("Keystore scrypt parameters out of range\n")
+	}
+	memory := 128 * int64(p.R) * int64(p.N)
+	if memory > maxMemory || memory*int64(p.P) > maxWork {
+		return nil, fmt.Errorf("Keystore scrypt parameters out of range\n")
+	}
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt(passphrase, salt, p.N, p.R, p.P, p.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("Keystore nonce length error: %d\n", len(nonce))
+	}
+	ad, err := f.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plaintext, nil
+}
+
+// IsKeystore reports whether data looks like a keystore rather than a raw
+// key.
+func IsKeystore(data []byte) bool {
+	var f keystoreFile
+	return json.Unmarshal(data, &f) == nil && f.KDF != "" && f.Ciphertext != ""
+}
@@ -0,0 +1,153 @@
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testParams = Params{N: 1 << 10, R: 8, P: 1}
+
+func TestKeystore(t *testing.T) {
+	secret := make([]byte, 96)
+	rand.Read(secret)
+	data, err := Encrypt(secret, []byte("correct horse"), testParams, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsKeystore(data) || IsKeystore(secret) {
+		t.Fatal("IsKeystore")
+	}
+	plain, err := Decrypt(data, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, secret) {
+		t.Fatal("decrypted another key")
+	}
+	if _, err = Decrypt(data, []byte("battery staple")); !errors.Is(err, ErrPassphrase) {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+
+	// the metadata is authenticated along with the ciphertext
+	var f map[string]interface{}
+	if err = json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	f["cipher"] = "aes-256-gcm"
+	f["kdfparams"].(map[string]interface{})["dklen"] = 32
+	f["extra"] = 1
+	tampered, _ := json.Marshal(f)
+	if _, err = Decrypt(tampered, []byte("correct horse")); err != nil {
+		t.Fatalf("unknown fields should be ignored: %v", err)
+	}
+	f["version"] = 2
+	tampered, _ = json.Marshal(f)
+	if _, err = Decrypt(tampered, []byte("correct horse")); err == nil {
+		t.Fatal("accepted version 2")
+	}
+	f["version"] = 1
+	f["kdfparams"].(map[string]interface{})["n"] = 1 << 30
+	tampered, _ = json.Marshal(f)
+	if _, err = Decrypt(tampered, []byte("correct horse")); err == nil {
+		t.Fatal("accepted a huge N")
+	}
+
+	// parameters within their own limits but too costly together are
+	// rejected before scrypt runs, which would only fail on the MAC
+	for _, params := range []map[string]interface{}{
+		{"n": 1 << 19, "r": 32, "p": 1},
+		{"n": 1 << 18, "r": 32, "p": 16},
+	} {
+		kdf := f["kdfparams"].(map[string]interface{})
+		for k, v := range params {
+			kdf[k] = v
+		}
+		tampered, _ = json.Marshal(f)
+		_, err = Decrypt(tampered, []byte("correct horse"))
+		if err == nil || errors.Is(err, ErrPassphrase) || !strings.Contains(err.Error(), "out of range") {
+			t.Fatalf("accepted scrypt parameters %v: %v", params, err)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	os.Setenv("KEYSTORE_TEST_PASSPHRASE", "from env")
+	defer os.Unsetenv("KEYSTORE_TEST_PASSPHRASE")
+	path := filepath.Join(t.TempDir(), "pass")
+	if err := os.WriteFile(path, []byte("from file\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for spec, want := range map[string]string{
+		"env:KEYSTORE_TEST_PASSPHRASE": "from env",
+		"file:" + path:                 "from file",
+	} {
+		read, err := Source(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := read()
+		if err != nil || string(got) != want {
+			t.Fatalf("%s: got %q, %v", spec, got, err)
+		}
+	}
+
+	// read once and then reused
+	read, err := Source("file:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read()
+	os.Remove(path)
+	if got, err := read(); err != nil || string(got) != "from file" {
+		t.Fatal("passphrase read twice")
+	}
+
+	if _, err = Source("stdin"); err == nil {
+		t.Fatal("accepted an unknown source")
+	}
+	read, _ = Source("env:KEYSTORE_TEST_UNSET")
+	if _, err = read(); err == nil {
+		t.Fatal("accepted an unset variable")
+	}
+}
+
+func TestConfirmedPrompt(t *testing.T) {
+	defer func(saved *bufio.Reader) { stdin = saved }(stdin)
+	for input, want := range map[string]string{
+		"secret\nsecret\n": "secret",
+		"secret\nsecre\n":  "",
+		"secret\n":         "",
+	} {
+		stdin = bufio.NewReader(strings.NewReader(input))
+		read, err := ConfirmedSource("prompt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := read()
+		if want == "" && err == nil {
+			t.Fatalf("%q: accepted an unconfirmed passphrase", input)
+		}
+		if want != "" && (err != nil || string(got) != want) {
+			t.Fatalf("%q: got %q, %v", input, got, err)
+		}
+	}
+
+	// the environment and files are not asked twice
+	os.Setenv("KEYSTORE_TEST_PASSPHRASE", "from env")
+	defer os.Unsetenv("KEYSTORE_TEST_PASSPHRASE")
+	read, err := ConfirmedSource("env:KEYSTORE_TEST_PASSPHRASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := read(); err != nil || string(got) != "from env" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
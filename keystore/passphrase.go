@@ -0,0 +1,115 @@
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Source returns a function reading the passphrase from spec, which is one
+// of:
+//
+//	prompt      ask on the terminal, without echo where supported
+//	env:NAME    the environment variable NAME
+//	file:PATH   the first line of the file PATH
+//
+// The passphrase is read on the first call only and then reused, so a
+// prompt is shown once however many keys are opened.
+func Source(spec string) (func() ([]byte, error), error) {
+	return source(spec, false)
+}
+
+// ConfirmedSource is Source for a passphrase that encrypts new keys. A
+// prompt asks for it twice and fails if the two differ, so a typo can't lock
+// the keys away.
+func ConfirmedSource(spec string) (func() ([]byte, error), error) {
+	return source(spec, true)
+}
+
+func source(spec string, confirm bool) (func() ([]byte, error), error) {
+	var read func() ([]byte, error)
+	switch {
+	case spec == "prompt" && confirm:
+		read = promptConfirmed
+	case spec == "prompt":
+		read = prompt
+	case strings.HasPrefix(spec, "env:"):
+		name := spec[len("env:"):]
+		read = func() ([]byte, error) {
+			value, ok := os.LookupEnv(name)
+			if !ok || value == "" {
+				return nil, fmt.Errorf("Environment variable %s is not set\n", name)
+			}
+			return []byte(value), nil
+		}
+	case strings.HasPrefix(spec, "file:"):
+		path := spec[len("file:"):]
+		read = func() ([]byte, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+				data = data[:i]
+			}
+			if len(data) == 0 {
+				return nil, fmt.Errorf("Passphrase file %s is empty\n", path)
+			}
+			return data, nil
+		}
+	default:
+		return nil, fmt.Errorf("Invalid passphrase source %q, expecting prompt, env:NAME or file:PATH\n", spec)
+	}
+
+	var once sync.Once
+	var passphrase []byte
+	var err error
+	return func() ([]byte, error) {
+		once.Do(func() {
+			passphrase, err = read()
+		})
+		return passphrase, err
+	}, nil
+}
+
+// stdin is shared by the prompts, a reader per prompt could buffer the line
+// meant for the next one.
+var stdin = bufio.NewReader(os.Stdin)
+
+func prompt() ([]byte, error) {
+	return readPassphrase("Passphrase: ")
+}
+
+func promptConfirmed() ([]byte, error) {
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	again, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		return nil, fmt.Errorf("Passphrases don't match\n")
+	}
+	return passphrase, nil
+}
+
+func readPassphrase(label string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, label)
+	restore := disableEcho(os.Stdin)
+	line, err := stdin.ReadString('\n')
+	restore()
+	fmt.Fprintln(os.Stderr)
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Empty passphrase\n")
+	}
+	return []byte(line), nil
+}
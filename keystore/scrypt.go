@@ -0,0 +1,118 @@
+package keystore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// pbkdf2 is PBKDF2-HMAC-SHA256 from RFC 8018.
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	out := make([]byte, 0, keyLen+sha256.Size)
+	var counter [4]byte
+	u := make([]byte, sha256.Size)
+	t := make([]byte, sha256.Size)
+	for block := uint32(1); len(out) < keyLen; block++ {
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
+
+// salsa208 applies the Salsa20/8 core to b in place.
+func salsa208(b *[16]uint32) {
+	x := *b
+	for i := 0; i < 8; i += 2 {
+		for _, q := range [8][4]int{
+			{0, 4, 8, 12}, {5, 9, 13, 1}, {10, 14, 2, 6}, {15, 3, 7, 11},
+			{0, 1, 2, 3}, {5, 6, 7, 4}, {10, 11, 8, 9}, {15, 12, 13, 14},
+		} {
+			x[q[1]] ^= bits.RotateLeft32(x[q[0]]+x[q[3]], 7)
+			x[q[2]] ^= bits.RotateLeft32(x[q[1]]+x[q[0]], 9)
+			x[q[3]] ^= bits.RotateLeft32(x[q[2]]+x[q[1]], 13)
+			x[q[0]] ^= bits.RotateLeft32(x[q[3]]+x[q[2]], 18)
+		}
+	}
+	for i := range b {
+		b[i] += x[i]
+	}
+}
+
+// blockMix is scryptBlockMix of RFC 7914 on 2*r blocks of 16 words, writing
+// the result to out.
+func blockMix(out, in []uint32, r int) {
+	var x [16]uint32
+	copy(x[:], in[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		for j := range x {
+			x[j] ^= in[i*16+j]
+		}
+		salsa208(&x)
+		// even blocks go to the first half, odd ones to the second
+		copy(out[(i/2+(i%2)*r)*16:], x[:])
+	}
+}
+
+// roMix is scryptROMix of RFC 7914, working in place on b.
+func roMix(b []uint32, r, n int) {
+	size := 32 * r
+	v := make([]uint32, n*size)
+	x := b
+	y := make([]uint32, size)
+	for i := 0; i < n; i++ {
+		copy(v[i*size:], x)
+		blockMix(y, x, r)
+		x, y = y, x
+	}
+	for i := 0; i < n; i++ {
+		j := int(x[(2*r-1)*16] & uint32(n-1))
+		for k := range x {
+			x[k] ^= v[j*size+k]
+		}
+		blockMix(y, x, r)
+		x, y = y, x
+	}
+	// n is even, so x is b again
+}
+
+// scrypt derives a keyLen-byte key as in RFC 7914. n must be a power of two
+// greater than 1.
+func scrypt(password, salt []byte, n, r, p, keyLen int) ([]byte, error) {
+	if n <= 1 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("Scrypt N must be a power of two greater than 1\n")
+	}
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || r > maxInt/128/p || r > maxInt/256 || n > maxInt/128/r {
+		return nil, fmt.Errorf("Scrypt parameters too large\n")
+	}
+	b := pbkdf2(password, salt, 1, p*128*r)
+	words := make([]uint32, 32*r)
+	for i := 0; i < p; i++ {
+		chunk := b[i*128*r : (i+1)*128*r]
+		for j := range words {
+			words[j] = binary.LittleEndian.Uint32(chunk[4*j:])
+		}
+		roMix(words, r, n)
+		for j, w := range words {
+			binary.LittleEndian.PutUint32(chunk[4*j:], w)
+		}
+	}
+	return pbkdf2(password, b, 1, keyLen), nil
+}
+
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,33 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors of RFC 7914 section 12, except the one with N = 2^20.
+func TestScrypt(t *testing.T) {
+	for _, tt := range []struct {
+		password, salt string
+		n, r, p        int
+		want           string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442" +
+			"fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162" +
+			"2eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2" +
+			"d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	} {
+		key, err := scrypt([]byte(tt.password), []byte(tt.salt), tt.n, tt.r, tt.p, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("N=%d: got %s", tt.n, got)
+		}
+	}
+	if _, err := scrypt(nil, nil, 1000, 8, 1, 32); err == nil {
+		t.Error("accepted N that is not a power of two")
+	}
+}
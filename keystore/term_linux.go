@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package keystore
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// disableEcho turns off echo on f if it is a terminal and returns a function
+// restoring it.
+func disableEcho(f *os.File) func() {
+	var old syscall.Termios
+	fd := f.Fd()
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return func() {}
+	}
+	noEcho := old
+	noEcho.Lflag &^= syscall.ECHO
+	noEcho.Lflag |= syscall.ICANON
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&noEcho))); errno != 0 {
+		return func() {}
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&old)))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package keystore
+
+import "os"
+
+// disableEcho is a no-op where the terminal can't be configured without
+// extra dependencies, the passphrase is then echoed.
+func disableEcho(f *os.File) func() {
+	return func() {}
+}
@@ -0,0 +1,12 @@
+package lpr
+
+import "errors"
+
+var (
+	// ErrNotEnoughData means the random source returned fewer bytes than
+	// requested.
+	ErrNotEnoughData = errors.New("No enough data")
+	// ErrDataLength means serialized input has the wrong length for the
+	// requested dimension.
+	ErrDataLength = errors.New("Data length error")
+)
@@ -2,21 +2,22 @@ package lpr
 
 import (
 	"encoding/binary"
+	"fmt"
 	"os"
 )
 
-func ReplaceRandomBy(d int32, fileName string) []int32 {
+func ReplaceRandomBy(d int32, fileName string) ([]int32, error) {
 	dataBytes, err := os.ReadFile(fileName)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	if len(dataBytes) != 4*int(d) {
-		panic("Data length error")
+		return nil, fmt.Errorf("%w: %d, expected %d\n", ErrDataLength, len(dataBytes), 4*int(d))
 	}
 	result := make([]int32, d)
 	for i := 0; i < int(d); i++ {
 		u := binary.LittleEndian.Uint32(dataBytes[i*4 : i*4+4])
 		result[i] = int32(u)
 	}
-	return result
+	return result, nil
 }
@@ -24,7 +24,7 @@ func GenerateR2(d int32, random io.Reader) ([]int32, error) {
 		return nil, err
 	}
 	if n != int(d) {
-		return nil, fmt.Errorf(fmt.Sprintf("No enough data: %d", n))
+		return nil, fmt.Errorf("%w: %d\n", ErrNotEnoughData, n)
 	}
 	result := make([]int32, d)
 	for i, s := range samples {
@@ -69,7 +69,7 @@ func GenerateRq(d int32, q int32, random io.Reader) ([]int32, error) {
 		return nil, err
 	}
 	if n != int(d)*4 {
-		return nil, fmt.Errorf(fmt.Sprintf("No enough data: %d", n))
+		return nil, fmt.Errorf("%w: %d\n", ErrNotEnoughData, n)
 	}
 	result := make([]int32, d)
 	for i := int32(0); i < d; i++ {
@@ -55,12 +55,13 @@ func (c *Ciphertext) Serialize(qMax int32) []byte {
 }
 
 func (c *Ciphertext) Deserialize(data []byte, D, qMax int32) (err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("Deserialize error\n")
-		}
-	}()
+	width := 4
+	if qMax <= 65536 {
+		width = 2
+	}
+	if D <= 0 || len(data) != 2*int(D)*width {
+		return fmt.Errorf("%w: %d, expected %d\n", ErrDataLength, len(data), 2*int(D)*width)
+	}
 	c.CT0 = make([]int32, D)
 	c.CT1 = make([]int32, D)
 	if qMax <= 65536 {
@@ -106,6 +107,5 @@ func (c *Ciphertext) Deserialize(data []byte, D, qMax int32) (err error) {
 			offset += 4
 		}
 	}
-	err = nil
-	return
+	return nil
 }
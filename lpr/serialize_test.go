@@ -0,0 +1,38 @@
+package lpr
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func FuzzCiphertextDeserialize(f *testing.F) {
+	d := int32(16)
+	q := int32(65536)
+	secret, err := GenSecret(d, rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	public, err := GenPublicKey(secret, q, rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	data := public.Serialize(q)
+	f.Add(data)
+	f.Add(data[:len(data)-1])
+	f.Add(append(data, 0))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c := new(Ciphertext)
+		if c.Deserialize(data, d, q) != nil {
+			return
+		}
+		if len(c.CT0) != int(d) || len(c.CT1) != int(d) {
+			t.Fatal("wrong ciphertext dimension")
+		}
+		for i := 0; i < int(d); i++ {
+			if c.CT0[i] < -q/2 || c.CT0[i] >= q/2 || c.CT1[i] < -q/2 || c.CT1[i] >= q/2 {
+				t.Fatal("coefficient out of range")
+			}
+		}
+	})
+}
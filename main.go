@@ -5,38 +5,85 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"hash"
 	"math/big"
 	"os"
 	"time"
 	"volley/adaptor"
 	vc "volley/curve"
+	"volley/keystore"
 	"volley/lpr"
 	"volley/protocol"
 	"volley/secp256k1"
+	"volley/sm2"
+	"volley/sm3"
 )
 
 func main() {
-	secp256k1.InitNAFTables(9)
-	protocol.SetCurve(secp256k1.FastCurve())
-	adaptor.SetCurve(secp256k1.FastCurve())
-
-	prefix := "./testdata"
-
-	setup, steps, threadNum, index, err := ParseArgument(os.Args)
+	setup, steps, threadNum, index, mmap, cachePrecomputed, curveName, passphrase, keydPath, serveKeyd, err := ParseArgument(os.Args)
 	if err != nil {
 		return
 	}
+	if passphrase != "" {
+		newSource := keystore.Source
+		if setup {
+			// setup encrypts new keys, don't let a typo lock them away
+			newSource = keystore.ConfirmedSource
+		}
+		source, sourceErr := newSource(passphrase)
+		if sourceErr != nil {
+			fmt.Print(sourceErr)
+			return
+		}
+		protocol.SetPassphrase(source)
+	}
+
+	prefix := "./testdata"
+	var fastCurve vc.FastCurve
+	var newHash func() hash.Hash
+	if curveName == "sm2" {
+		// keep the SM2 files apart, they are useless with the other curve
+		prefix = "./testdata_sm2"
+		fastCurve = sm2.FastCurve()
+		newHash = sm3.New
+	} else {
+		fastCurve = secp256k1.FastCurve()
+		newHash = sha256.New
+	}
+	protocol.SetCurve(fastCurve)
+	protocol.SetHash(newHash)
+	adaptor.SetCurve(fastCurve)
+	// the parties sign spends of the Taproot outputs of their keys, see tx.go
+	protocol.SetTaprootKeys(curveName == "secp256k1")
 
 	protocol.SetCoreNum(threadNum)
+	protocol.SetMmapPrecomputes(mmap)
 	random := rand.Reader
+	if serveKeyd != "" {
+		err = serveKeys(serveKeyd, prefix+"/tumbler/tumbler_private.dat", prefix+"/tumbler/tumbler_rlwe_private.dat")
+		if err != nil {
+			fmt.Print(err)
+		}
+		return
+	}
 	if setup {
 		_ = os.MkdirAll(prefix+"/public", os.ModePerm)
-		_ = os.MkdirAll(prefix+"/tumbler", os.ModePerm)
-		_ = os.MkdirAll(prefix+"/bob", os.ModePerm)
-		_ = os.MkdirAll(prefix+"/alice", os.ModePerm)
+		// the party folders hold private keys
+		_ = os.MkdirAll(prefix+"/tumbler", 0700)
+		_ = os.MkdirAll(prefix+"/bob", 0700)
+		_ = os.MkdirAll(prefix+"/alice", 0700)
 
 		start := time.Now()
-		err = protocol.Setup(prefix+"/public/generator.dat", prefix+"/public/precomputes.dat", rand.Reader)
+		err = protocol.Setup(prefix+"/public/generator.dat", prefix+"/public/precomputes.dat", rand.Reader,
+			func(done, total int) {
+				elapsed := time.Since(start)
+				eta := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+				fmt.Printf("\rGenerating generators and precomputes: %d/%d (%.1f%%), ETA %v    ",
+					done, total, float64(done)*100/float64(total), eta.Round(time.Second))
+				if done == total {
+					fmt.Println()
+				}
+			})
 		if err != nil {
 			panic(err)
 		}
@@ -54,7 +101,7 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		fmt.Println("Private/Public key of Tumbler/Alice/Bob generated(secp256k1)")
+		fmt.Printf("Private/Public key of Tumbler/Alice/Bob generated(%s)\n", curveName)
 		err = protocol.GenKeyRLWE(prefix+"/tumbler/tumbler_rlwe_private.dat",
 			prefix+"/public/tumbler_rlwe_public.dat", random)
 		if err != nil {
@@ -63,6 +110,20 @@ func main() {
 		fmt.Println("Private/Public key of Tumbler generated(RLWE)")
 	}
 
+	if cachePrecomputed {
+		// the tumbler verifies signatures of Alice and Bob those of the tumbler
+		for _, path := range []string{prefix + "/public/alice_public.dat", prefix + "/public/tumbler_public.dat"} {
+			public, loadErr := protocol.LoadPublicKey(path)
+			if loadErr == nil {
+				loadErr = protocol.CachePrecomputed(path+".pre", public)
+			}
+			if loadErr != nil {
+				panic(loadErr)
+			}
+		}
+		fmt.Println("Precomputed tables of the public keys cached")
+	}
+
 	if !(steps[0] || steps[1] || steps[2] || steps[3] || steps[4] || steps[5]) {
 		return
 	}
@@ -70,14 +131,26 @@ func main() {
 	var tumbler *protocol.Tumbler
 	var bob *protocol.Bob
 	var alice *protocol.Alice
-	tx := []byte("This is the tx transferred from tumbler to bob")
-	tx2 := []byte("This is the tx transferred from alice to tumbler")
 
 	generatorFile := prefix + "/public/generator.dat"
 	precomputesFile := prefix + "/public/precomputes.dat"
 	tumblerPublic := prefix + "/public/tumbler_public.dat"
 	alicePublic := prefix + "/public/alice_public.dat"
 	bobPublic := prefix + "/public/bob_public.dat"
+
+	tx := []byte("This is the tx transferred from tumbler to bob")
+	tx2 := []byte("This is the tx transferred from alice to tumbler")
+	if curveName == "secp256k1" {
+		// sign the spends of Taproot outputs of the parties
+		tx, err = spendDigest(tumblerPublic, bobPublic, "tumbler escrow")
+		if err != nil {
+			panic(err)
+		}
+		tx2, err = spendDigest(alicePublic, tumblerPublic, "alice escrow")
+		if err != nil {
+			panic(err)
+		}
+	}
 	tumblerPrivate := prefix + "/tumbler/tumbler_private.dat"
 	bobPrivate := prefix + "/bob/bob_private.dat"
 	alicePrivate := prefix + "/alice/alice_private.dat"
@@ -87,8 +160,7 @@ func main() {
 	spaceString := "Data Transferred:\n"
 	fmt.Println("Time Cost:")
 	if steps[0] {
-		tumbler = new(protocol.Tumbler)
-		err = tumbler.Init(generatorFile, precomputesFile, tumblerPrivate, alicePublic, bobPublic,
+		tumbler, err = initTumbler(keydPath, generatorFile, precomputesFile, tumblerPrivate, alicePublic, bobPublic,
 			rlweSecret, rlwePublic)
 		if err != nil {
 			panic(err)
@@ -252,7 +324,10 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		yPrime := protocol.GetPointCompressed(yPrimeBytes)
+		yPrime, err := protocol.GetPointCompressed(yPrimeBytes)
+		if err != nil {
+			panic(err)
+		}
 		d1 := time.Since(start)
 		start = time.Now()
 		var sigAlice *adaptor.Signature
@@ -295,9 +370,8 @@ func main() {
 
 	if steps[3] {
 		if tumbler == nil {
-			tumbler = new(protocol.Tumbler)
-			err = tumbler.Init(generatorFile, precomputesFile, tumblerPrivate, alicePublic, bobPublic,
-				rlweSecret, rlwePublic)
+			tumbler, err = initTumbler(keydPath, generatorFile, precomputesFile, tumblerPrivate, alicePublic,
+				bobPublic, rlweSecret, rlwePublic)
 			if err != nil {
 				panic(err)
 			}
@@ -342,7 +416,10 @@ func main() {
 			lweCipherList[i] = lpr.Extract(newCiphertext, protocol.Q, index*64+i)
 		}
 
-		yPrime := protocol.GetPointCompressed(yPrimeBytes)
+		yPrime, err := protocol.GetPointCompressed(yPrimeBytes)
+		if err != nil {
+			panic(err)
+		}
 		sigAlice := new(adaptor.Signature)
 		sigAlice.E = new(big.Int).SetBytes(sigAliceBytes[0:32])
 		sigAlice.S = new(big.Int).SetBytes(sigAliceBytes[32:64])
@@ -369,7 +446,7 @@ func main() {
 			panic(err)
 		}
 
-		if adaptor.SchnorrVerify(sigAliceRecovered, tx2, tumbler.AlicePublic, sha256.New()) {
+		if adaptor.SchnorrVerify(sigAliceRecovered, tx2, tumbler.AlicePublic, protocol.NewHash()) {
 			fmt.Println("Recovered signature of alice verified")
 		} else {
 			panic("Recovered signature of alice not verified")
@@ -450,7 +527,7 @@ func main() {
 		fmt.Println("Step6: Time cost in all", d1+d2)
 		fmt.Printf("\t--Deserialization of data received: %v\n", d1)
 		fmt.Printf("\t--Tumbler's Signature recovery : %v\n", d2)
-		if adaptor.SchnorrVerify(sigTumblerReal, tx, bob.TumblerPublic, sha256.New()) {
+		if adaptor.SchnorrVerify(sigTumblerReal, tx, bob.TumblerPublic, protocol.NewHash()) {
 			fmt.Println("Recovered signature of Tumbler verified")
 		} else {
 			panic("Recovered signature of Tumbler not verified")
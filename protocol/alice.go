@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"crypto/sha256"
 	"io"
 	"math/big"
 	"os"
@@ -19,39 +18,35 @@ type Alice struct {
 	adaptorSig *adaptor.Signature
 }
 
+// NewAlice sets up Alice from already loaded keys.
+func NewAlice(key *PrivateKey, tumblerPublic, bobPublic vc.FastPoint) *Alice {
+	return &Alice{
+		TumblerPublic: tumblerPublic,
+		BobPublic:     bobPublic,
+		Secret:        key.Secret,
+		Public:        key.Public,
+	}
+}
+
 func (alice *Alice) Init(tumblerPath, secretPath, bobPath string) error {
-	secretBytes, err := os.ReadFile(secretPath)
+	key, err := LoadPrivateKey(secretPath)
 	if err != nil {
 		return err
 	}
-	alice.Secret = new(big.Int).SetBytes(secretBytes[0:32])
-	publicX := new(big.Int).SetBytes(secretBytes[32:64])
-	publicY := new(big.Int).SetBytes(secretBytes[64:96])
-	alice.Public = fastCurve.NewPoint()
-	alice.Public.From(publicX, publicY)
-
-	tumblerBytes, err := os.ReadFile(tumblerPath)
+	tumblerPublic, err := LoadPublicKey(tumblerPath)
 	if err != nil {
 		return err
 	}
-	publicX = new(big.Int).SetBytes(tumblerBytes[0:32])
-	publicY = new(big.Int).SetBytes(tumblerBytes[32:64])
-	alice.TumblerPublic = fastCurve.NewPoint()
-	alice.TumblerPublic.From(publicX, publicY)
-
-	bobBytes, err := os.ReadFile(bobPath)
+	bobPublic, err := LoadPublicKey(bobPath)
 	if err != nil {
 		return err
 	}
-	publicX = new(big.Int).SetBytes(bobBytes[0:32])
-	publicY = new(big.Int).SetBytes(bobBytes[32:64])
-	alice.BobPublic = fastCurve.NewPoint()
-	alice.BobPublic.From(publicX, publicY)
+	*alice = *NewAlice(key, tumblerPublic, bobPublic)
 	return nil
 }
 
 func (alice *Alice) Step3(tx []byte, yPrime vc.FastPoint, random io.Reader) (*adaptor.Signature, error) {
-	sig, err := adaptor.SchnorrSignAdaptor(tx, yPrime, alice.Secret, sha256.New(), random)
+	sig, err := adaptor.SchnorrSignAdaptor(tx, yPrime, alice.Secret, newHash(), random)
 	if err != nil {
 		return nil, err
 	}
@@ -220,17 +220,22 @@ func initTumbler() (*protocol.Tumbler, vc.FastPoint, vc.FastPoint) {
 	b1List[B1-1].Sub(N, b1List[B1-1])
 	tumbler.B1List = b1List
 
-	tumbler.Secret, err = rand.Int(random, n1)
+	secret, err := rand.Int(random, n1)
 	if err != nil {
 		panic(err)
 	}
-	tumbler.Public = fastCurve.FastBaseScalar(tumbler.Secret.Bytes())
+	tumbler.Signer = protocol.NewLocalSigner(&protocol.PrivateKey{
+		Secret: secret,
+		Public: fastCurve.FastBaseScalar(secret.Bytes()),
+	})
+	tumbler.Public = tumbler.Signer.Public()
 
-	tumbler.RLWESecret, err = lpr.GenSecret(D, random)
+	rlweSecret, err := lpr.GenSecret(D, random)
 	if err != nil {
 		panic(err)
 	}
-	tumbler.RLWEPublic, err = lpr.GenPublicKey(tumbler.RLWESecret, Q, random)
+	tumbler.Decrypter = protocol.NewLocalDecrypter(rlweSecret)
+	tumbler.RLWEPublic, err = lpr.GenPublicKey(rlweSecret, Q, random)
 	if err != nil {
 		panic(err)
 	}
@@ -1,9 +1,7 @@
 package protocol
 
 import (
-	"crypto/sha256"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"math"
 	"math/big"
@@ -30,161 +28,56 @@ type Bob struct {
 	Public     vc.FastPoint
 	RLWEPublic *lpr.PublicKey
 
+	// TumblerTable is optional and speeds up checking the tumbler's signature
+	TumblerTable vc.Precomputed
+
 	rdmPlaintext *big.Int
 	adaptorSig   *adaptor.Signature
 }
 
-func (bob *Bob) Init(genPath, prePath, tumblerPath, alicePath, secretPath, rlwePublic string) error {
-	ghuBytes, err := os.ReadFile(genPath)
-	if err != nil {
-		return err
+// NewBob sets up Bob from already loaded parameters and keys.
+func NewBob(params *PublicParams, key *PrivateKey, rlwePublic *lpr.PublicKey,
+	tumblerPublic, alicePublic vc.FastPoint) *Bob {
+	return &Bob{
+		G:             params.G,
+		H:             params.H,
+		U:             params.U,
+		TumblerPublic: tumblerPublic,
+		AlicePublic:   alicePublic,
+		HSum1:         params.HSum1,
+		HSum2:         params.HSum2,
+		Box:           newBox(),
+		B1List:        newB1List(),
+		Secret:        key.Secret,
+		Public:        key.Public,
+		RLWEPublic:    rlwePublic,
 	}
-	if int32(len(ghuBytes)) < (L*2+1)*64 {
-		return fmt.Errorf("Length error: %d, %d\n", len(ghuBytes), (L*2+1)*64)
-	}
-	bob.G = make([]vc.FastPoint, L)
-	bob.H = make([]vc.FastPoint, L)
-	for i := int32(0); i < L; i++ {
-		bob.G[i] = fastCurve.NewPoint()
-		gx := new(big.Int).SetBytes(ghuBytes[i*64 : i*64+32])
-		gy := new(big.Int).SetBytes(ghuBytes[i*64+32 : i*64+64])
-		bob.G[i].From(gx, gy)
-	}
-	hBytes := ghuBytes[64*L:]
-	for i := int32(0); i < L; i++ {
-		bob.H[i] = fastCurve.NewPoint()
-		hx := new(big.Int).SetBytes(hBytes[i*64 : i*64+32])
-		hy := new(big.Int).SetBytes(hBytes[i*64+32 : i*64+64])
-		bob.H[i].From(hx, hy)
-	}
-	uBytes := ghuBytes[64*2*L:]
-	bob.U = fastCurve.NewPoint()
-	ux := new(big.Int).SetBytes(uBytes[0:32])
-	uy := new(big.Int).SetBytes(uBytes[32:64])
-	bob.U.From(ux, uy)
-
-	secretBytes, err := os.ReadFile(secretPath)
-	if err != nil {
-		return err
-	}
-	bob.Secret = new(big.Int).SetBytes(secretBytes[0:32])
-	publicX := new(big.Int).SetBytes(secretBytes[32:64])
-	publicY := new(big.Int).SetBytes(secretBytes[64:96])
-	bob.Public = fastCurve.NewPoint()
-	bob.Public.From(publicX, publicY)
+}
 
-	tumblerBytes, err := os.ReadFile(tumblerPath)
+func (bob *Bob) Init(genPath, prePath, tumblerPath, alicePath, secretPath, rlwePublic string) error {
+	params, err := LoadPublicParams(genPath, prePath)
 	if err != nil {
 		return err
 	}
-	publicX = new(big.Int).SetBytes(tumblerBytes[0:32])
-	publicY = new(big.Int).SetBytes(tumblerBytes[32:64])
-	bob.TumblerPublic = fastCurve.NewPoint()
-	bob.TumblerPublic.From(publicX, publicY)
-
-	aliceBytes, err := os.ReadFile(alicePath)
+	key, err := LoadPrivateKey(secretPath)
 	if err != nil {
 		return err
 	}
-	publicX = new(big.Int).SetBytes(aliceBytes[0:32])
-	publicY = new(big.Int).SetBytes(aliceBytes[32:64])
-	bob.AlicePublic = fastCurve.NewPoint()
-	bob.AlicePublic.From(publicX, publicY)
-
-	box := make([][]*big.Int, 16)
-	for i := 0; i < 16; i++ {
-		box[i] = make([]*big.Int, D)
-		for j := 0; j < int(D); j++ {
-			box[i][j] = big.NewInt(0)
-		}
-	}
-
-	tmpForBox := make([]*big.Int, 64)
-	tmpForBox[0] = big.NewInt(1)
-	for i := 1; i < 64; i++ {
-		tmpForBox[i] = new(big.Int).Mul(tmpForBox[i-1], big.NewInt(int64(Step)))
-	}
-	for i := 0; i < 16; i++ {
-		for j := 0; j < 64; j++ {
-			box[i][i*64+j] = new(big.Int).Set(tmpForBox[j])
-		}
-	}
-	boxPrime := make([][]*big.Int, 16)
-	for i := 0; i < 16; i++ {
-		boxPrime[i] = make([]*big.Int, D*2)
-	}
-	N := fastCurve.Params().N
-	minus2 := new(big.Int).Sub(N, big.NewInt(2))
-	for i := 0; i < 16; i++ {
-		for j := 0; j < int(D); j++ {
-			d1 := new(big.Int).Set(box[i][j])
-			d2 := new(big.Int).Mul(box[i][j], minus2)
-			d1.Mod(d1, N)
-			d2.Mod(d2, N)
-			boxPrime[i][j*2] = d1
-			boxPrime[i][j*2+1] = d2
-		}
-	}
-	bob.Box = boxPrime
-
-	b1List := make([]*big.Int, B1)
-	for i := int32(0); i < B1; i++ {
-		b1List[i] = big.NewInt(int64(1) << i)
-	}
-	b1List[B1-1].Sub(N, b1List[B1-1])
-	bob.B1List = b1List
-
-	preFile, err := os.Open(prePath)
+	tumblerPublic, err := LoadPublicKey(tumblerPath)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = preFile.Close() }()
-
-	precomputes := make([]byte, 8*64)
-	for i := int32(0); i < L; i++ {
-		_, err = preFile.Read(precomputes)
-		if err != nil {
-			return err
-		}
-		bob.G[i].ImportTable(precomputes, true)
-	}
-	for i := int32(0); i < L; i++ {
-		_, err = preFile.Read(precomputes)
-		if err != nil {
-			return err
-		}
-		bob.H[i].ImportTable(precomputes, true)
-	}
-	pointBytes := make([]byte, 64)
-	_, err = preFile.Read(pointBytes)
+	alicePublic, err := LoadPublicKey(alicePath)
 	if err != nil {
 		return err
 	}
-	hx := new(big.Int).SetBytes(pointBytes[0:32])
-	hy := new(big.Int).SetBytes(pointBytes[32:64])
-	bob.HSum1 = fastCurve.NewPoint()
-	bob.HSum1.From(hx, hy)
-
-	_, err = preFile.Read(pointBytes)
+	rlwePublicKey, err := LoadRLWEPublicKey(rlwePublic)
 	if err != nil {
 		return err
 	}
-	hx = new(big.Int).SetBytes(pointBytes[0:32])
-	hy = new(big.Int).SetBytes(pointBytes[32:64])
-	bob.HSum2 = fastCurve.NewPoint()
-	bob.HSum2.From(hx, hy)
-
-	rlwePublicBytes, err := os.ReadFile(rlwePublic)
-	if err != nil {
-		panic(err)
-	}
-	bob.RLWEPublic = new(lpr.PublicKey)
-	err = bob.RLWEPublic.Deserialize(rlwePublicBytes, D, Q)
-	if err != nil {
-		panic(err)
-	}
-
-	return nil
+	*bob = *NewBob(params, key, rlwePublicKey, tumblerPublic, alicePublic)
+	bob.TumblerTable, err = LoadPrecomputed(tumblerPath+".pre", tumblerPublic)
+	return err
 }
 
 func (bob *Bob) Step2(tx []byte, proof *Proof, rlweCipher *lpr.Ciphertext, y []vc.FastPoint,
@@ -195,9 +88,14 @@ func (bob *Bob) Step2(tx []byte, proof *Proof, rlweCipher *lpr.Ciphertext, y []v
 		return nil, nil, err
 	}
 
-	verified := adaptor.SchnorrPreVerifyAdaptor(sig, tx, y[index], bob.TumblerPublic, sha256.New())
+	var verified bool
+	if bob.TumblerTable != nil {
+		verified = adaptor.SchnorrPreVerifyAdaptorPrecomputed(sig, tx, y[index], bob.TumblerTable, newHash())
+	} else {
+		verified = adaptor.SchnorrPreVerifyAdaptor(sig, tx, y[index], bob.TumblerPublic, newHash())
+	}
 	if !verified {
-		return nil, nil, fmt.Errorf("Adaptor signature not verified\n")
+		return nil, nil, &AdaptorError{Party: "tumbler"}
 	}
 
 	rdmPlainData, err := lpr.GenerateRq(D, T/2, random)
@@ -237,7 +135,7 @@ func (bob *Bob) Verify(proof *Proof, rlweCipher *lpr.Ciphertext, puzzleKey *lpr.
 	vectorT.T0 = rlweCipher.CT0
 	vectorT.T1 = rlweCipher.CT1
 
-	var vectorV []*big.Int
+	var vectorV []vc.FastBn
 	if coreNum > 1 {
 		vectorV = CalcLargeVectorVMultiCore(rp, matrixA, bob.B1List)
 	} else {
@@ -250,6 +148,13 @@ func (bob *Bob) Verify(proof *Proof, rlweCipher *lpr.Ciphertext, puzzleKey *lpr.
 	ipssPieces := make([]vc.FastPoint, coreNum)
 	gScalar := make([][]byte, L)
 	gFactor := make([]vc.FastBn, LP)
+	phi := newBnList(rp.Phi)
+	for i := int32(0); i < L; i++ {
+		gFactor[i] = fastCurve.NewBn()
+		gFactor[i].CopyFrom(phi[i])
+	}
+	fastCurve.BatchInverse(gFactor[:L])
+	psi := newBn(rp.Psi)
 
 	var wg sync.WaitGroup
 	for t := 0; t < coreNum; t++ {
@@ -258,18 +163,11 @@ func (bob *Bob) Verify(proof *Proof, rlweCipher *lpr.Ciphertext, puzzleKey *lpr.
 		wg.Add(1)
 		go func(s, e, index int) {
 			defer wg.Done()
+			tmp := fastCurve.NewBn()
 			for i := s; i < e; i++ {
-				inv := fastCurve.Inverse(rp.Phi[i])
-				gFactor[i] = fastCurve.NewBn()
-				gFactor[i].From(inv)
-
-				tmp := new(big.Int).Mul(rp.Phi[i], rp.Psi)
-				tmp.Mod(tmp, N)
+				tmp.Mul(phi[i], psi)
 				tmp.Add(tmp, vectorV[i])
-				tmp.Mod(tmp, N)
-				tmp.Mul(tmp, inv)
-				tmp.Mod(tmp, N)
-				gScalar[i] = tmp.Bytes()
+				gScalar[i] = tmp.Mul(tmp, gFactor[i]).Back()
 			}
 
 			ipssPieces[index] = fastCurve.NewPoint()
@@ -317,34 +215,30 @@ func (bob *Bob) Verify(proof *Proof, rlweCipher *lpr.Ciphertext, puzzleKey *lpr.
 	//	fastCurve.FastPointAdd(Cipsp, Cipsp, tmpPoint)
 	//}
 
-	x := big.NewInt(0)
+	gamma := newBnList(rp.Gamma)
+	x := fastCurve.NewBn().SetInt64(0)
+	tmpVal := fastCurve.NewBn()
 	for i := int32(0); i < D; i++ {
-		tmpVal := new(big.Int).Mul(big.NewInt(int64(vectorT.T0[i])), rp.Gamma[i])
-		x.Add(x, tmpVal)
-		x.Mod(x, N)
-		tmpVal.Mul(big.NewInt(int64(vectorT.T1[i])), rp.Gamma[i+D])
-		x.Add(x, tmpVal)
-		x.Mod(x, N)
+		tmpVal.SetInt64(int64(vectorT.T0[i]))
+		x.Add(x, tmpVal.Mul(tmpVal, gamma[i]))
+		tmpVal.SetInt64(int64(vectorT.T1[i]))
+		x.Add(x, tmpVal.Mul(tmpVal, gamma[i+D]))
 	}
 
-	sumV := new(big.Int).Add(vectorV[0], vectorV[1])
-	for i := int32(2); i < L; i++ {
+	sumV := fastCurve.NewBn().SetInt64(0)
+	for i := int32(0); i < L; i++ {
 		sumV.Add(sumV, vectorV[i])
-		sumV.Mod(sumV, N)
 	}
-	x.Add(x, new(big.Int).Mul(sumV, rp.Psi))
-	x.Mod(x, N)
+	x.Add(x, tmpVal.Mul(sumV, psi))
 
-	psiSum := new(big.Int).Add(rp.Psi, new(big.Int).Mul(rp.Psi, rp.Psi))
-	psiSum.Mod(psiSum, N)
+	psiSum := fastCurve.NewBn().Square(psi)
+	psiSum.Add(psiSum, psi)
 
-	sumPhi := new(big.Int).Add(rp.Phi[0], rp.Phi[1])
-	for i := int32(2); i < L; i++ {
-		sumPhi.Add(sumPhi, rp.Phi[i])
-		sumPhi.Mod(sumPhi, N)
+	sumPhi := fastCurve.NewBn().SetInt64(0)
+	for i := int32(0); i < L; i++ {
+		sumPhi.Add(sumPhi, phi[i])
 	}
-	x.Add(x, new(big.Int).Mul(sumPhi, psiSum))
-	x.Mod(x, N)
+	x.Add(x, tmpVal.Mul(sumPhi, psiSum))
 
 	data := make([]byte, 2*D*2)
 	for i := int32(0); i < D; i++ {
@@ -353,25 +247,24 @@ func (bob *Bob) Verify(proof *Proof, rlweCipher *lpr.Ciphertext, puzzleKey *lpr.
 	for i := int32(0); i < D; i++ {
 		binary.LittleEndian.PutUint16(data[2*D+i*2:], uint16(vectorT.T1[i]))
 	}
-	challengeBytes := sha256.Sum256(data)
+	challengeBytes := hashSum(data)
 	hashData := GetChallengeData(challengeBytes[:], []vc.FastPoint{proof.W1, proof.W2, proof.W3}, nil)
 	hashBig := new(big.Int).SetBytes(hashData)
 	hashBig.Mod(hashBig, N)
 	hashR := fastCurve.FastBaseScalar(hashBig.Bytes())
 
 	CipssPrime := fastCurve.NewPoint()
-	fastCurve.FastScalarMult(CipssPrime, hashR, x.Bytes())
+	fastCurve.FastScalarMult(CipssPrime, hashR, x.Back())
 	fastCurve.FastPointAdd(CipssPrime, CipssPrime, Cipss)
 
 	verified, challenge := bob.VerifySub1(hashR, CipssPrime, proof, hashData, gFactor, rp.Eta)
 	if !verified {
-		fmt.Println("Failed to verify sub proof 1")
-		//return fmt.Errorf("Failed to verify sub proof 1\n")
+		return &ProofError{Check: "sub proof 1"}
 	}
 
 	verified = bob.VerifySub2(fPrime, Cipsp, vectorZ, challenge, proof)
 	if !verified {
-		return fmt.Errorf("Failed to verify sub proof 2\n")
+		return &ProofError{Check: "sub proof 2"}
 	}
 
 	return nil
@@ -515,30 +408,29 @@ func (bob *Bob) VerifySub1(hashR, CipssPrime vc.FastPoint, proof *Proof, challen
 	ecList = []vc.FastPoint{gPieces[0], hPieces[0], hashR, bob.U}
 	scalarList := [][]byte{proof.Sub1.E1.Bytes(), proof.Sub1.E2.Bytes(), tmpVal.Bytes(), proof.Sub1.O.Bytes()}
 	fastCurve.FastPolynomial(ecRight, ecList, scalarList)
-	ecRight.Neg()
-	zeroPoint := fastCurve.NewPoint()
-	fastCurve.FastPointAdd(zeroPoint, ecLeft, ecRight)
-	return zeroPoint.IsZero(), challengeBytes
+	return ecLeft.Equal(ecRight), challengeBytes
 }
 
-func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []*big.Int, challengeBytes []byte, proof *Proof) bool {
+func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []vc.FastBn, challengeBytes []byte, proof *Proof) bool {
 	h := bob.H[3*D*B : 3*D*B+D*BPrime]
 	N := fastCurve.Params().N
 	count := int32(math.Log2(float64(D * BPrime)))
 	length := 1 << count
 
-	zSlot := make([]*big.Int, length)
+	zSlot := make([]vc.FastBn, length)
 	hSlot := make([]vc.FastPoint, length)
 	hFactor := make([]vc.FastBn, length)
 	hScalar := make([][]byte, length)
 
 	for i := int32(0); i < D*BPrime; i++ {
-		zSlot[i] = new(big.Int).Set(vectorZ[i])
+		zSlot[i] = fastCurve.NewBn()
+		zSlot[i].CopyFrom(vectorZ[i])
 		hFactor[i] = fastCurve.NewBn()
 		hSlot[i] = h[i]
 	}
 	for i := D * BPrime; i < int32(length); i++ {
-		zSlot[i] = new(big.Int).Set(vectorZ[L-1])
+		zSlot[i] = fastCurve.NewBn()
+		zSlot[i].CopyFrom(vectorZ[L-1])
 		hFactor[i] = fastCurve.NewBn()
 		hSlot[i] = h[L-1]
 	}
@@ -560,10 +452,7 @@ func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []*big.Int, challengeB
 		cList[stackDepth] = hashC.Bytes()
 		cInvList[stackDepth] = hashCInv.Bytes()
 
-		bnC := fastCurve.NewBn()
-		bnC.From(hashC)
-		bnCInv := fastCurve.NewBn()
-		bnCInv.From(hashCInv)
+		bnC := newBn(hashC)
 		if coreNum > 1 {
 			var wg sync.WaitGroup
 			for t := 0; t < coreNum; t++ {
@@ -574,11 +463,9 @@ func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []*big.Int, challengeB
 					start := index * seg / coreNum
 					end := (index + 1) * seg / coreNum
 
+					tmpVal := fastCurve.NewBn()
 					for i := start; i < end; i++ {
-						tmpVal := new(big.Int).Mul(zSlot[seg+i], hashC)
-						tmpVal.Mod(tmpVal, N)
-						zSlot[i].Add(zSlot[i], tmpVal)
-						zSlot[i].Mod(zSlot[i], N)
+						zSlot[i].Add(zSlot[i], tmpVal.Mul(zSlot[seg+i], bnC))
 					}
 					start = index * len(hFactor) / coreNum
 					end = (index + 1) * len(hFactor) / coreNum
@@ -602,11 +489,9 @@ func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []*big.Int, challengeB
 			}
 			wg.Wait()
 		} else {
+			tmpVal := fastCurve.NewBn()
 			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(zSlot[half+i], hashC)
-				tmpVal.Mod(tmpVal, N)
-				zSlot[i].Add(zSlot[i], tmpVal)
-				zSlot[i].Mod(zSlot[i], N)
+				zSlot[i].Add(zSlot[i], tmpVal.Mul(zSlot[half+i], bnC))
 			}
 			if stackDepth > 0 {
 				for i := 0; i < len(hFactor); i++ {
@@ -662,15 +547,13 @@ func (bob *Bob) VerifySub2(f, Cipsp vc.FastPoint, vectorZ []*big.Int, challengeB
 	randomXi.Mod(randomXi, N)
 
 	ecRight := fastCurve.NewPoint()
-	tmpVal := new(big.Int).Mul(zSlot[0], proof.Sub2.E1)
+	tmpVal := new(big.Int).Mul(zSlot[0].Big(), proof.Sub2.E1)
 	tmpVal.Mod(tmpVal, N)
 	ecList := []vc.FastPoint{Cipsp, hPieces[0], f, bob.U}
 	scalarList := [][]byte{randomXi.Bytes(), proof.Sub2.E1.Bytes(), tmpVal.Bytes(), proof.Sub2.E2.Bytes()}
 	fastCurve.FastPolynomial(ecRight, ecList, scalarList)
-	ecRight.Neg()
-	fastCurve.FastPointAdd(ecRight, ecRight, proof.Sub2.BigC)
 
-	return ecRight.IsZero()
+	return ecRight.Equal(proof.Sub2.BigC)
 }
 
 func (bob *Bob) Step6(plainNum *big.Int) *adaptor.Signature {
@@ -1,9 +1,33 @@
 package protocol
 
-import "volley/curve"
+import (
+	"crypto/sha256"
+	"hash"
+	"volley/curve"
+)
 
 var fastCurve curve.FastCurve
 
+// newHash builds the hash behind challenges, the random parameters and the
+// adaptor signatures. It must produce 32 bytes.
+var newHash = sha256.New
+
 func SetCurve(c curve.FastCurve) {
 	fastCurve = c
 }
+
+// SetHash selects the hash, SHA-256 by default.
+func SetHash(h func() hash.Hash) {
+	newHash = h
+}
+
+// NewHash returns a new instance of the hash selected with SetHash.
+func NewHash() hash.Hash {
+	return newHash()
+}
+
+func hashSum(data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
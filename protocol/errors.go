@@ -0,0 +1,86 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrProofInvalid means the tumbler's proof failed to verify. The
+	// returned error is a *ProofError naming the check that failed.
+	ErrProofInvalid = errors.New("Proof invalid\n")
+	// ErrAdaptorPreVerify means an adaptor pre-signature failed to verify.
+	// The returned error is an *AdaptorError naming the signer.
+	ErrAdaptorPreVerify = errors.New("Adaptor signature not verified\n")
+	// ErrPuzzleMismatch means the decrypted puzzle solution doesn't open Y'.
+	ErrPuzzleMismatch = errors.New("Y not verified\n")
+	// ErrParamMismatch means a parameter or key file doesn't match the
+	// compiled protocol parameters. The returned error is a *ParamError.
+	ErrParamMismatch = errors.New("Parameter mismatch\n")
+	// ErrMalformed means a message received from another party couldn't be
+	// decoded. The returned error is a *MalformedError.
+	ErrMalformed = errors.New("Malformed data\n")
+)
+
+type ProofError struct {
+	Check string
+}
+
+func (e *ProofError) Error() string {
+	return "Failed to verify " + e.Check + "\n"
+}
+
+func (e *ProofError) Is(target error) bool {
+	return target == ErrProofInvalid
+}
+
+type AdaptorError struct {
+	Party string
+}
+
+func (e *AdaptorError) Error() string {
+	return "Failed to verify adaptor signature of " + e.Party + "\n"
+}
+
+func (e *AdaptorError) Is(target error) bool {
+	return target == ErrAdaptorPreVerify
+}
+
+type ParamError struct {
+	Path string
+	Got  int
+	Want int
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Invalid parameter file %s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("Length error in %s: %d, %d\n", e.Path, e.Got, e.Want)
+}
+
+func (e *ParamError) Is(target error) bool {
+	return target == ErrParamMismatch
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+type MalformedError struct {
+	What string
+	Err  error
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("%s deserialization error: %v", e.What, e.Err)
+}
+
+func (e *MalformedError) Is(target error) bool {
+	return target == ErrMalformed
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,69 @@
+package protocol_test
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"volley/adaptor"
+	"volley/lpr"
+	"volley/protocol"
+)
+
+func TestErrors(t *testing.T) {
+	initFuzz()
+
+	var err error = &protocol.ProofError{Check: "sub proof 1"}
+	if !errors.Is(err, protocol.ErrProofInvalid) {
+		t.Fatal("ProofError is not ErrProofInvalid")
+	}
+	err = &protocol.AdaptorError{Party: "alice"}
+	if !errors.Is(err, protocol.ErrAdaptorPreVerify) || errors.Is(err, protocol.ErrProofInvalid) {
+		t.Fatal("AdaptorError matches the wrong sentinel")
+	}
+
+	_, err = protocol.DeserializeYListCompressed(make([]byte, 3))
+	if !errors.Is(err, protocol.ErrMalformed) {
+		t.Fatal("short Y list is not ErrMalformed:", err)
+	}
+	_, err = protocol.GetPointCompressed(make([]byte, 33))
+	var malformed *protocol.MalformedError
+	if !errors.As(err, &malformed) {
+		t.Fatal("bad point prefix is not a MalformedError:", err)
+	}
+
+	tumbler := new(protocol.Tumbler)
+	err = tumbler.Init("proof_test.go", "", "", "", "", "", "")
+	var param *protocol.ParamError
+	if !errors.As(err, &param) || !errors.Is(err, protocol.ErrParamMismatch) {
+		t.Fatal("short generator file is not a ParamError:", err)
+	}
+	if param.Path != "proof_test.go" {
+		t.Fatal("ParamError has the wrong path:", param.Path)
+	}
+}
+
+func TestStep4Malformed(t *testing.T) {
+	fastCurve := initFuzz()
+	adaptor.SetCurve(fastCurve)
+	secrets := randomScalars(t, fastCurve, 2)
+	tumbler := &protocol.Tumbler{AlicePublic: fastCurve.FastBaseScalar(secrets[0].Bytes())}
+	y := fastCurve.FastBaseScalar(secrets[1].Bytes())
+	tx := []byte("tx")
+	sig, err := adaptor.SchnorrSignAdaptor(tx, y, secrets[0], sha256.New(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// short or incomplete lists from Bob never reach the decrypter
+	for _, cipherList := range [][]*lpr.LWECiphertext{
+		nil,
+		make([]*lpr.LWECiphertext, 63),
+		make([]*lpr.LWECiphertext, 64),
+	} {
+		_, err = tumbler.Step4(tx, sig, y, cipherList)
+		if !errors.Is(err, protocol.ErrMalformed) {
+			t.Fatalf("%d ciphertexts: %v", len(cipherList), err)
+		}
+	}
+}
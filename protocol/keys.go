@@ -0,0 +1,349 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	vc "volley/curve"
+	"volley/keystore"
+	"volley/lpr"
+	"volley/secp256k1"
+	"volley/secp256k1/taproot"
+)
+
+// PrivateKey is a key pair of one of the parties on the curve set with
+// SetCurve.
+type PrivateKey struct {
+	Secret *big.Int
+	Public vc.FastPoint
+}
+
+// NewPrivateKey derives the public key of secret.
+func NewPrivateKey(secret *big.Int) (*PrivateKey, error) {
+	if secret.Sign() <= 0 || secret.Cmp(fastCurve.Params().N) >= 0 {
+		return nil, fmt.Errorf("Secret out of range\n")
+	}
+	return &PrivateKey{
+		Secret: new(big.Int).Set(secret),
+		Public: fastCurve.FastBaseScalarSecret(secret.Bytes()),
+	}, nil
+}
+
+// SharedSecret returns the x coordinate of Secret*public, the ECDH secret
+// key shares with the owner of public.
+func (key *PrivateKey) SharedSecret(public vc.FastPoint) ([]byte, error) {
+	shared := fastCurve.NewPoint()
+	fastCurve.FastScalarMultSecret(shared, public, key.Secret.Bytes())
+	if shared.IsZero() {
+		return nil, fmt.Errorf("Shared secret is infinity\n")
+	}
+	x, _ := shared.Back()
+	secret := make([]byte, 32)
+	x.FillBytes(secret)
+	return secret, nil
+}
+
+// ParsePrivateKey decodes the 96-byte secret || X || Y format written by
+// GenKey, checking that the stored public key matches the secret. On
+// secp256k1 it also reads SEC1 and PKCS #8 PEM keys as written by OpenSSL.
+func ParsePrivateKey(data []byte) (*PrivateKey, error) {
+	if secp256k1.IsPEM(data) {
+		if err := checkSecp256k1("PEM keys"); err != nil {
+			return nil, err
+		}
+		key, err := secp256k1.DecodePEMPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(key.D)
+	}
+	if len(data) != 96 {
+		return nil, fmt.Errorf("Private key length error: %d, %d\n", len(data), 96)
+	}
+	key, err := NewPrivateKey(new(big.Int).SetBytes(data[0:32]))
+	if err != nil {
+		return nil, err
+	}
+	public, err := getPoint(data[32:96])
+	if err != nil {
+		return nil, err
+	}
+	x1, y1 := key.Public.Back()
+	x2, y2 := public.Back()
+	if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+		return nil, fmt.Errorf("Public key doesn't match secret\n")
+	}
+	return key, nil
+}
+
+// ParsePublicKey decodes a 64-byte X || Y public key, or on secp256k1 a
+// "PUBLIC KEY" PEM block.
+func ParsePublicKey(data []byte) (vc.FastPoint, error) {
+	if secp256k1.IsPEM(data) {
+		if err := checkSecp256k1("PEM keys"); err != nil {
+			return nil, err
+		}
+		pub, err := secp256k1.DecodePEMPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+		point := fastCurve.NewPoint()
+		point.From(pub.X, pub.Y)
+		return point, nil
+	}
+	if len(data) != 64 {
+		return nil, fmt.Errorf("Public key length error: %d, %d\n", len(data), 64)
+	}
+	return getPoint(data)
+}
+
+// checkSecp256k1 fails unless the curve is secp256k1, the only one with PEM
+// keys and BIP-32 derivation.
+func checkSecp256k1(what string) error {
+	if name := fastCurve.Params().Name; name != "secp256k1" {
+		return fmt.Errorf("%s are not supported on %s\n", what, name)
+	}
+	return nil
+}
+
+// DeriveSessionKey returns the key of a party for one session, the hardened
+// child m/session' of master. Session keys can't be linked to each other or
+// to master, and all of them are recovered from the seed of master.
+func DeriveSessionKey(master *secp256k1.ExtendedKey, session uint32) (*PrivateKey, error) {
+	if err := checkSecp256k1("BIP-32 keys"); err != nil {
+		return nil, err
+	}
+	if !master.IsPrivate() {
+		return nil, fmt.Errorf("Session keys need a private extended key\n")
+	}
+	if session >= secp256k1.HardenedOffset {
+		return nil, fmt.Errorf("Session index %d out of range\n", session)
+	}
+	child, err := master.Child(session + secp256k1.HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(child.Private.D)
+}
+
+// taprootKeys makes the Load functions return Taproot output keys, see
+// SetTaprootKeys.
+var taprootKeys bool
+
+// SetTaprootKeys makes LoadPrivateKey and LoadPublicKey return the output
+// keys of the key path only Taproot outputs whose internal keys are in the
+// files, so signatures are made and checked with the keys the outputs commit
+// to. Only secp256k1 has Taproot.
+func SetTaprootKeys(enable bool) {
+	taprootKeys = enable
+}
+
+// taprootPrivateKey returns the key of the Taproot output of key.
+func taprootPrivateKey(key *PrivateKey) (*PrivateKey, error) {
+	if err := checkSecp256k1("Taproot keys"); err != nil {
+		return nil, err
+	}
+	internal, err := secp256k1.NewPrivateKey(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	tweaked, err := taproot.TweakPrivateKey(internal, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(tweaked.D)
+}
+
+// taprootPublicKey returns the output key of the Taproot output of public.
+func taprootPublicKey(public vc.FastPoint) (vc.FastPoint, error) {
+	if err := checkSecp256k1("Taproot keys"); err != nil {
+		return nil, err
+	}
+	x, y := public.Back()
+	output, err := taproot.OutputKey(&secp256k1.PublicKey{X: x, Y: y}, nil)
+	if err != nil {
+		return nil, err
+	}
+	point := fastCurve.NewPoint()
+	point.From(output.X, output.Y)
+	return point, nil
+}
+
+// ParseRLWEPrivateKey decodes a secret written by GenKeyRLWE, one byte per
+// coefficient with any non-zero byte standing for -1.
+func ParseRLWEPrivateKey(data []byte) (*lpr.PrivateKey, error) {
+	if len(data) != int(D) {
+		return nil, fmt.Errorf("RLWE private key length error: %d, %d\n", len(data), D)
+	}
+	key := &lpr.PrivateKey{
+		Data: make([]int32, D),
+	}
+	for i := int32(0); i < D; i++ {
+		if data[i] != 0 {
+			key.Data[i] = -1
+		}
+	}
+	return key, nil
+}
+
+func ParseRLWEPublicKey(data []byte) (*lpr.PublicKey, error) {
+	key := new(lpr.PublicKey)
+	err := key.Deserialize(data, D, Q)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// passphrase returns the passphrase of the private key files, it is nil when
+// they are stored in plain.
+var passphrase func() ([]byte, error)
+
+// SetPassphrase makes GenKey and GenKeyRLWE encrypt the private keys they
+// write with the passphrase f returns, and lets LoadPrivateKey and
+// LoadRLWEPrivateKey open such keys. Plain keys are read either way.
+func SetPassphrase(f func() ([]byte, error)) {
+	passphrase = f
+}
+
+// readPrivateFile reads a private key file, decrypting it if it is a
+// keystore. Decryption errors aren't ParamErrors, a wrong passphrase only
+// matches keystore.ErrPassphrase.
+func readPrivateFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !keystore.IsKeystore(data) {
+		return data, err
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("Private key %s is encrypted and no passphrase is set\n", path)
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	data, err = keystore.Decrypt(data, pass)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decrypt %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// writePrivateFile writes a private key file readable by its owner only, as
+// a keystore when a passphrase is set.
+func writePrivateFile(path string, data []byte, random io.Reader) error {
+	if passphrase != nil {
+		pass, err := passphrase()
+		if err != nil {
+			return err
+		}
+		data, err = keystore.Encrypt(data, pass, keystore.DefaultParams, random)
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// OpenFile leaves the mode of an existing file alone
+	if err = f.Chmod(0600); err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func LoadPrivateKey(path string) (*PrivateKey, error) {
+	data, err := readPrivateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(data)
+	if err == nil && taprootKeys {
+		key, err = taprootPrivateKey(key)
+	}
+	if err != nil {
+		return nil, &ParamError{Path: path, Err: err}
+	}
+	return key, nil
+}
+
+func LoadPublicKey(path string) (vc.FastPoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePublicKey(data)
+	if err == nil && taprootKeys {
+		key, err = taprootPublicKey(key)
+	}
+	if err != nil {
+		return nil, &ParamError{Path: path, Err: err}
+	}
+	return key, nil
+}
+
+// precomputedWindow is the window of the public key tables built by
+// LoadPrecomputed.
+const precomputedWindow = 9
+
+// LoadPrecomputed returns the precomputed tables of public. It reads them from
+// path when that file holds valid tables of the same point, every entry being
+// checked as the file may be written by others, and otherwise builds them. It
+// never writes path, see CachePrecomputed. It returns nil if the curve has no
+// precomputed tables.
+func LoadPrecomputed(path string, public vc.FastPoint) (vc.Precomputed, error) {
+	precomputer, ok := fastCurve.(vc.Precomputer)
+	if !ok {
+		return nil, nil
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		table, err := precomputer.UnmarshalPrecomputed(data)
+		if err == nil && table.Point().Equal(public) {
+			return table, nil
+		}
+	}
+	return precomputer.NewPrecomputed(public, precomputedWindow)
+}
+
+// CachePrecomputed writes the precomputed tables of public to path, for
+// LoadPrecomputed to read them instead of building them. It does nothing if
+// the curve has no precomputed tables.
+func CachePrecomputed(path string, public vc.FastPoint) error {
+	table, err := LoadPrecomputed(path, public)
+	if err != nil || table == nil {
+		return err
+	}
+	data, err := table.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func LoadRLWEPrivateKey(path string) (*lpr.PrivateKey, error) {
+	data, err := readPrivateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseRLWEPrivateKey(data)
+	if err != nil {
+		return nil, &ParamError{Path: path, Err: err}
+	}
+	return key, nil
+}
+
+func LoadRLWEPublicKey(path string) (*lpr.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseRLWEPublicKey(data)
+	if err != nil {
+		return nil, &ParamError{Path: path, Err: err}
+	}
+	return key, nil
+}
@@ -0,0 +1,339 @@
+package protocol_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	vc "volley/curve"
+	"volley/keystore"
+	"volley/protocol"
+	"volley/secp256k1"
+	"volley/secp256k1/taproot"
+	"volley/sm2"
+)
+
+func TestLoadKeys(t *testing.T) {
+	initFuzz()
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.dat")
+	publicPath := filepath.Join(dir, "public.dat")
+	err := protocol.GenKey(privatePath, publicPath, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := protocol.LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := protocol.LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x1, y1 := key.Public.Back()
+	x2, y2 := public.Back()
+	if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+		t.Fatal("public key doesn't match private key")
+	}
+
+	data, err := os.ReadFile(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[31] ^= 1
+	if _, err = protocol.ParsePrivateKey(data); err == nil {
+		t.Fatal("accepted a private key whose public part doesn't match")
+	}
+	if _, err = protocol.ParsePrivateKey(data[:64]); err == nil {
+		t.Fatal("accepted a short private key")
+	}
+}
+
+func TestEncryptedKeys(t *testing.T) {
+	initFuzz()
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.dat")
+	publicPath := filepath.Join(dir, "public.dat")
+	rlwePath := filepath.Join(dir, "rlwe_private.dat")
+	// an existing world readable file must not stay so
+	if err := os.WriteFile(privatePath, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	pass := []byte("correct horse")
+	protocol.SetPassphrase(func() ([]byte, error) { return pass, nil })
+	defer protocol.SetPassphrase(nil)
+	if err := protocol.GenKey(privatePath, publicPath, rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if err := protocol.GenKeyRLWE(rlwePath, filepath.Join(dir, "rlwe_public.dat"), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{privatePath, rlwePath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+			t.Fatalf("%s has mode %v", path, info.Mode())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !keystore.IsKeystore(data) {
+			t.Fatalf("%s is not encrypted", path)
+		}
+	}
+
+	key, err := protocol.LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := protocol.LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Public.Equal(public) {
+		t.Fatal("public key doesn't match private key")
+	}
+	if _, err = protocol.LoadRLWEPrivateKey(rlwePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// a wrong passphrase is not a parameter mismatch
+	pass = []byte("battery staple")
+	_, err = protocol.LoadPrivateKey(privatePath)
+	_, rlweErr := protocol.LoadRLWEPrivateKey(rlwePath)
+	for _, err := range []error{err, rlweErr} {
+		if !errors.Is(err, keystore.ErrPassphrase) || errors.Is(err, protocol.ErrParamMismatch) {
+			t.Fatalf("wrong passphrase: %v", err)
+		}
+	}
+	protocol.SetPassphrase(nil)
+	_, err = protocol.LoadPrivateKey(privatePath)
+	if err == nil {
+		t.Fatal("opened an encrypted key without a passphrase")
+	}
+	if errors.Is(err, protocol.ErrParamMismatch) {
+		t.Fatalf("missing passphrase: %v", err)
+	}
+}
+
+func TestParsePEMKeys(t *testing.T) {
+	initFuzz()
+	key, err := ecdsa.GenerateKey(secp256k1.Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM, err := secp256k1.EncodePEMPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM, err := secp256k1.EncodePEMPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	private, err := protocol.ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := protocol.ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := public.Back()
+	if private.Secret.Cmp(key.D) != 0 || !private.Public.Equal(public) || x.Cmp(key.X) != 0 || y.Cmp(key.Y) != 0 {
+		t.Fatal("PEM keys decoded to another key")
+	}
+
+	protocol.SetCurve(sm2.FastCurve())
+	defer initFuzz()
+	if _, err = protocol.ParsePublicKey(publicPEM); err == nil {
+		t.Fatal("read a secp256k1 PEM key on SM2")
+	}
+}
+
+func TestLoadPrecomputed(t *testing.T) {
+	fastCurve := initFuzz()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "public.dat.pre")
+	points := randomPoints(t, fastCurve, 2)
+
+	// loading builds the tables without writing a cache
+	table, err := protocol.LoadPrecomputed(path, points[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("LoadPrecomputed wrote a cache: ", err)
+	}
+	scalar := randomScalars(t, fastCurve, 1)[0].Bytes()
+	want := fastCurve.NewPoint()
+	fastCurve.FastScalarMult(want, points[0], scalar)
+	got := fastCurve.NewPoint()
+	table.ScalarMult(got, scalar)
+	if !got.Equal(want) {
+		t.Fatal("precomputed multiplication mismatch")
+	}
+
+	if err = protocol.CachePrecomputed(path, points[0]); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("tables were not cached: ", err)
+	}
+	if err = protocol.CachePrecomputed(filepath.Join(dir, "missing", "public.dat.pre"), points[0]); err == nil {
+		t.Fatal("failed cache write was not reported")
+	}
+
+	// the cache is used for the same point only
+	table, err = protocol.LoadPrecomputed(path, points[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := table.MarshalBinary(); !bytes.Equal(data, cached) {
+		t.Fatal("cache was not used")
+	}
+	table, err = protocol.LoadPrecomputed(path, points[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !table.Point().Equal(points[1]) {
+		t.Fatal("stale cache was used for another point")
+	}
+
+	// a cache of the right point with a forged entry is rebuilt
+	forged := append([]byte(nil), cached...)
+	copy(forged[len(forged)-64:], cached[len(cached)-128:len(cached)-64])
+	if err = os.WriteFile(path, forged, 0644); err != nil {
+		t.Fatal(err)
+	}
+	table, err = protocol.LoadPrecomputed(path, points[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.ScalarMult(got, scalar)
+	if !got.Equal(want) {
+		t.Fatal("forged cache was used")
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	for _, fastCurve := range []vc.FastCurve{secp256k1.FastCurve(), sm2.FastCurve()} {
+		protocol.SetCurve(fastCurve)
+		scalars := randomScalars(t, fastCurve, 2)
+		alice, err := protocol.NewPrivateKey(scalars[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, err := protocol.NewPrivateKey(scalars[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		s1, err := alice.SharedSecret(bob.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, err := bob.SharedSecret(alice.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(s1, s2) {
+			t.Fatalf("%s: shared secrets differ", fastCurve.Params().Name)
+		}
+	}
+	initFuzz()
+}
+
+func TestDeriveSessionKey(t *testing.T) {
+	initFuzz()
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		t.Fatal(err)
+	}
+	master, err := secp256k1.NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := protocol.DeriveSessionKey(master, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := master.Derive("m/3'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := key.Public.Back()
+	if key.Secret.Cmp(child.Private.D) != 0 || x.Cmp(child.Public.X) != 0 || y.Cmp(child.Public.Y) != 0 {
+		t.Fatal("session key is not m/3'")
+	}
+	other, err := protocol.DeriveSessionKey(master, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Public.Equal(key.Public) {
+		t.Fatal("two sessions got the same key")
+	}
+	if _, err = protocol.DeriveSessionKey(master.Neuter(), 3); err == nil {
+		t.Fatal("derived a session key from a public key")
+	}
+
+	protocol.SetCurve(sm2.FastCurve())
+	defer initFuzz()
+	if _, err = protocol.DeriveSessionKey(master, 3); err == nil {
+		t.Fatal("derived a secp256k1 session key on SM2")
+	}
+}
+
+func TestTaprootKeys(t *testing.T) {
+	initFuzz()
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.dat")
+	publicPath := filepath.Join(dir, "public.dat")
+	defer protocol.SetTaprootKeys(false)
+	for i := 0; i < 4; i++ {
+		if err := protocol.GenKey(privatePath, publicPath, rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		protocol.SetTaprootKeys(false)
+		internal, err := protocol.LoadPrivateKey(privatePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		protocol.SetTaprootKeys(true)
+		key, err := protocol.LoadPrivateKey(privatePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := protocol.LoadPublicKey(publicPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Public.Equal(public) {
+			t.Fatal("tweaked private and public keys don't match")
+		}
+		x, y := internal.Public.Back()
+		output, odd, err := taproot.TweakPublicKey(&secp256k1.PublicKey{X: x, Y: y}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qx, qy := public.Back()
+		if taproot.XOnly(&secp256k1.PublicKey{X: qx, Y: qy}) != output || (qy.Bit(0) == 1) != odd {
+			t.Fatal("loaded key is not the Taproot output key")
+		}
+	}
+
+	protocol.SetCurve(sm2.FastCurve())
+	defer initFuzz()
+	if _, err := protocol.LoadPublicKey(publicPath); err == nil {
+		t.Fatal("loaded a Taproot key on SM2")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package protocol
+
+import "os"
+
+func mapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
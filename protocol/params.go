@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	vc "volley/curve"
+)
+
+// PublicParams holds the generators shared by all parties, together with
+// their precomputed tables.
+type PublicParams struct {
+	G []vc.FastPoint
+	H []vc.FastPoint
+	U vc.FastPoint
+
+	// HSum1 and HSum2 are sums over H that Bob needs for verification.
+	HSum1 vc.FastPoint
+	HSum2 vc.FastPoint
+}
+
+// LoadPublicParams reads the generator and precomputes files written by Setup.
+func LoadPublicParams(genPath, prePath string) (*PublicParams, error) {
+	ghuBytes, err := os.ReadFile(genPath)
+	if err != nil {
+		return nil, err
+	}
+	if int32(len(ghuBytes)) < (L*2+1)*64 {
+		return nil, &ParamError{Path: genPath, Got: len(ghuBytes), Want: int((L*2 + 1) * 64)}
+	}
+	params := &PublicParams{
+		G: make([]vc.FastPoint, L),
+		H: make([]vc.FastPoint, L),
+	}
+	for i := int32(0); i < L; i++ {
+		params.G[i] = fastCurve.NewPoint()
+		gx := new(big.Int).SetBytes(ghuBytes[i*64 : i*64+32])
+		gy := new(big.Int).SetBytes(ghuBytes[i*64+32 : i*64+64])
+		params.G[i].From(gx, gy)
+	}
+	hBytes := ghuBytes[64*L:]
+	for i := int32(0); i < L; i++ {
+		params.H[i] = fastCurve.NewPoint()
+		hx := new(big.Int).SetBytes(hBytes[i*64 : i*64+32])
+		hy := new(big.Int).SetBytes(hBytes[i*64+32 : i*64+64])
+		params.H[i].From(hx, hy)
+	}
+	uBytes := ghuBytes[64*2*L:]
+	params.U = fastCurve.NewPoint()
+	ux := new(big.Int).SetBytes(uBytes[0:32])
+	uy := new(big.Int).SetBytes(uBytes[32:64])
+	params.U.From(ux, uy)
+
+	rest, err := loadPrecomputes(prePath, params.G, params.H)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < 128 {
+		return nil, &ParamError{Path: prePath, Got: len(rest), Want: 128, Err: fmt.Errorf("HSum missing\n")}
+	}
+	hx := new(big.Int).SetBytes(rest[0:32])
+	hy := new(big.Int).SetBytes(rest[32:64])
+	params.HSum1 = fastCurve.NewPoint()
+	params.HSum1.From(hx, hy)
+	hx = new(big.Int).SetBytes(rest[64:96])
+	hy = new(big.Int).SetBytes(rest[96:128])
+	params.HSum2 = fastCurve.NewPoint()
+	params.HSum2.From(hx, hy)
+	return params, nil
+}
+
+// newBox returns the 16 rows mapping the bits of each 64-coefficient block of
+// the plaintext to the base-Step digits of a Y secret.
+func newBox() [][]*big.Int {
+	box := make([][]*big.Int, 16)
+	for i := 0; i < 16; i++ {
+		box[i] = make([]*big.Int, D)
+		for j := 0; j < int(D); j++ {
+			box[i][j] = big.NewInt(0)
+		}
+	}
+
+	tmpForBox := make([]*big.Int, 64)
+	tmpForBox[0] = big.NewInt(1)
+	for i := 1; i < 64; i++ {
+		tmpForBox[i] = new(big.Int).Mul(tmpForBox[i-1], big.NewInt(int64(Step)))
+	}
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 64; j++ {
+			box[i][i*64+j] = new(big.Int).Set(tmpForBox[j])
+		}
+	}
+	boxPrime := make([][]*big.Int, 16)
+	for i := 0; i < 16; i++ {
+		boxPrime[i] = make([]*big.Int, D*2)
+	}
+	N := fastCurve.Params().N
+	minus2 := new(big.Int).Sub(N, big.NewInt(2))
+	for i := 0; i < 16; i++ {
+		for j := 0; j < int(D); j++ {
+			d1 := new(big.Int).Set(box[i][j])
+			d2 := new(big.Int).Mul(box[i][j], minus2)
+			d1.Mod(d1, N)
+			d2.Mod(d2, N)
+			boxPrime[i][j*2] = d1
+			boxPrime[i][j*2+1] = d2
+		}
+	}
+	return boxPrime
+}
+
+func newB1List() []*big.Int {
+	N := fastCurve.Params().N
+	b1List := make([]*big.Int, B1)
+	for i := int32(0); i < B1; i++ {
+		b1List[i] = big.NewInt(int64(1) << i)
+	}
+	b1List[B1-1].Sub(N, b1List[B1-1])
+	return b1List
+}
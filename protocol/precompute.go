@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"os"
+	vc "volley/curve"
+)
+
+const tableBytes = 8 * 64
+
+var mmapPrecomputes bool
+
+// SetMmapPrecomputes makes Init map the precomputes file into memory instead
+// of reading it. Tables are then decoded on first use, and concurrent
+// processes share the same pages through the page cache.
+func SetMmapPrecomputes(enable bool) {
+	mmapPrecomputes = enable
+}
+
+// loadPrecomputes attaches the affine tables stored in prePath to g and h,
+// and returns whatever follows the tables in the file.
+func loadPrecomputes(prePath string, g, h []vc.FastPoint) ([]byte, error) {
+	var data []byte
+	var err error
+	if mmapPrecomputes {
+		data, err = mapFile(prePath)
+	} else {
+		data, err = os.ReadFile(prePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	size := (len(g) + len(h)) * tableBytes
+	if len(data) < size {
+		return nil, &ParamError{Path: prePath, Got: len(data), Want: size}
+	}
+	for i, pt := range append(g[:len(g):len(g)], h...) {
+		table := data[i*tableBytes : (i+1)*tableBytes : (i+1)*tableBytes]
+		if mmapPrecomputes {
+			pt.MapTable(table, true)
+		} else {
+			pt.ImportTable(table, true)
+		}
+	}
+	return data[size:], nil
+}
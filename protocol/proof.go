@@ -23,197 +23,208 @@ func storePoint(data []byte, point vc.FastPoint) {
 }
 
 func storePointCompressed(data []byte, point vc.FastPoint) {
-	data[0] = 0x02
-	x, y := point.Back()
-	x.FillBytes(data[1:33])
-	data[0] |= byte(y.Bit(0))
+	copy(data[0:33], point.MarshalCompressed())
+}
+
+// normalizedCopies returns copies of points rescaled to Z = 1 with a single
+// field inversion, which makes encoding them cheap.
+func normalizedCopies(points []vc.FastPoint) []vc.FastPoint {
+	normalized := make([]vc.FastPoint, len(points))
+	for i, point := range points {
+		normalized[i] = fastCurve.NewPoint()
+		normalized[i].CopyFrom(point)
+	}
+	fastCurve.BatchNormalize(normalized)
+	return normalized
+}
+
+// storePoints writes points one after the other.
+func storePoints(data []byte, points []vc.FastPoint, pointSize int, store func([]byte, vc.FastPoint)) {
+	for i, point := range normalizedCopies(points) {
+		store(data[i*pointSize:], point)
+	}
 }
 
-func getPoint(data []byte) vc.FastPoint {
+func getPoint(data []byte) (vc.FastPoint, error) {
+	if len(data) != 64 {
+		return nil, fmt.Errorf("Invalid point length %d\n", len(data))
+	}
 	x := new(big.Int).SetBytes(data[0:32])
 	y := new(big.Int).SetBytes(data[32:64])
+	// the identity has no affine encoding, so (0, 0) fails here as well
+	if !fastCurve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("Point not on curve\n")
+	}
 	point := fastCurve.NewPoint()
 	point.From(x, y)
-	return point
+	return point, nil
 }
 
-func GetPointCompressed(data []byte) vc.FastPoint {
-	return getPointCompressed(data)
+func GetPointCompressed(data []byte) (vc.FastPoint, error) {
+	point, err := getPointCompressed(data)
+	if err != nil {
+		return nil, &MalformedError{What: "Point", Err: err}
+	}
+	return point, nil
 }
 
-func getPointCompressed(data []byte) vc.FastPoint {
-	P := fastCurve.Params().P
-	x := new(big.Int).SetBytes(data[1:33])
-	sign := uint(data[0] & 0x1)
-	t := new(big.Int).Mul(x, x)
-	t.Mod(t, P)
-	t.Mul(t, x)
-	t.Mod(t, P)
-	t.Add(t, fastCurve.Params().B)
-	t.Mod(t, P)
-	y := new(big.Int).ModSqrt(t, P)
-	if y.Bit(0)^sign == 1 {
-		y.Sub(P, y)
+func getPointCompressed(data []byte) (vc.FastPoint, error) {
+	// proofs never carry the identity, so its one byte encoding is refused
+	if len(data) != 33 {
+		return nil, fmt.Errorf("Invalid compressed point length %d\n", len(data))
 	}
 	point := fastCurve.NewPoint()
-	point.From(x, y)
-	return point
+	if err := point.UnmarshalCompressed(data); err != nil {
+		return nil, err
+	}
+	return point, nil
 }
 
-func (p *Proof) Deserialize(data []byte) (err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("Deserialization error\n")
-		}
-	}()
-
-	offset := 0
-	p.W1 = getPoint(data[offset:])
-	offset += 64
-	p.W2 = getPoint(data[offset:])
-	offset += 64
-	p.W3 = getPoint(data[offset:])
-	offset += 64
-	p.Sub1 = new(SubProof1)
-	length1 := int(math.Log2(float64(LP)))
-	p.Sub1.TL = make([]vc.FastPoint, length1)
-	p.Sub1.TR = make([]vc.FastPoint, length1)
-	for i := 0; i < length1; i++ {
-		p.Sub1.TL[i] = getPoint(data[offset:])
-		offset += 64
+func getScalar(data []byte) (*big.Int, error) {
+	if len(data) != 32 {
+		return nil, fmt.Errorf("Invalid scalar length %d\n", len(data))
 	}
-	for i := 0; i < length1; i++ {
-		p.Sub1.TR[i] = getPoint(data[offset:])
-		offset += 64
+	k := new(big.Int).SetBytes(data)
+	if k.Cmp(fastCurve.Params().N) >= 0 {
+		return nil, fmt.Errorf("Scalar not below N\n")
 	}
-	p.Sub1.BigC = getPoint(data[offset:])
-	offset += 64
-	p.Sub1.BigCPrime = getPoint(data[offset:])
-	offset += 64
-	p.Sub1.E1 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.E2 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.O = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub2 = new(SubProof2)
-	length2 := int(math.Log2(float64(D * BPrime)))
-	p.Sub2.TL = make([]vc.FastPoint, length2)
-	p.Sub2.TR = make([]vc.FastPoint, length2)
-	for i := 0; i < length2; i++ {
-		p.Sub2.TL[i] = getPoint(data[offset:])
-		offset += 64
-	}
-	for i := 0; i < length2; i++ {
-		p.Sub2.TR[i] = getPoint(data[offset:])
-		offset += 64
+	return k, nil
+}
+
+// getPoints decodes num consecutive points of pointSize bytes each.
+func getPoints(data []byte, num, pointSize int, get func([]byte) (vc.FastPoint, error)) ([]vc.FastPoint, error) {
+	points := make([]vc.FastPoint, num)
+	for i := 0; i < num; i++ {
+		point, err := get(data[i*pointSize : (i+1)*pointSize])
+		if err != nil {
+			return nil, fmt.Errorf("Point %d: %w", i, err)
+		}
+		points[i] = point
 	}
-	p.Sub2.BigC = getPoint(data[offset:])
-	offset += 64
-	p.Sub2.E1 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub2.E2 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	if offset != len(data) {
-		panic("size error")
+	return points, nil
+}
+
+func getScalars(data []byte, num int) ([]*big.Int, error) {
+	scalars := make([]*big.Int, num)
+	for i := 0; i < num; i++ {
+		k, err := getScalar(data[i*32 : (i+1)*32])
+		if err != nil {
+			return nil, fmt.Errorf("Scalar %d: %w", i, err)
+		}
+		scalars[i] = k
 	}
+	return scalars, nil
+}
 
-	err = nil
-	return
+func proofLengths() (length1, length2 int) {
+	return int(math.Log2(float64(LP))), int(math.Log2(float64(D * BPrime)))
 }
 
-func (p *Proof) Serialize() []byte {
-	size := (3+3+2*int(len(p.Sub1.TL))+2*int(len(p.Sub2.TL)))*64 + 5*32
-	data := make([]byte, size)
-	offset := 0
-	storePoint(data[offset:], p.W1)
-	offset += 64
-	storePoint(data[offset:], p.W2)
-	offset += 64
-	storePoint(data[offset:], p.W3)
-	offset += 64
-	for _, point := range p.Sub1.TL {
-		storePoint(data[offset:], point)
-		offset += 64
-	}
-	for _, point := range p.Sub1.TR {
-		storePoint(data[offset:], point)
-		offset += 64
-	}
-	storePoint(data[offset:], p.Sub1.BigC)
-	offset += 64
-	storePoint(data[offset:], p.Sub1.BigCPrime)
-	offset += 64
+// ProofSize is the length of a serialized Proof, or of a compressed one if
+// compressed is set.
+func ProofSize(compressed bool) int {
+	pointSize := 64
+	if compressed {
+		pointSize = 33
+	}
+	length1, length2 := proofLengths()
+	return (3+3+2*length1+2*length2)*pointSize + 5*32
+}
 
-	p.Sub1.E1.FillBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.E2.FillBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.O.FillBytes(data[offset : offset+32])
-	offset += 32
+func (p *Proof) Deserialize(data []byte) error {
+	return p.deserialize(data, 64, getPoint)
+}
 
-	for _, point := range p.Sub2.TL {
-		storePoint(data[offset:], point)
-		offset += 64
-	}
-	for _, point := range p.Sub2.TR {
-		storePoint(data[offset:], point)
-		offset += 64
+func (p *Proof) DeserializeCompressed(data []byte) error {
+	return p.deserialize(data, 33, getPointCompressed)
+}
+
+func (p *Proof) deserialize(data []byte, pointSize int, get func([]byte) (vc.FastPoint, error)) error {
+	size := ProofSize(pointSize == 33)
+	if len(data) != size {
+		return &MalformedError{What: "Proof", Err: fmt.Errorf("length %d, expected %d\n", len(data), size)}
 	}
-	storePoint(data[offset:], p.Sub2.BigC)
-	offset += 64
-	p.Sub2.E1.FillBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub2.E2.FillBytes(data[offset : offset+32])
-	offset += 32
-	if offset != size {
-		panic("Size error")
+	length1, length2 := proofLengths()
+
+	offset := 0
+	num := 3 + 2*length1 + 2
+	points1, err := getPoints(data[offset:offset+num*pointSize], num, pointSize, get)
+	if err != nil {
+		return &MalformedError{What: "Proof", Err: fmt.Errorf("W/sub proof 1: %w", err)}
+	}
+	offset += num * pointSize
+	scalars1, err := getScalars(data[offset:offset+3*32], 3)
+	if err != nil {
+		return &MalformedError{What: "Proof", Err: fmt.Errorf("sub proof 1: %w", err)}
+	}
+	offset += 3 * 32
+	num = 2*length2 + 1
+	points2, err := getPoints(data[offset:offset+num*pointSize], num, pointSize, get)
+	if err != nil {
+		return &MalformedError{What: "Proof", Err: fmt.Errorf("sub proof 2: %w", err)}
+	}
+	offset += num * pointSize
+	scalars2, err := getScalars(data[offset:offset+2*32], 2)
+	if err != nil {
+		return &MalformedError{What: "Proof", Err: fmt.Errorf("sub proof 2: %w", err)}
 	}
 
-	return data
+	p.W1 = points1[0]
+	p.W2 = points1[1]
+	p.W3 = points1[2]
+	p.Sub1 = &SubProof1{
+		TL:        points1[3 : 3+length1],
+		TR:        points1[3+length1 : 3+2*length1],
+		BigC:      points1[3+2*length1],
+		BigCPrime: points1[4+2*length1],
+		E1:        scalars1[0],
+		E2:        scalars1[1],
+		O:         scalars1[2],
+	}
+	p.Sub2 = &SubProof2{
+		TL:   points2[0:length2],
+		TR:   points2[length2 : 2*length2],
+		BigC: points2[2*length2],
+		E1:   scalars2[0],
+		E2:   scalars2[1],
+	}
+	return nil
+}
+
+func (p *Proof) Serialize() []byte {
+	return p.serialize(64, storePoint)
 }
 
 func (p *Proof) SerializeCompressed() []byte {
-	size := (3+3+2*int(len(p.Sub1.TL))+2*int(len(p.Sub2.TL)))*33 + 5*32
+	return p.serialize(33, storePointCompressed)
+}
+
+func (p *Proof) serialize(pointSize int, store func([]byte, vc.FastPoint)) []byte {
+	points1 := []vc.FastPoint{p.W1, p.W2, p.W3}
+	points1 = append(points1, p.Sub1.TL...)
+	points1 = append(points1, p.Sub1.TR...)
+	points1 = append(points1, p.Sub1.BigC, p.Sub1.BigCPrime)
+	points2 := append(append([]vc.FastPoint{}, p.Sub2.TL...), p.Sub2.TR...)
+	points2 = append(points2, p.Sub2.BigC)
+
+	size := (len(points1)+len(points2))*pointSize + 5*32
 	data := make([]byte, size)
+	normalized := normalizedCopies(append(points1, points2...))
 	offset := 0
-	storePointCompressed(data[offset:], p.W1)
-	offset += 33
-	storePointCompressed(data[offset:], p.W2)
-	offset += 33
-	storePointCompressed(data[offset:], p.W3)
-	offset += 33
-	for _, point := range p.Sub1.TL {
-		storePointCompressed(data[offset:], point)
-		offset += 33
-	}
-	for _, point := range p.Sub1.TR {
-		storePointCompressed(data[offset:], point)
-		offset += 33
+	for _, point := range normalized[:len(points1)] {
+		store(data[offset:], point)
+		offset += pointSize
 	}
-	storePointCompressed(data[offset:], p.Sub1.BigC)
-	offset += 33
-	storePointCompressed(data[offset:], p.Sub1.BigCPrime)
-	offset += 33
-
 	p.Sub1.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	p.Sub1.E2.FillBytes(data[offset : offset+32])
 	offset += 32
 	p.Sub1.O.FillBytes(data[offset : offset+32])
 	offset += 32
-
-	for _, point := range p.Sub2.TL {
-		storePointCompressed(data[offset:], point)
-		offset += 33
+	for _, point := range normalized[len(points1):] {
+		store(data[offset:], point)
+		offset += pointSize
 	}
-	for _, point := range p.Sub2.TR {
-		storePointCompressed(data[offset:], point)
-		offset += 33
-	}
-	storePointCompressed(data[offset:], p.Sub2.BigC)
-	offset += 33
 	p.Sub2.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	p.Sub2.E2.FillBytes(data[offset : offset+32])
@@ -225,69 +236,6 @@ func (p *Proof) SerializeCompressed() []byte {
 	return data
 }
 
-func (p *Proof) DeserializeCompressed(data []byte) (err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("Deserialization error\n")
-		}
-	}()
-
-	offset := 0
-	p.W1 = getPointCompressed(data[offset:])
-	offset += 33
-	p.W2 = getPointCompressed(data[offset:])
-	offset += 33
-	p.W3 = getPointCompressed(data[offset:])
-	offset += 33
-	p.Sub1 = new(SubProof1)
-	length1 := int(math.Log2(float64(LP)))
-	p.Sub1.TL = make([]vc.FastPoint, length1)
-	p.Sub1.TR = make([]vc.FastPoint, length1)
-	for i := 0; i < length1; i++ {
-		p.Sub1.TL[i] = getPointCompressed(data[offset:])
-		offset += 33
-	}
-	for i := 0; i < length1; i++ {
-		p.Sub1.TR[i] = getPointCompressed(data[offset:])
-		offset += 33
-	}
-	p.Sub1.BigC = getPointCompressed(data[offset:])
-	offset += 33
-	p.Sub1.BigCPrime = getPointCompressed(data[offset:])
-	offset += 33
-	p.Sub1.E1 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.E2 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub1.O = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub2 = new(SubProof2)
-	length2 := int(math.Log2(float64(D * BPrime)))
-	p.Sub2.TL = make([]vc.FastPoint, length2)
-	p.Sub2.TR = make([]vc.FastPoint, length2)
-	for i := 0; i < length2; i++ {
-		p.Sub2.TL[i] = getPointCompressed(data[offset:])
-		offset += 33
-	}
-	for i := 0; i < length2; i++ {
-		p.Sub2.TR[i] = getPointCompressed(data[offset:])
-		offset += 33
-	}
-	p.Sub2.BigC = getPointCompressed(data[offset:])
-	offset += 33
-	p.Sub2.E1 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	p.Sub2.E2 = new(big.Int).SetBytes(data[offset : offset+32])
-	offset += 32
-	if offset != len(data) {
-		panic("size error")
-	}
-
-	err = nil
-	return
-}
-
 func SerializeSigList(sigs []*adaptor.Signature) []byte {
 	data := make([]byte, YNumber*(32+32))
 	offset := 0
@@ -302,92 +250,54 @@ func SerializeSigList(sigs []*adaptor.Signature) []byte {
 	return data
 }
 
-func DeserializeSigList(data []byte) (sigs []*adaptor.Signature, err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("SigList deserliazation error\n")
+func DeserializeSigList(data []byte) ([]*adaptor.Signature, error) {
+	if len(data) != int(YNumber)*64 {
+		return nil, &MalformedError{What: "SigList", Err: fmt.Errorf("length %d, expected %d\n", len(data), YNumber*64)}
+	}
+	scalars, err := getScalars(data, 2*int(YNumber))
+	if err != nil {
+		return nil, &MalformedError{What: "SigList", Err: err}
+	}
+	sigs := make([]*adaptor.Signature, YNumber)
+	for i := range sigs {
+		sigs[i] = &adaptor.Signature{
+			E: scalars[2*i],
+			S: scalars[2*i+1],
 		}
-	}()
-	sigs = make([]*adaptor.Signature, YNumber)
-	offset := 0
-	for i := int32(0); i < YNumber; i++ {
-		sigs[i] = new(adaptor.Signature)
-		sigs[i].E = new(big.Int).SetBytes(data[offset : offset+32])
-		sigs[i].S = new(big.Int).SetBytes(data[offset+32 : offset+64])
-		offset += 64
 	}
-	if offset != len(data) {
-		panic("size error")
-	}
-	err = nil
-	return
+	return sigs, nil
 }
 
 func SerializeYListCompressed(yPoints []vc.FastPoint) []byte {
 	data := make([]byte, YNumber*33)
-	offset := 0
-	for i := int32(0); i < YNumber; i++ {
-		storePointCompressed(data[offset:], yPoints[i])
-		offset += 33
-	}
-	if offset != len(data) {
-		panic("YList size error")
-	}
+	storePoints(data, yPoints[:YNumber], 33, storePointCompressed)
 	return data
 }
 
 func SerializeYList(yPoints []vc.FastPoint) []byte {
 	data := make([]byte, YNumber*64)
-	offset := 0
-	for i := int32(0); i < YNumber; i++ {
-		storePoint(data[offset:], yPoints[i])
-		offset += 64
-	}
-	if offset != len(data) {
-		panic("YList size error")
-	}
+	storePoints(data, yPoints[:YNumber], 64, storePoint)
 	return data
 }
 
-func DeserializeYList(data []byte) (yPoints []vc.FastPoint, err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("YList deserliazation error\n")
-		}
-	}()
-
-	yPoints = make([]vc.FastPoint, YNumber)
-	offset := 0
-	for i := int32(0); i < YNumber; i++ {
-		yPoints[i] = getPoint(data[offset:])
-		offset += 64
+func DeserializeYList(data []byte) ([]vc.FastPoint, error) {
+	if len(data) != int(YNumber)*64 {
+		return nil, &MalformedError{What: "YList", Err: fmt.Errorf("length %d, expected %d\n", len(data), YNumber*64)}
 	}
-	if offset != len(data) {
-		panic("size error")
+	yPoints, err := getPoints(data, int(YNumber), 64, getPoint)
+	if err != nil {
+		return nil, &MalformedError{What: "YList", Err: err}
 	}
-	err = nil
-	return
+	return yPoints, nil
 }
 
-func DeserializeYListCompressed(data []byte) (yPoints []vc.FastPoint, err error) {
-	defer func() {
-		fatal := recover()
-		if fatal != nil {
-			err = fmt.Errorf("YList deserliazation error\n")
-		}
-	}()
-
-	yPoints = make([]vc.FastPoint, YNumber)
-	offset := 0
-	for i := int32(0); i < YNumber; i++ {
-		yPoints[i] = getPointCompressed(data[offset:])
-		offset += 33
+func DeserializeYListCompressed(data []byte) ([]vc.FastPoint, error) {
+	if len(data) != int(YNumber)*33 {
+		return nil, &MalformedError{What: "YList", Err: fmt.Errorf("length %d, expected %d\n", len(data), YNumber*33)}
 	}
-	if offset != len(data) {
-		panic("size error")
+	yPoints, err := getPoints(data, int(YNumber), 33, getPointCompressed)
+	if err != nil {
+		return nil, &MalformedError{What: "YList", Err: err}
 	}
-	err = nil
-	return
+	return yPoints, nil
 }
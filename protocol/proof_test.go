@@ -0,0 +1,180 @@
+package protocol_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math"
+	"math/big"
+	"testing"
+	"volley/adaptor"
+	vc "volley/curve"
+	"volley/protocol"
+	"volley/secp256k1"
+)
+
+func initFuzz() vc.FastCurve {
+	secp256k1.InitNAFTables(9)
+	fastCurve := secp256k1.FastCurve()
+	protocol.SetCurve(fastCurve)
+	return fastCurve
+}
+
+func randomPoints(f testing.TB, fastCurve vc.FastCurve, num int) []vc.FastPoint {
+	points := make([]vc.FastPoint, num)
+	for i := range points {
+		k, err := rand.Int(rand.Reader, fastCurve.Params().N)
+		if err != nil {
+			f.Fatal(err)
+		}
+		points[i] = fastCurve.FastBaseScalar(k.Bytes())
+	}
+	return points
+}
+
+func randomScalars(f testing.TB, fastCurve vc.FastCurve, num int) []*big.Int {
+	scalars := make([]*big.Int, num)
+	for i := range scalars {
+		k, err := rand.Int(rand.Reader, fastCurve.Params().N)
+		if err != nil {
+			f.Fatal(err)
+		}
+		scalars[i] = k
+	}
+	return scalars
+}
+
+// fakeProof has the shape of a real proof without running the prover.
+func fakeProof(f testing.TB, fastCurve vc.FastCurve) *protocol.Proof {
+	length1 := int(math.Log2(float64(protocol.LP)))
+	length2 := int(math.Log2(float64(protocol.D * protocol.BPrime)))
+	points := randomPoints(f, fastCurve, 6+2*length1+2*length2)
+	scalars := randomScalars(f, fastCurve, 5)
+	return &protocol.Proof{
+		W1: points[0],
+		W2: points[1],
+		W3: points[2],
+		Sub1: &protocol.SubProof1{
+			TL:        points[6 : 6+length1],
+			TR:        points[6+length1 : 6+2*length1],
+			BigC:      points[3],
+			BigCPrime: points[4],
+			E1:        scalars[0],
+			E2:        scalars[1],
+			O:         scalars[2],
+		},
+		Sub2: &protocol.SubProof2{
+			TL:   points[6+2*length1 : 6+2*length1+length2],
+			TR:   points[6+2*length1+length2:],
+			BigC: points[5],
+			E1:   scalars[3],
+			E2:   scalars[4],
+		},
+	}
+}
+
+// addCorruptSeeds adds data along with copies that are truncated, extended,
+// or have a byte flipped.
+func addCorruptSeeds(f *testing.F, data []byte) {
+	f.Add(data)
+	f.Add(data[:len(data)-1])
+	f.Add(append(append([]byte{}, data...), 0))
+	for _, i := range []int{0, 1, 32, 33, 64, len(data) - 1} {
+		if i >= len(data) {
+			continue
+		}
+		corrupt := append([]byte{}, data...)
+		corrupt[i] ^= 0xff
+		f.Add(corrupt)
+	}
+	f.Add([]byte{})
+}
+
+func FuzzProofDeserialize(f *testing.F) {
+	fastCurve := initFuzz()
+	addCorruptSeeds(f, fakeProof(f, fastCurve).Serialize())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		proof := new(protocol.Proof)
+		if proof.Deserialize(data) != nil {
+			return
+		}
+		if !bytes.Equal(proof.Serialize(), data) {
+			t.Fatal("accepted a non-canonical proof encoding")
+		}
+	})
+}
+
+func FuzzProofDeserializeCompressed(f *testing.F) {
+	fastCurve := initFuzz()
+	addCorruptSeeds(f, fakeProof(f, fastCurve).SerializeCompressed())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		proof := new(protocol.Proof)
+		if proof.DeserializeCompressed(data) != nil {
+			return
+		}
+		if !bytes.Equal(proof.SerializeCompressed(), data) {
+			t.Fatal("accepted a non-canonical compressed proof encoding")
+		}
+	})
+}
+
+func FuzzDeserializeSigList(f *testing.F) {
+	fastCurve := initFuzz()
+	scalars := randomScalars(f, fastCurve, 2*int(protocol.YNumber))
+	sigs := make([]*adaptor.Signature, protocol.YNumber)
+	for i := range sigs {
+		sigs[i] = &adaptor.Signature{E: scalars[2*i], S: scalars[2*i+1]}
+	}
+	addCorruptSeeds(f, protocol.SerializeSigList(sigs))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sigs, err := protocol.DeserializeSigList(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(protocol.SerializeSigList(sigs), data) {
+			t.Fatal("accepted a non-canonical signature list")
+		}
+	})
+}
+
+func FuzzDeserializeYList(f *testing.F) {
+	fastCurve := initFuzz()
+	addCorruptSeeds(f, protocol.SerializeYList(randomPoints(f, fastCurve, int(protocol.YNumber))))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		yPoints, err := protocol.DeserializeYList(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(protocol.SerializeYList(yPoints), data) {
+			t.Fatal("accepted a non-canonical Y list")
+		}
+	})
+}
+
+func FuzzDeserializeYListCompressed(f *testing.F) {
+	fastCurve := initFuzz()
+	addCorruptSeeds(f, protocol.SerializeYListCompressed(randomPoints(f, fastCurve, int(protocol.YNumber))))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		yPoints, err := protocol.DeserializeYListCompressed(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(protocol.SerializeYListCompressed(yPoints), data) {
+			t.Fatal("accepted a non-canonical compressed Y list")
+		}
+	})
+}
+
+func FuzzGetPointCompressed(f *testing.F) {
+	fastCurve := initFuzz()
+	addCorruptSeeds(f, protocol.SerializeYListCompressed(randomPoints(f, fastCurve, int(protocol.YNumber)))[:33])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		point, err := protocol.GetPointCompressed(data)
+		if err != nil {
+			return
+		}
+		x, y := point.Back()
+		if !fastCurve.IsOnCurve(x, y) {
+			t.Fatal("decoded point not on curve")
+		}
+	})
+}
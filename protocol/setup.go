@@ -5,6 +5,8 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"sync"
+	vc "volley/curve"
 	"volley/lpr"
 )
 
@@ -21,7 +23,7 @@ func GenKeyRLWE(privatePath, publicPath string, random io.Reader) error {
 	for i, d := range secretKey.Data {
 		secretBytes[i] = byte(d)
 	}
-	err = os.WriteFile(privatePath, secretBytes, os.ModePerm)
+	err = writePrivateFile(privatePath, secretBytes, random)
 	if err != nil {
 		return err
 	}
@@ -39,7 +41,7 @@ func GenKey(privatePath, publicPath string, random io.Reader) error {
 	if err != nil {
 		return err
 	}
-	p := fastCurve.FastBaseScalar(k.Bytes())
+	p := fastCurve.FastBaseScalarSecret(k.Bytes())
 	secretBytes := make([]byte, 96)
 	k.FillBytes(secretBytes[0:32])
 	publicBytes := make([]byte, 64)
@@ -48,7 +50,7 @@ func GenKey(privatePath, publicPath string, random io.Reader) error {
 	py.FillBytes(publicBytes[32:64])
 	px.FillBytes(secretBytes[32:64])
 	py.FillBytes(secretBytes[64:96])
-	err = os.WriteFile(privatePath, secretBytes, os.ModePerm)
+	err = writePrivateFile(privatePath, secretBytes, random)
 	if err != nil {
 		return err
 	}
@@ -59,7 +61,19 @@ func GenKey(privatePath, publicPath string, random io.Reader) error {
 	return nil
 }
 
-func Setup(genPath, precomputesPath string, random io.Reader) (err error) {
+// setupBatch is the number of generators drawn, computed and written per
+// round of Setup.
+const setupBatch = 1024
+
+// SetupProgress is called by Setup after each batch with the number of
+// generators written so far and the total number of generators.
+type SetupProgress func(done, total int)
+
+// Setup generates the 2*L+1 generators and the precomputed tables of the
+// first 2*L of them. Scalars are drawn from random in order, while points
+// and tables are computed by coreNum workers, so the output for a given
+// random stream doesn't depend on the worker count. progress may be nil.
+func Setup(genPath, precomputesPath string, random io.Reader, progress SetupProgress) (err error) {
 	genFile, err := os.Create(genPath)
 	if err != nil {
 		return err
@@ -85,55 +99,52 @@ func Setup(genPath, precomputesPath string, random io.Reader) (err error) {
 	n1 := fastCurve.Params().N
 	hSum1 := fastCurve.NewPoint()
 	hSum2 := fastCurve.NewPoint()
-	var k *big.Int
-	pointBytes := make([]byte, 64)
+	total := 2*int(L) + 1
 
-	for i := int32(0); i < L; i++ {
-		k, err = rand.Int(random, n1)
-		if err != nil {
-			return
-		}
-		point := fastCurve.FastBaseScalar(k.Bytes())
-		px, py := point.Back()
-		px.FillBytes(pointBytes[0:32])
-		py.FillBytes(pointBytes[32:64])
-		precomputes := point.ExportTable(true)
-		_, err = genFile.Write(pointBytes)
-		if err != nil {
-			return
+	scalars := make([]*big.Int, setupBatch)
+	points := make([]vc.FastPoint, setupBatch)
+	pointBytes := make([]byte, setupBatch*64)
+	precomputes := make([]byte, setupBatch*tableBytes)
+
+	for done := 0; done < 2*int(L); {
+		n := 2*int(L) - done
+		if n > setupBatch {
+			n = setupBatch
 		}
-		_, err = preFile.Write(precomputes)
-		if err != nil {
-			return
+		for i := 0; i < n; i++ {
+			scalars[i], err = rand.Int(random, n1)
+			if err != nil {
+				return
+			}
 		}
-	}
+		genSetupBatch(points[:n], scalars[:n], pointBytes, precomputes)
 
-	for i := int32(0); i < L; i++ {
-		k, err = rand.Int(random, n1)
-		if err != nil {
-			return err
-		}
-		point := fastCurve.FastBaseScalar(k.Bytes())
-		px, py := point.Back()
-		px.FillBytes(pointBytes[0:32])
-		py.FillBytes(pointBytes[32:64])
-		precomputes := point.ExportTable(true)
-		_, err = genFile.Write(pointBytes)
+		_, err = genFile.Write(pointBytes[:n*64])
 		if err != nil {
 			return
 		}
-		_, err = preFile.Write(precomputes)
+		_, err = preFile.Write(precomputes[:n*tableBytes])
 		if err != nil {
 			return
 		}
-		if i >= 3*D*B && i < 3*D*B+D*BPrime {
-			fastCurve.FastPointAdd(hSum2, hSum2, point)
-		} else {
-			fastCurve.FastPointAdd(hSum1, hSum1, point)
+		for i := 0; i < n; i++ {
+			h := int32(done+i) - L
+			if h < 0 {
+				continue
+			}
+			if h >= 3*D*B && h < 3*D*B+D*BPrime {
+				fastCurve.FastPointAdd(hSum2, hSum2, points[i])
+			} else {
+				fastCurve.FastPointAdd(hSum1, hSum1, points[i])
+			}
+		}
+		done += n
+		if progress != nil {
+			progress(done, total)
 		}
 	}
 
-	k, err = rand.Int(random, n1)
+	k, err := rand.Int(random, n1)
 	if err != nil {
 		return
 	}
@@ -141,24 +152,57 @@ func Setup(genPath, precomputesPath string, random io.Reader) (err error) {
 	ux, uy := u.Back()
 	ux.FillBytes(pointBytes[0:32])
 	uy.FillBytes(pointBytes[32:64])
-	_, err = genFile.Write(pointBytes)
+	_, err = genFile.Write(pointBytes[:64])
 	if err != nil {
 		return
 	}
 	hx, hy := hSum1.Back()
 	hx.FillBytes(pointBytes[0:32])
 	hy.FillBytes(pointBytes[32:64])
-	_, err = preFile.Write(pointBytes)
+	_, err = preFile.Write(pointBytes[:64])
 	if err != nil {
 		return
 	}
 	hx, hy = hSum2.Back()
 	hx.FillBytes(pointBytes[0:32])
 	hy.FillBytes(pointBytes[32:64])
-	_, err = preFile.Write(pointBytes)
+	_, err = preFile.Write(pointBytes[:64])
 	if err != nil {
 		return
 	}
+	if progress != nil {
+		progress(total, total)
+	}
 	err = nil
 	return
 }
+
+// genSetupBatch computes scalars[i]*G into points[i], and fills the encoded
+// point and its affine table at index i of pointBytes and precomputes.
+func genSetupBatch(points []vc.FastPoint, scalars []*big.Int, pointBytes, precomputes []byte) {
+	num := len(scalars)
+	workers := coreNum
+	if workers > num {
+		workers = num
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for t := 0; t < workers; t++ {
+		start := t * num / workers
+		end := (t + 1) * num / workers
+		wg.Add(1)
+		go func(s, e int) {
+			defer wg.Done()
+			for i := s; i < e; i++ {
+				points[i] = fastCurve.FastBaseScalar(scalars[i].Bytes())
+				px, py := points[i].Back()
+				px.FillBytes(pointBytes[i*64 : i*64+32])
+				py.FillBytes(pointBytes[i*64+32 : i*64+64])
+				copy(precomputes[i*tableBytes:], points[i].ExportTable(true))
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
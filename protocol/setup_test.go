@@ -0,0 +1,56 @@
+package protocol_test
+
+import (
+	"bytes"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"volley/protocol"
+)
+
+func TestSetupDeterministic(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Setup generates every generator")
+	}
+	initFuzz()
+	defer protocol.SetCoreNum(16)
+
+	dir := t.TempDir()
+	var gens, pres [][]byte
+	for _, cores := range []int{1, 3} {
+		protocol.SetCoreNum(cores)
+		genPath := filepath.Join(dir, "gen.dat")
+		prePath := filepath.Join(dir, "gen.dat.pre")
+		last, total := 0, 0
+		progress := func(done, all int) {
+			if done < last {
+				t.Errorf("progress went back from %d to %d", last, done)
+			}
+			last, total = done, all
+		}
+		err := protocol.Setup(genPath, prePath, mrand.New(mrand.NewSource(1)), progress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total == 0 || last != total {
+			t.Fatalf("progress stopped at %d of %d", last, total)
+		}
+		gen, err := os.ReadFile(genPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pre, err := os.ReadFile(prePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gens = append(gens, gen)
+		pres = append(pres, pre)
+	}
+	if !bytes.Equal(gens[0], gens[1]) {
+		t.Fatal("generators depend on the worker count")
+	}
+	if !bytes.Equal(pres[0], pres[1]) {
+		t.Fatal("precomputes depend on the worker count")
+	}
+}
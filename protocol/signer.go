@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+	"volley/adaptor"
+	vc "volley/curve"
+	"volley/lpr"
+)
+
+// Signer holds the tumbler's signing key. Implementations may keep the key
+// in another process, see package keyd.
+type Signer interface {
+	Public() vc.FastPoint
+	// SignAdaptor returns the adaptor signature of msg locked to y.
+	SignAdaptor(msg []byte, y vc.FastPoint, random io.Reader) (*adaptor.Signature, error)
+}
+
+// Decrypter holds the tumbler's RLWE secret key.
+type Decrypter interface {
+	// DecryptLWE decrypts each ciphertext to a value in [-T/2, T/2).
+	DecryptLWE(cipherList []*lpr.LWECiphertext) ([]int32, error)
+}
+
+type localSigner struct {
+	key *PrivateKey
+}
+
+// NewLocalSigner returns a Signer that keeps key in memory.
+func NewLocalSigner(key *PrivateKey) Signer {
+	return &localSigner{key: key}
+}
+
+func (s *localSigner) Public() vc.FastPoint {
+	return s.key.Public
+}
+
+func (s *localSigner) SignAdaptor(msg []byte, y vc.FastPoint, random io.Reader) (*adaptor.Signature, error) {
+	return adaptor.SchnorrSignAdaptor(msg, y, s.key.Secret, newHash(), random)
+}
+
+type localDecrypter struct {
+	key *lpr.PrivateKey
+}
+
+// NewLocalDecrypter returns a Decrypter that keeps key in memory.
+func NewLocalDecrypter(key *lpr.PrivateKey) Decrypter {
+	return &localDecrypter{key: key}
+}
+
+func (d *localDecrypter) DecryptLWE(cipherList []*lpr.LWECiphertext) ([]int32, error) {
+	plaintext := make([]int32, len(cipherList))
+	for i, cipher := range cipherList {
+		if len(cipher.A) != len(d.key.Data) {
+			return nil, fmt.Errorf("LWE ciphertext %d has dimension %d, expected %d\n", i, len(cipher.A), len(d.key.Data))
+		}
+		plaintext[i] = lpr.LWEDecrypt(cipher, d.key, Q, T)
+	}
+	return plaintext, nil
+}
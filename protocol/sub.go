@@ -2,7 +2,6 @@ package protocol
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/binary"
 	"io"
 	"math"
@@ -34,26 +33,10 @@ func (sp1 *SubProof1) Serialize() []byte {
 	data := make([]byte, 64*count+64*count+64+64+32*3+4)
 	binary.BigEndian.PutUint32(data, uint32(count))
 	offset := 4
-	for _, tl := range sp1.TL {
-		x, y := tl.Back()
-		x.FillBytes(data[offset : offset+32])
-		y.FillBytes(data[offset : offset+64])
-		offset += 64
-	}
-	for _, tr := range sp1.TR {
-		x, y := tr.Back()
-		x.FillBytes(data[offset : offset+32])
-		y.FillBytes(data[offset : offset+64])
-		offset += 64
-	}
-	x, y := sp1.BigC.Back()
-	x.FillBytes(data[offset : offset+32])
-	y.FillBytes(data[offset : offset+64])
-	offset += 64
-	x, y = sp1.BigCPrime.Back()
-	x.FillBytes(data[offset : offset+32])
-	y.FillBytes(data[offset : offset+64])
-	offset += 64
+	points := append(append([]vc.FastPoint{}, sp1.TL...), sp1.TR...)
+	points = append(points, sp1.BigC, sp1.BigCPrime)
+	storePoints(data[offset:], points, 64, storePoint)
+	offset += 64 * len(points)
 	sp1.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	sp1.E2.FillBytes(data[offset : offset+32])
@@ -67,22 +50,10 @@ func (sp1 *SubProof2) Serialize() []byte {
 	data := make([]byte, 64*count+64*count+64+32*2+4)
 	binary.BigEndian.PutUint32(data, uint32(count))
 	offset := 4
-	for _, tl := range sp1.TL {
-		x, y := tl.Back()
-		x.FillBytes(data[offset : offset+32])
-		y.FillBytes(data[offset : offset+64])
-		offset += 64
-	}
-	for _, tr := range sp1.TR {
-		x, y := tr.Back()
-		x.FillBytes(data[offset : offset+32])
-		y.FillBytes(data[offset : offset+64])
-		offset += 64
-	}
-	x, y := sp1.BigC.Back()
-	x.FillBytes(data[offset : offset+32])
-	y.FillBytes(data[offset : offset+64])
-	offset += 64
+	points := append(append([]vc.FastPoint{}, sp1.TL...), sp1.TR...)
+	points = append(points, sp1.BigC)
+	storePoints(data[offset:], points, 64, storePoint)
+	offset += 64 * len(points)
 	sp1.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	sp1.E2.FillBytes(data[offset : offset+32])
@@ -95,26 +66,10 @@ func (sp1 *SubProof1) SerializeCompressed() []byte {
 	data[0] = 2
 	data[1] = byte(count)
 	offset := 2
-	for _, tl := range sp1.TL {
-		x, y := tl.Back()
-		data[offset] = 0x02 | byte(y.Bit(0))
-		x.FillBytes(data[offset+1 : offset+33])
-		offset += 33
-	}
-	for _, tr := range sp1.TR {
-		x, y := tr.Back()
-		data[offset] = 0x02 | byte(y.Bit(0))
-		x.FillBytes(data[offset+1 : offset+33])
-		offset += 33
-	}
-	x, y := sp1.BigC.Back()
-	data[offset] = 0x02 | byte(y.Bit(0))
-	x.FillBytes(data[offset+1 : offset+33])
-	offset += 33
-	x, y = sp1.BigCPrime.Back()
-	data[offset] = 0x02 | byte(y.Bit(0))
-	x.FillBytes(data[offset+1 : offset+33])
-	offset += 33
+	points := append(append([]vc.FastPoint{}, sp1.TL...), sp1.TR...)
+	points = append(points, sp1.BigC, sp1.BigCPrime)
+	storePoints(data[offset:], points, 33, storePointCompressed)
+	offset += 33 * len(points)
 	sp1.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	sp1.E2.FillBytes(data[offset : offset+32])
@@ -129,22 +84,10 @@ func (sp1 *SubProof2) SerializeCompressed() []byte {
 	data[0] = 2
 	data[1] = byte(count)
 	offset := 2
-	for _, tl := range sp1.TL {
-		x, y := tl.Back()
-		data[offset] = 0x02 | byte(y.Bit(0))
-		x.FillBytes(data[offset+1 : offset+33])
-		offset += 33
-	}
-	for _, tr := range sp1.TR {
-		x, y := tr.Back()
-		data[offset] = 0x02 | byte(y.Bit(0))
-		x.FillBytes(data[offset+1 : offset+33])
-		offset += 33
-	}
-	x, y := sp1.BigC.Back()
-	data[offset] = 0x02 | byte(y.Bit(0))
-	x.FillBytes(data[offset+1 : offset+33])
-	offset += 33
+	points := append(append([]vc.FastPoint{}, sp1.TL...), sp1.TR...)
+	points = append(points, sp1.BigC)
+	storePoints(data[offset:], points, 33, storePointCompressed)
+	offset += 33 * len(points)
 	sp1.E1.FillBytes(data[offset : offset+32])
 	offset += 32
 	sp1.E2.FillBytes(data[offset : offset+32])
@@ -152,16 +95,16 @@ func (sp1 *SubProof2) SerializeCompressed() []byte {
 }
 
 func GetChallengeData(base []byte, ec []vc.FastPoint, bn []*big.Int) []byte {
-	h := sha256.New()
+	h := newHash()
 	h.Reset()
 	if len(base) > 0 {
 		h.Write(base)
 	}
 	if len(ec) > 0 {
-		for _, e := range ec {
-			ex, ey := e.Back()
-			h.Write(ex.Bytes())
-			h.Write(ey.Bytes())
+		xs, ys := fastCurve.BatchBack(ec)
+		for i := range ec {
+			h.Write(xs[i].Bytes())
+			h.Write(ys[i].Bytes())
 		}
 	}
 	if len(bn) > 0 {
@@ -172,7 +115,7 @@ func GetChallengeData(base []byte, ec []vc.FastPoint, bn []*big.Int) []byte {
 	return h.Sum(nil)
 }
 
-func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.FastPoint, v1, v2 []*big.Int, x,
+func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []vc.FastBn, hashR, u vc.FastPoint, v1, v2 []vc.FastBn, x,
 	o *big.Int, challengeBytes []byte, random io.Reader) (*SubProof1, []byte, error) {
 	N := fastCurve.Params().N
 	//hashR := GetHashByECBN(g, hSum, Cipss, u, x)
@@ -181,25 +124,28 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 
 	gSlot := make([]vc.FastPoint, length)
 	hSlot := make([]vc.FastPoint, length)
-	v1Slot := make([]*big.Int, length)
-	v2Slot := make([]*big.Int, length)
+	v1Slot := make([]vc.FastBn, length)
+	v2Slot := make([]vc.FastBn, length)
 	for i := int32(0); i < L; i++ {
 		gSlot[i] = fastCurve.NewPoint()
 		hSlot[i] = fastCurve.NewPoint()
 		gSlot[i].CopyFrom(tumbler.G[i])
 		hSlot[i].CopyFrom(tumbler.H[i])
-		v1Slot[i] = new(big.Int).Set(v1[i])
-		v2Slot[i] = new(big.Int).Set(v2[i])
+		v1Slot[i] = fastCurve.NewBn()
+		v1Slot[i].CopyFrom(v1[i])
+		v2Slot[i] = fastCurve.NewBn()
+		v2Slot[i].CopyFrom(v2[i])
 	}
 	for i := int(L); i < length; i++ {
 		gSlot[i] = fastCurve.NewPoint()
 		hSlot[i] = fastCurve.NewPoint()
 		gSlot[i].CopyFrom(tumbler.G[L-1])
 		hSlot[i].CopyFrom(tumbler.H[L-1])
-		v1Slot[i] = big.NewInt(0)
-		v2Slot[i] = big.NewInt(0)
+		v1Slot[i] = fastCurve.NewBn().SetInt64(0)
+		v2Slot[i] = fastCurve.NewBn().SetInt64(0)
 	}
-	var sigmaL, sigmaR, exp, hashC, hashCInv *big.Int
+	var sigmaL, sigmaR, hashC, hashCInv *big.Int
+	var bnC, bnCInv vc.FastBn
 	tL := make([]vc.FastPoint, count)
 	tR := make([]vc.FastPoint, count)
 	//accL := fastCurve.NewPoint()
@@ -248,17 +194,14 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 				wg.Add(1)
 				go func(start, end, index int, tl, tr []vc.FastPoint) {
 					defer wg.Done()
+					tmpVal := fastCurve.NewBn()
 					for i := start; i < end; i++ {
 						if stackDepth > 0 {
-							scalarG[i] = v1Slot[half+i].Bytes()
-							scalarH[i] = v2Slot[i].Bytes()
+							scalarG[i] = v1Slot[half+i].Back()
+							scalarH[i] = v2Slot[i].Back()
 						} else {
-							tmpVal := new(big.Int).Mul(gFactor[i], v1Slot[half+i])
-							tmpVal.Mod(tmpVal, N)
-							scalarG[i] = tmpVal.Bytes()
-							tmpVal = new(big.Int).Mul(hFactor[half+i], v2Slot[i])
-							tmpVal.Mod(tmpVal, N)
-							scalarH[i] = tmpVal.Bytes()
+							scalarG[i] = tmpVal.Mul(gFactor[i], v1Slot[half+i]).Back()
+							scalarH[i] = tmpVal.Mul(hFactor[half+i], v2Slot[i]).Back()
 						}
 					}
 					tmpG := fastCurve.NewPoint()
@@ -275,15 +218,11 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 
 					for i := start; i < end; i++ {
 						if stackDepth > 0 {
-							scalarG[i] = v1Slot[i].Bytes()
-							scalarH[i] = v2Slot[half+i].Bytes()
+							scalarG[i] = v1Slot[i].Back()
+							scalarH[i] = v2Slot[half+i].Back()
 						} else {
-							tmpVal := new(big.Int).Mul(gFactor[half+i], v1Slot[i])
-							tmpVal.Mod(tmpVal, N)
-							scalarG[i] = tmpVal.Bytes()
-							tmpVal = new(big.Int).Mul(hFactor[i], v2Slot[half+i])
-							tmpVal.Mod(tmpVal, N)
-							scalarH[i] = tmpVal.Bytes()
+							scalarG[i] = tmpVal.Mul(gFactor[half+i], v1Slot[i]).Back()
+							scalarH[i] = tmpVal.Mul(hFactor[i], v2Slot[half+i]).Back()
 						}
 					}
 					if stackDepth > 0 {
@@ -303,31 +242,23 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 				fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tLPieces[i])
 				fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tRPieces[i])
 			}
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(v1Slot[half+i], v2Slot[i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
+			exp := innerProduct(v1Slot[half:2*half], v2Slot[:half])
 			tmpG := fastCurve.NewPoint()
-			fastCurve.FastScalarMult(tmpG, hashR, exp.Bytes())
+			fastCurve.FastScalarMult(tmpG, hashR, exp.Back())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpG)
 			fastCurve.FastScalarMult(tmpG, u, sigmaL.Bytes())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpG)
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(v1Slot[i], v2Slot[half+i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpG, hashR, exp.Bytes())
+			exp = innerProduct(v1Slot[:half], v2Slot[half:2*half])
+			fastCurve.FastScalarMult(tmpG, hashR, exp.Back())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpG)
 			fastCurve.FastScalarMult(tmpG, u, sigmaR.Bytes())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpG)
 
 			challengeBytes = GetChallengeData(challengeBytes, []vc.FastPoint{tL[stackDepth], tR[stackDepth]}, nil)
 			hashC = new(big.Int).Mod(new(big.Int).SetBytes(challengeBytes), N)
-			hashCInv = fastCurve.Inverse(hashC)
+			bnC = newBn(hashC)
+			bnCInv = fastCurve.NewBn().Inverse(bnC)
+			hashCInv = bnCInv.Big()
 
 			val := new(big.Int).Mul(hashCInv, sigmaL)
 			o.Add(o, val)
@@ -343,27 +274,21 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 				wg.Add(1)
 				go func(start, end int) {
 					defer wg.Done()
+					tmpVal := fastCurve.NewBn()
 					for i := start; i < end; i++ {
-						tmpVal := new(big.Int).Mul(hashCInv, v1Slot[half+i])
-						tmpVal.Mod(tmpVal, N)
-						v1Slot[i].Add(v1Slot[i], tmpVal)
-						v1Slot[i].Mod(v1Slot[i], N)
+						v1Slot[i].Add(v1Slot[i], tmpVal.Mul(bnCInv, v1Slot[half+i]))
 					}
 					for i := start; i < end; i++ {
-						tmpVal := new(big.Int).Mul(hashC, v2Slot[half+i])
-						tmpVal.Mod(tmpVal, N)
-						v2Slot[i].Add(v2Slot[i], tmpVal)
-						v2Slot[i].Mod(v2Slot[i], N)
+						v2Slot[i].Add(v2Slot[i], tmpVal.Mul(bnC, v2Slot[half+i]))
 					}
 					for i := start; i < end; i++ {
 						if stackDepth > 0 {
 							fastCurve.FastScalarMult(gSlot[half+i], gSlot[half+i], hashCBytes)
 							fastCurve.FastPointAdd(gSlot[i], gSlot[i], gSlot[half+i])
 						} else {
-							tmpVal := new(big.Int).Mul(hashC, gFactor[half+i])
-							tmpVal.Mod(tmpVal, N)
+							tmpVal.Mul(bnC, gFactor[half+i])
 							fastCurve.FasterPolynomial(gSlot[i], []vc.FastPoint{gSlot[i], gSlot[half+i]},
-								[][]byte{gFactor[i].Bytes(), tmpVal.Bytes()}, true)
+								[][]byte{gFactor[i].Back(), tmpVal.Back()}, true)
 						}
 
 					}
@@ -372,10 +297,9 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 							fastCurve.FastScalarMult(hSlot[half+i], hSlot[half+i], invBytes)
 							fastCurve.FastPointAdd(hSlot[i], hSlot[i], hSlot[half+i])
 						} else {
-							tmpVal := new(big.Int).Mul(hashCInv, hFactor[half+i])
-							tmpVal.Mod(tmpVal, N)
+							tmpVal.Mul(bnCInv, hFactor[half+i])
 							fastCurve.FasterPolynomial(hSlot[i], []vc.FastPoint{hSlot[i], hSlot[half+i]},
-								[][]byte{hFactor[i].Bytes(), tmpVal.Bytes()}, true)
+								[][]byte{hFactor[i].Back(), tmpVal.Back()}, true)
 						}
 					}
 				}(startIndex, endIndex)
@@ -386,19 +310,16 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 		} else {
 			scalarG := fullScalarG[:half]
 			scalarH := fullScalarH[:half]
+			tmpVal := fastCurve.NewBn()
 			if stackDepth > 0 {
 				for i := 0; i < half; i++ {
-					scalarG[i] = v1Slot[half+i].Bytes()
-					scalarH[i] = v2Slot[i].Bytes()
+					scalarG[i] = v1Slot[half+i].Back()
+					scalarH[i] = v2Slot[i].Back()
 				}
 			} else {
 				for i := 0; i < half; i++ {
-					tmpVal := new(big.Int).Mul(v1Slot[half+i], gFactor[i])
-					tmpVal.Mod(tmpVal, N)
-					scalarG[i] = tmpVal.Bytes()
-					tmpVal = new(big.Int).Mul(v2Slot[i], hFactor[half+i])
-					tmpVal.Mod(tmpVal, N)
-					scalarH[i] = tmpVal.Bytes()
+					scalarG[i] = tmpVal.Mul(v1Slot[half+i], gFactor[i]).Back()
+					scalarH[i] = tmpVal.Mul(v2Slot[i], hFactor[half+i]).Back()
 				}
 			}
 			tmpG := fastCurve.NewPoint()
@@ -412,29 +333,20 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 			}
 			fastCurve.FastPointAdd(tmpG, tmpG, tmpH)
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpG)
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(v1Slot[half+i], v2Slot[i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpG, hashR, exp.Bytes())
+			exp := innerProduct(v1Slot[half:2*half], v2Slot[:half])
+			fastCurve.FastScalarMult(tmpG, hashR, exp.Back())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpG)
 			fastCurve.FastScalarMult(tmpH, u, sigmaL.Bytes())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
 			if stackDepth > 0 {
 				for i := 0; i < half; i++ {
-					scalarG[i] = v1Slot[i].Bytes()
-					scalarH[i] = v2Slot[half+i].Bytes()
+					scalarG[i] = v1Slot[i].Back()
+					scalarH[i] = v2Slot[half+i].Back()
 				}
 			} else {
 				for i := 0; i < half; i++ {
-					tmpVal := new(big.Int).Mul(v1Slot[i], gFactor[half+i])
-					tmpVal.Mod(tmpVal, N)
-					scalarG[i] = tmpVal.Bytes()
-					tmpVal.Mul(v2Slot[half+i], hFactor[i])
-					tmpVal.Mod(tmpVal, N)
-					scalarH[i] = tmpVal.Bytes()
+					scalarG[i] = tmpVal.Mul(v1Slot[i], gFactor[half+i]).Back()
+					scalarH[i] = tmpVal.Mul(v2Slot[half+i], hFactor[i]).Back()
 				}
 			}
 			if stackDepth > 0 {
@@ -446,13 +358,8 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 			}
 			fastCurve.FastPointAdd(tmpG, tmpG, tmpH)
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpG)
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(v1Slot[i], v2Slot[half+i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpG, hashR, exp.Bytes())
+			exp = innerProduct(v1Slot[:half], v2Slot[half:2*half])
+			fastCurve.FastScalarMult(tmpG, hashR, exp.Back())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpG)
 			fastCurve.FastScalarMult(tmpH, u, sigmaR.Bytes())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
@@ -462,19 +369,15 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 			//hashC = GetHashByEC(accL, accR)
 			challengeBytes = GetChallengeData(challengeBytes, []vc.FastPoint{tL[stackDepth], tR[stackDepth]}, nil)
 			hashC = new(big.Int).Mod(new(big.Int).SetBytes(challengeBytes), N)
-			hashCInv = fastCurve.Inverse(hashC)
+			bnC = newBn(hashC)
+			bnCInv = fastCurve.NewBn().Inverse(bnC)
+			hashCInv = bnCInv.Big()
 
 			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(hashCInv, v1Slot[half+i])
-				tmpVal.Mod(tmpVal, N)
-				v1Slot[i].Add(v1Slot[i], tmpVal)
-				v1Slot[i].Mod(v1Slot[i], N)
+				v1Slot[i].Add(v1Slot[i], tmpVal.Mul(bnCInv, v1Slot[half+i]))
 			}
 			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(hashC, v2Slot[half+i])
-				tmpVal.Mod(tmpVal, N)
-				v2Slot[i].Add(v2Slot[i], tmpVal)
-				v2Slot[i].Mod(v2Slot[i], N)
+				v2Slot[i].Add(v2Slot[i], tmpVal.Mul(bnC, v2Slot[half+i]))
 			}
 			val := new(big.Int).Mul(hashCInv, sigmaL)
 			o.Add(o, val)
@@ -487,10 +390,9 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 				if stackDepth > 0 {
 					fastCurve.FastScalarMult(gSlot[half+i], gSlot[half+i], hashCBytes)
 				} else {
-					tmpVal := new(big.Int).Mul(hashC, gFactor[half+i])
-					tmpVal.Mod(tmpVal, N)
-					fastCurve.FastScalarMult(gSlot[i], gSlot[i], gFactor[i].Bytes())
-					fastCurve.FastScalarMult(gSlot[half+i], gSlot[half+i], tmpVal.Bytes())
+					tmpVal.Mul(bnC, gFactor[half+i])
+					fastCurve.FastScalarMult(gSlot[i], gSlot[i], gFactor[i].Back())
+					fastCurve.FastScalarMult(gSlot[half+i], gSlot[half+i], tmpVal.Back())
 
 				}
 				fastCurve.FastPointAdd(gSlot[i], gSlot[i], gSlot[half+i])
@@ -499,10 +401,9 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 				if stackDepth > 0 {
 					fastCurve.FastScalarMult(hSlot[half+i], hSlot[half+i], invBytes)
 				} else {
-					tmpVal := new(big.Int).Mul(hashCInv, hFactor[half+i])
-					tmpVal.Mod(tmpVal, N)
-					fastCurve.FastScalarMult(hSlot[i], hSlot[i], hFactor[i].Bytes())
-					fastCurve.FastScalarMult(hSlot[half+i], hSlot[half+i], tmpVal.Bytes())
+					tmpVal.Mul(bnCInv, hFactor[half+i])
+					fastCurve.FastScalarMult(hSlot[i], hSlot[i], hFactor[i].Back())
+					fastCurve.FastScalarMult(hSlot[half+i], hSlot[half+i], tmpVal.Back())
 				}
 				fastCurve.FastPointAdd(hSlot[i], hSlot[i], hSlot[half+i])
 			}
@@ -527,35 +428,35 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 	}
 
 	bigC := fastCurve.NewPoint()
-	fastCurve.FastScalarMult(bigC, gSlot[0], randomY1.Bytes())
+	fastCurve.FastScalarMultSecret(bigC, gSlot[0], randomY1.Bytes())
 	tmpVal := fastCurve.NewPoint()
-	fastCurve.FastScalarMult(tmpVal, hSlot[0], randomY2.Bytes())
+	fastCurve.FastScalarMultSecret(tmpVal, hSlot[0], randomY2.Bytes())
 	fastCurve.FastPointAdd(bigC, bigC, tmpVal)
-	tmpBn := new(big.Int).Mul(v2Slot[0], randomY1)
+	tmpBn := new(big.Int).Mul(v2Slot[0].Big(), randomY1)
 	tmpBn.Mod(tmpBn, N)
-	tmpBn.Add(tmpBn, new(big.Int).Mul(v1Slot[0], randomY2))
+	tmpBn.Add(tmpBn, new(big.Int).Mul(v1Slot[0].Big(), randomY2))
 	tmpBn.Mod(tmpBn, N)
-	fastCurve.FastScalarMult(tmpVal, hashR, tmpBn.Bytes())
+	fastCurve.FastScalarMultSecret(tmpVal, hashR, tmpBn.Bytes())
 	fastCurve.FastPointAdd(bigC, bigC, tmpVal)
-	fastCurve.FastScalarMult(tmpVal, u, randomSigma.Bytes())
+	fastCurve.FastScalarMultSecret(tmpVal, u, randomSigma.Bytes())
 	fastCurve.FastPointAdd(bigC, bigC, tmpVal)
 
 	tmpBn.Mul(randomY1, randomY2)
 	tmpBn.Mod(tmpBn, N)
 	bigCPrime := fastCurve.NewPoint()
-	fastCurve.FastScalarMult(bigCPrime, hashR, tmpBn.Bytes())
-	fastCurve.FastScalarMult(tmpVal, u, randomSigmaPrime.Bytes())
+	fastCurve.FastScalarMultSecret(bigCPrime, hashR, tmpBn.Bytes())
+	fastCurve.FastScalarMultSecret(tmpVal, u, randomSigmaPrime.Bytes())
 	fastCurve.FastPointAdd(bigCPrime, bigCPrime, tmpVal)
 
 	challengeBytes = GetChallengeData(challengeBytes, []vc.FastPoint{bigC, bigCPrime}, nil)
 	randomXi := new(big.Int).Mod(new(big.Int).SetBytes(challengeBytes), N)
 	randomXiInv := fastCurve.Inverse(randomXi)
 
-	e1 := new(big.Int).Mul(randomXi, v1Slot[0])
+	e1 := new(big.Int).Mul(randomXi, v1Slot[0].Big())
 	e1.Add(e1, randomY1)
 	e1.Mod(e1, N)
 
-	e2 := new(big.Int).Mul(randomXi, v2Slot[0])
+	e2 := new(big.Int).Mul(randomXi, v2Slot[0].Big())
 	e2.Add(e2, randomY2)
 	e2.Mod(e2, N)
 
@@ -583,29 +484,31 @@ func (tumbler *Tumbler) GenSubProof1(gFactor, hFactor []*big.Int, hashR, u vc.Fa
 	}, challengeBytes, nil
 }
 
-func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*big.Int, streamA []byte,
+func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []vc.FastBn, streamA []byte,
 	o3 *big.Int, challengeBytes []byte, random io.Reader) (*SubProof2, error) {
 	N := fastCurve.Params().N
 	count := int32(math.Log2(float64(D * BPrime)))
 	length := 1 << count
 
 	hSlot := make([]vc.FastPoint, length)
-	zSlot := make([]*big.Int, length)
-	aSlot := make([]*big.Int, length)
+	zSlot := make([]vc.FastBn, length)
+	aSlot := make([]vc.FastBn, length)
 	for i := int32(0); i < D*BPrime; i++ {
 		hSlot[i] = fastCurve.NewPoint()
 		hSlot[i].CopyFrom(h[i])
-		zSlot[i] = new(big.Int).Set(z[i])
-		aSlot[i] = big.NewInt(int64(streamA[i]))
+		zSlot[i] = fastCurve.NewBn()
+		zSlot[i].CopyFrom(z[i])
+		aSlot[i] = fastCurve.NewBn().SetInt64(int64(streamA[i]))
 	}
 	for i := D * BPrime; i < int32(length); i++ {
 		hSlot[i] = fastCurve.NewPoint()
 		hSlot[i].CopyFrom(h[D*BPrime-1])
-		zSlot[i] = new(big.Int).Set(z[D*BPrime-1])
-		aSlot[i] = big.NewInt(int64(streamA[D*BPrime-1]))
+		zSlot[i] = fastCurve.NewBn()
+		zSlot[i].CopyFrom(z[D*BPrime-1])
+		aSlot[i] = fastCurve.NewBn().SetInt64(int64(streamA[D*BPrime-1]))
 	}
 
-	var sigmaL, sigmaR, exp *big.Int
+	var sigmaL, sigmaR *big.Int
 	tL := make([]vc.FastPoint, count)
 	tR := make([]vc.FastPoint, count)
 	//accL := fastCurve.NewPoint()
@@ -667,13 +570,13 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 						}
 					} else {
 						for i := start; i < end; i++ {
-							scalarH[i] = aSlot[i].Bytes()
+							scalarH[i] = aSlot[i].Back()
 						}
 						fastCurve.FastPolynomial(tmpH, hSlot[half+start:half+end], scalarH[start:end])
 						fastCurve.FastPointAdd(tLPieces[index], tLPieces[index], tmpH)
 
 						for i := start; i < end; i++ {
-							scalarH[i] = aSlot[half+i].Bytes()
+							scalarH[i] = aSlot[half+i].Back()
 						}
 						fastCurve.FastPolynomial(tmpH, hSlot[start:end], scalarH[start:end])
 						fastCurve.FastPointAdd(tRPieces[index], tRPieces[index], tmpH)
@@ -685,31 +588,23 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 				fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tLPieces[i])
 				fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tRPieces[i])
 			}
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(aSlot[i], zSlot[half+i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
+			exp := innerProduct(aSlot[:half], zSlot[half:2*half])
 			tmpH := fastCurve.NewPoint()
-			fastCurve.FastScalarMult(tmpH, f, exp.Bytes())
+			fastCurve.FastScalarMult(tmpH, f, exp.Back())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
 			fastCurve.FastScalarMult(tmpH, u, sigmaL.Bytes())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(aSlot[half+i], zSlot[i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpH, f, exp.Bytes())
+			exp = innerProduct(aSlot[half:2*half], zSlot[:half])
+			fastCurve.FastScalarMult(tmpH, f, exp.Back())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
 			fastCurve.FastScalarMult(tmpH, u, sigmaR.Bytes())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
 
 			challengeBytes = GetChallengeData(challengeBytes, []vc.FastPoint{tL[stackDepth], tR[stackDepth]}, nil)
 			hashC := new(big.Int).Mod(new(big.Int).SetBytes(challengeBytes), N)
-			hashCInv := fastCurve.Inverse(hashC)
+			bnC := newBn(hashC)
+			bnCInv := fastCurve.NewBn().Inverse(bnC)
+			hashCInv := bnCInv.Big()
 			hashCBytes := hashC.Bytes()
 
 			val := new(big.Int).Mul(hashC, sigmaL)
@@ -730,18 +625,13 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 						fastCurve.FastScalarMult(tmpPoint, hSlot[i+half], hashCBytes)
 						fastCurve.FastPointAdd(hSlot[i], hSlot[i], tmpPoint)
 					}
+					tmpVal := fastCurve.NewBn()
 					for i := start; i < end; i++ {
-						tmpVal := new(big.Int).Mul(zSlot[half+i], hashC)
-						tmpVal.Mod(tmpVal, N)
-						zSlot[i].Add(zSlot[i], tmpVal)
-						zSlot[i].Mod(zSlot[i], N)
+						zSlot[i].Add(zSlot[i], tmpVal.Mul(zSlot[half+i], bnC))
 					}
 
 					for i := start; i < end; i++ {
-						tmpVal := new(big.Int).Mul(aSlot[half+i], hashCInv)
-						tmpVal.Mod(tmpVal, N)
-						aSlot[i].Add(aSlot[i], tmpVal)
-						aSlot[i].Mod(aSlot[i], N)
+						aSlot[i].Add(aSlot[i], tmpVal.Mul(aSlot[half+i], bnCInv))
 					}
 
 				}(startIndex, endIndex)
@@ -762,19 +652,14 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 				}
 			} else {
 				for i := 0; i < half; i++ {
-					scalarH[i] = aSlot[i].Bytes()
+					scalarH[i] = aSlot[i].Back()
 				}
 				fastCurve.FastPolynomial(tmpH, hSlot[half:2*half], scalarH)
 				fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
 			}
 
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(aSlot[i], zSlot[half+i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpH, f, exp.Bytes())
+			exp := innerProduct(aSlot[:half], zSlot[half:2*half])
+			fastCurve.FastScalarMult(tmpH, f, exp.Back())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
 			fastCurve.FastScalarMult(tmpH, u, sigmaL.Bytes())
 			fastCurve.FastPointAdd(tL[stackDepth], tL[stackDepth], tmpH)
@@ -790,25 +675,22 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 				}
 			} else {
 				for i := 0; i < half; i++ {
-					scalarH[i] = aSlot[half+i].Bytes()
+					scalarH[i] = aSlot[half+i].Back()
 				}
 				fastCurve.FastPolynomial(tmpH, hSlot[0:half], scalarH)
 				fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
 			}
-			exp = big.NewInt(0)
-			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(aSlot[half+i], zSlot[i])
-				exp.Add(exp, tmpVal)
-				exp.Mod(exp, N)
-			}
-			fastCurve.FastScalarMult(tmpH, f, exp.Bytes())
+			exp = innerProduct(aSlot[half:2*half], zSlot[:half])
+			fastCurve.FastScalarMult(tmpH, f, exp.Back())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
 			fastCurve.FastScalarMult(tmpH, u, sigmaR.Bytes())
 			fastCurve.FastPointAdd(tR[stackDepth], tR[stackDepth], tmpH)
 
 			challengeBytes = GetChallengeData(challengeBytes, []vc.FastPoint{tL[stackDepth], tR[stackDepth]}, nil)
 			hashC := new(big.Int).Mod(new(big.Int).SetBytes(challengeBytes), N)
-			hashCInv := fastCurve.Inverse(hashC)
+			bnC := newBn(hashC)
+			bnCInv := fastCurve.NewBn().Inverse(bnC)
+			hashCInv := bnCInv.Big()
 			hashCBytes := hashC.Bytes()
 
 			tmpPoint := fastCurve.NewPoint()
@@ -816,18 +698,13 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 				fastCurve.FastScalarMult(tmpPoint, hSlot[i+half], hashCBytes)
 				fastCurve.FastPointAdd(hSlot[i], hSlot[i], tmpPoint)
 			}
+			tmpVal := fastCurve.NewBn()
 			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(zSlot[half+i], hashC)
-				tmpVal.Mod(tmpVal, N)
-				zSlot[i].Add(zSlot[i], tmpVal)
-				zSlot[i].Mod(zSlot[i], N)
+				zSlot[i].Add(zSlot[i], tmpVal.Mul(zSlot[half+i], bnC))
 			}
 
 			for i := 0; i < half; i++ {
-				tmpVal := new(big.Int).Mul(aSlot[half+i], hashCInv)
-				tmpVal.Mod(tmpVal, N)
-				aSlot[i].Add(aSlot[i], tmpVal)
-				aSlot[i].Mod(aSlot[i], N)
+				aSlot[i].Add(aSlot[i], tmpVal.Mul(aSlot[half+i], bnCInv))
 			}
 			val := new(big.Int).Mul(hashC, sigmaL)
 			o3.Add(o3, val)
@@ -848,7 +725,7 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 	}
 
 	bigC := fastCurve.NewPoint()
-	tmpVal := new(big.Int).Mul(randomY1, zSlot[0])
+	tmpVal := new(big.Int).Mul(randomY1, zSlot[0].Big())
 	tmpVal.Mod(tmpVal, N)
 	fastCurve.FastPolynomial(bigC, []vc.FastPoint{hSlot[0], f, u},
 		[][]byte{randomY1.Bytes(), tmpVal.Bytes(), randomY2.Bytes()})
@@ -857,7 +734,7 @@ func (tumbler *Tumbler) GenSubProof2(h []vc.FastPoint, f, u vc.FastPoint, z []*b
 	randomXi := new(big.Int).SetBytes(challengeBytes)
 	randomXi.Mod(randomXi, N)
 
-	e1 := new(big.Int).Sub(randomY1, new(big.Int).Mul(randomXi, aSlot[0]))
+	e1 := new(big.Int).Sub(randomY1, new(big.Int).Mul(randomXi, aSlot[0].Big()))
 	e1.Mod(e1, N)
 
 	e2 := new(big.Int).Sub(randomY2, new(big.Int).Mul(randomXi, o3))
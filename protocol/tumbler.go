@@ -2,12 +2,10 @@ package protocol
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"math/big"
-	"os"
 	"sync"
 	"volley/adaptor"
 	vc "volley/curve"
@@ -50,162 +48,85 @@ type Tumbler struct {
 	Box    [][]*big.Int
 	B1List []*big.Int
 
-	Secret *big.Int
+	// Signer and Decrypter hold the secret keys, the tumbler itself never
+	// sees them
+	Signer Signer
 	Public vc.FastPoint
 
 	BobPublic   vc.FastPoint
 	AlicePublic vc.FastPoint
+	// AliceTable is optional and speeds up checking Alice's signature
+	AliceTable vc.Precomputed
 
-	RLWESecret *lpr.PrivateKey
+	Decrypter  Decrypter
 	RLWEPublic *lpr.PublicKey
 }
 
-func (tumbler *Tumbler) Init(genPath, prePath, secretPath, alicePath, bobPath, rlwePrivate, rlwePublic string) error {
-	ghuBytes, err := os.ReadFile(genPath)
-	if err != nil {
-		return err
-	}
-	if int32(len(ghuBytes)) < (L*2+1)*64 {
-		return fmt.Errorf("Length error: %d, %d\n", len(ghuBytes), (L*2+1)*64)
-	}
-	tumbler.G = make([]vc.FastPoint, L)
-	tumbler.H = make([]vc.FastPoint, L)
-	for i := int32(0); i < L; i++ {
-		tumbler.G[i] = fastCurve.NewPoint()
-		gx := new(big.Int).SetBytes(ghuBytes[i*64 : i*64+32])
-		gy := new(big.Int).SetBytes(ghuBytes[i*64+32 : i*64+64])
-		tumbler.G[i].From(gx, gy)
-	}
-	hBytes := ghuBytes[64*L:]
-	for i := int32(0); i < L; i++ {
-		tumbler.H[i] = fastCurve.NewPoint()
-		hx := new(big.Int).SetBytes(hBytes[i*64 : i*64+32])
-		hy := new(big.Int).SetBytes(hBytes[i*64+32 : i*64+64])
-		tumbler.H[i].From(hx, hy)
-	}
-	uBytes := ghuBytes[64*2*L:]
-	tumbler.U = fastCurve.NewPoint()
-	ux := new(big.Int).SetBytes(uBytes[0:32])
-	uy := new(big.Int).SetBytes(uBytes[32:64])
-	tumbler.U.From(ux, uy)
-
-	secretBytes, err := os.ReadFile(secretPath)
-	if err != nil {
-		return err
-	}
-	tumbler.Secret = new(big.Int).SetBytes(secretBytes[0:32])
-	publicX := new(big.Int).SetBytes(secretBytes[32:64])
-	publicY := new(big.Int).SetBytes(secretBytes[64:96])
-	tumbler.Public = fastCurve.NewPoint()
-	tumbler.Public.From(publicX, publicY)
-
-	bobBytes, err := os.ReadFile(bobPath)
-	if err != nil {
-		return err
-	}
-	publicX = new(big.Int).SetBytes(bobBytes[0:32])
-	publicY = new(big.Int).SetBytes(bobBytes[32:64])
-	tumbler.BobPublic = fastCurve.NewPoint()
-	tumbler.BobPublic.From(publicX, publicY)
-
-	aliceBytes, err := os.ReadFile(alicePath)
-	if err != nil {
-		return err
-	}
-	publicX = new(big.Int).SetBytes(aliceBytes[0:32])
-	publicY = new(big.Int).SetBytes(aliceBytes[32:64])
-	tumbler.AlicePublic = fastCurve.NewPoint()
-	tumbler.AlicePublic.From(publicX, publicY)
-
-	box := make([][]*big.Int, 16)
-	for i := 0; i < 16; i++ {
-		box[i] = make([]*big.Int, D)
-		for j := 0; j < int(D); j++ {
-			box[i][j] = big.NewInt(0)
-		}
+// NewTumbler sets up the tumbler from already loaded parameters and keys.
+func NewTumbler(params *PublicParams, signer Signer, decrypter Decrypter, rlwePublic *lpr.PublicKey,
+	alicePublic, bobPublic vc.FastPoint) *Tumbler {
+	return &Tumbler{
+		G:           params.G,
+		H:           params.H,
+		U:           params.U,
+		Box:         newBox(),
+		B1List:      newB1List(),
+		Signer:      signer,
+		Public:      signer.Public(),
+		BobPublic:   bobPublic,
+		AlicePublic: alicePublic,
+		Decrypter:   decrypter,
+		RLWEPublic:  rlwePublic,
 	}
+}
 
-	tmpForBox := make([]*big.Int, 64)
-	tmpForBox[0] = big.NewInt(1)
-	for i := 1; i < 64; i++ {
-		tmpForBox[i] = new(big.Int).Mul(tmpForBox[i-1], big.NewInt(int64(Step)))
-	}
-	for i := 0; i < 16; i++ {
-		for j := 0; j < 64; j++ {
-			box[i][i*64+j] = new(big.Int).Set(tmpForBox[j])
+func (tumbler *Tumbler) Init(genPath, prePath, secretPath, alicePath, bobPath, rlwePrivate, rlwePublic string) error {
+	return tumbler.init(genPath, prePath, alicePath, bobPath, rlwePublic, func() (Signer, Decrypter, error) {
+		key, err := LoadPrivateKey(secretPath)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	boxPrime := make([][]*big.Int, 16)
-	for i := 0; i < 16; i++ {
-		boxPrime[i] = make([]*big.Int, D*2)
-	}
-	N := fastCurve.Params().N
-	minus2 := new(big.Int).Sub(N, big.NewInt(2))
-	for i := 0; i < 16; i++ {
-		for j := 0; j < int(D); j++ {
-			d1 := new(big.Int).Set(box[i][j])
-			d2 := new(big.Int).Mul(box[i][j], minus2)
-			d1.Mod(d1, N)
-			d2.Mod(d2, N)
-			boxPrime[i][j*2] = d1
-			boxPrime[i][j*2+1] = d2
+		rlweKey, err := LoadRLWEPrivateKey(rlwePrivate)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	tumbler.Box = boxPrime
+		return NewLocalSigner(key), NewLocalDecrypter(rlweKey), nil
+	})
+}
 
-	b1List := make([]*big.Int, B1)
-	for i := int32(0); i < B1; i++ {
-		b1List[i] = big.NewInt(int64(1) << i)
-	}
-	b1List[B1-1].Sub(N, b1List[B1-1])
-	tumbler.B1List = b1List
+// InitWithKeys is Init for secret keys held by signer and decrypter.
+func (tumbler *Tumbler) InitWithKeys(genPath, prePath string, signer Signer, decrypter Decrypter,
+	alicePath, bobPath, rlwePublic string) error {
+	return tumbler.init(genPath, prePath, alicePath, bobPath, rlwePublic, func() (Signer, Decrypter, error) {
+		return signer, decrypter, nil
+	})
+}
 
-	preFile, err := os.Open(prePath)
+func (tumbler *Tumbler) init(genPath, prePath, alicePath, bobPath, rlwePublic string,
+	keys func() (Signer, Decrypter, error)) error {
+	params, err := LoadPublicParams(genPath, prePath)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = preFile.Close() }()
-
-	precomputes := make([]byte, 8*64)
-	for i := int32(0); i < L; i++ {
-		_, err = preFile.Read(precomputes)
-		if err != nil {
-			return err
-		}
-		tumbler.G[i].ImportTable(precomputes, true)
-	}
-	for i := int32(0); i < L; i++ {
-		_, err = preFile.Read(precomputes)
-		if err != nil {
-			return err
-		}
-		tumbler.H[i].ImportTable(precomputes, true)
-	}
-
-	rlweSecretBytes, err := os.ReadFile(rlwePrivate)
+	signer, decrypter, err := keys()
 	if err != nil {
 		return err
 	}
-	tumbler.RLWESecret = &lpr.PrivateKey{
-		Data: make([]int32, D),
-	}
-	for i := int32(0); i < D; i++ {
-		if rlweSecretBytes[i] != 0 {
-			tumbler.RLWESecret.Data[i] = -1
-		}
+	alicePublic, err := LoadPublicKey(alicePath)
+	if err != nil {
+		return err
 	}
-
-	rlwePublicBytes, err := os.ReadFile(rlwePublic)
+	bobPublic, err := LoadPublicKey(bobPath)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	tumbler.RLWEPublic = new(lpr.PublicKey)
-	err = tumbler.RLWEPublic.Deserialize(rlwePublicBytes, D, Q)
+	rlwePublicKey, err := LoadRLWEPublicKey(rlwePublic)
 	if err != nil {
-		panic(err)
+		return err
 	}
-
-	return nil
+	*tumbler = *NewTumbler(params, signer, decrypter, rlwePublicKey, alicePublic, bobPublic)
+	tumbler.AliceTable, err = LoadPrecomputed(alicePath+".pre", alicePublic)
+	return err
 }
 
 func (tumbler *Tumbler) Step1x(random io.Reader) (*Proof, []vc.FastPoint, *lpr.Ciphertext, error) {
@@ -256,7 +177,7 @@ func (tumbler *Tumbler) Step1y(tx []byte, y []vc.FastPoint, random io.Reader) ([
 	var err error
 	sigs := make([]*adaptor.Signature, YNumber)
 	for i := 0; i < int(YNumber); i++ {
-		sigs[i], err = adaptor.SchnorrSignAdaptor(tx, y[i], tumbler.Secret, sha256.New(), random)
+		sigs[i], err = tumbler.Signer.SignAdaptor(tx, y[i], random)
 		if err != nil {
 			return nil, err
 		}
@@ -318,7 +239,7 @@ func CalculateY(msg []int32) (vc.FastPoint, *big.Int) {
 	if sum.Sign() == -1 {
 		panic("Sign Error")
 	}
-	return fastCurve.FastBaseScalar(sum.Bytes()), sum
+	return fastCurve.FastBaseScalarSecret(sum.Bytes()), sum
 }
 
 func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *VectorS, random io.Reader) (*Proof, error) {
@@ -375,16 +296,16 @@ func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *Ve
 	}
 
 	tmpEC := fastCurve.NewPoint()
-	fastCurve.FastScalarMult(tmpEC, tumbler.U, o1.Bytes())
+	fastCurve.FastScalarMultSecret(tmpEC, tumbler.U, o1.Bytes())
 	fastCurve.FastPointAdd(w1, w1, tmpEC)
-	fastCurve.FastScalarMult(tmpEC, tumbler.U, o2.Bytes())
+	fastCurve.FastScalarMultSecret(tmpEC, tumbler.U, o2.Bytes())
 	fastCurve.FastPointAdd(w2, w2, tmpEC)
-	fastCurve.FastScalarMult(tmpEC, tumbler.U, o3.Bytes())
+	fastCurve.FastScalarMultSecret(tmpEC, tumbler.U, o3.Bytes())
 	fastCurve.FastPointAdd(w3, w3, tmpEC)
 
 	rp := GetRandomParameter(w1, w2, w3)
 
-	var vectorV []*big.Int
+	var vectorV []vc.FastBn
 	if coreNum > 1 {
 		vectorV = CalcLargeVectorVMultiCore(rp, matrixA, tumbler.B1List)
 	} else {
@@ -394,39 +315,29 @@ func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *Ve
 
 	//gPrime := make([]vc.FastPoint, L)
 	//hPrime := make([]vc.FastPoint, L)
-	gFactor := make([]*big.Int, LP)
-	hFactor := make([]*big.Int, LP)
+	gFactor := make([]vc.FastBn, LP)
+	hFactor := make([]vc.FastBn, LP)
 
 	fPrime := fastCurve.FastBaseScalar(rp.Theta.Bytes())
 	//etaBytes := rp.Eta[0].Bytes()
 	eta2Start := int(3 * D * B)
 	eta2End := eta2Start + int(D*BPrime)
 
-	lastPhiInv := fastCurve.Inverse(rp.Phi[L-1])
-	var wg sync.WaitGroup
-
-	for t := 0; t < coreNum; t++ {
-		start := t * int(LP) / coreNum
-		end := (t + 1) * int(LP) / coreNum
-		wg.Add(1)
-		go func(s, e, index int) {
-			defer wg.Done()
-			for i := s; i < e; i++ {
-				if i >= int(L-1) {
-					gFactor[i] = lastPhiInv
-				} else {
-					inv := fastCurve.Inverse(rp.Phi[i])
-					gFactor[i] = inv
-				}
-				if i >= eta2Start && i < eta2End {
-					hFactor[i] = rp.Eta[1]
-				} else {
-					hFactor[i] = rp.Eta[0]
-				}
-			}
-		}(start, end, t)
+	phiInv := newBnList(rp.Phi)
+	fastCurve.BatchInverse(phiInv)
+	eta := newBnList(rp.Eta)
+	for i := 0; i < int(LP); i++ {
+		if i >= int(L-1) {
+			gFactor[i] = phiInv[L-1]
+		} else {
+			gFactor[i] = phiInv[i]
+		}
+		if i >= eta2Start && i < eta2End {
+			hFactor[i] = eta[1]
+		} else {
+			hFactor[i] = eta[0]
+		}
 	}
-	wg.Wait()
 
 	vectorV1, vectorV2 := CalcVectorV1V2(rp, vectorV, bitStream)
 
@@ -436,13 +347,7 @@ func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *Ve
 	o.Mod(o, N)
 	o.Add(o, o2)
 	o.Mod(o, N)
-	x := big.NewInt(0)
-	for i := int32(0); i < L; i++ {
-		tmpVal := new(big.Int).Mul(vectorV1[i], vectorV2[i])
-		tmpVal.Mod(tmpVal, N)
-		x.Add(x, tmpVal)
-		x.Mod(x, N)
-	}
+	x := innerProduct(vectorV1, vectorV2).Big()
 	data := make([]byte, 2*D*2)
 	for i := int32(0); i < D; i++ {
 		binary.LittleEndian.PutUint16(data[i*2:], uint16(vectorT.T0[i]))
@@ -450,7 +355,7 @@ func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *Ve
 	for i := int32(0); i < D; i++ {
 		binary.LittleEndian.PutUint16(data[2*D+i*2:], uint16(vectorT.T1[i]))
 	}
-	challengeBytes := sha256.Sum256(data)
+	challengeBytes := hashSum(data)
 
 	hashData := GetChallengeData(challengeBytes[:], []vc.FastPoint{w1, w2, w3}, nil)
 	hashBig := new(big.Int).SetBytes(hashData)
@@ -460,12 +365,12 @@ func (tumbler *Tumbler) GenProof(vectorT *VectorT, matrixA *MatrixA, vectorS *Ve
 	sub1, challenge, err := tumbler.GenSubProof1(gFactor, hFactor, hashR, tumbler.U, vectorV1, vectorV2, x, o,
 		hashData, random)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	sub2, err := tumbler.GenSubProof2(tumbler.H[3*D*B:3*D*B+D*BPrime], fPrime, tumbler.U, vectorZ,
 		bitStream[3*D*B:3*D*B+D*BPrime], o3, challenge, random)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	return &Proof{
 		Sub1: sub1,
@@ -508,14 +413,14 @@ func GetRandomParameter(w1, w2, w3 vc.FastPoint) *RandomParameter {
 	rp := new(RandomParameter)
 	binary.BigEndian.PutUint32(slot, count)
 	count++
-	digest := sha256.Sum256(data)
+	digest := hashSum(data)
 	rp.Alpha = new(big.Int).SetBytes(digest[:])
 	rp.Alpha.Mod(rp.Alpha, N)
 	rp.Beta = make([]*big.Int, 16)
 	for i := range rp.Beta {
 		binary.BigEndian.PutUint32(slot, count)
 		count++
-		digest = sha256.Sum256(data)
+		digest = hashSum(data)
 		rp.Beta[i] = new(big.Int).SetBytes(digest[:])
 		rp.Beta[i].Mod(rp.Beta[i], N)
 	}
@@ -523,13 +428,13 @@ func GetRandomParameter(w1, w2, w3 vc.FastPoint) *RandomParameter {
 	for i := range rp.Gamma {
 		binary.BigEndian.PutUint32(slot, count)
 		count++
-		digest = sha256.Sum256(data)
+		digest = hashSum(data)
 		rp.Gamma[i] = new(big.Int).SetBytes(digest[:])
 		rp.Gamma[i].Mod(rp.Gamma[i], N)
 	}
 	binary.BigEndian.PutUint32(slot, count)
 	count++
-	digest = sha256.Sum256(data)
+	digest = hashSum(data)
 	rp.Theta = new(big.Int).SetBytes(digest[:])
 	rp.Theta.Mod(rp.Theta, N)
 
@@ -537,14 +442,14 @@ func GetRandomParameter(w1, w2, w3 vc.FastPoint) *RandomParameter {
 	for i := range rp.Eta {
 		binary.BigEndian.PutUint32(slot, count)
 		count++
-		digest = sha256.Sum256(data)
+		digest = hashSum(data)
 		rp.Eta[i] = new(big.Int).SetBytes(digest[:])
 		rp.Eta[i].Mod(rp.Eta[i], N)
 	}
 
 	binary.BigEndian.PutUint32(slot, count)
 	count++
-	digest = sha256.Sum256(data)
+	digest = hashSum(data)
 	rp.Psi = new(big.Int).SetBytes(digest[:])
 	rp.Psi.Mod(rp.Psi, N)
 
@@ -552,201 +457,168 @@ func GetRandomParameter(w1, w2, w3 vc.FastPoint) *RandomParameter {
 	for i := int32(0); i < L; i++ {
 		binary.BigEndian.PutUint32(slot, count)
 		count++
-		digest = sha256.Sum256(data)
+		digest = hashSum(data)
 		rp.Phi[i] = new(big.Int).SetBytes(digest[:])
 		rp.Phi[i].Mod(rp.Phi[i], N)
 	}
 	return rp
 }
 
-func CalcLargeVectorV(rp *RandomParameter, matrixA *MatrixA, b1List []*big.Int) []*big.Int {
-	vectorV := make([]*big.Int, L)
-	v := vectorV[:]
-	N := fastCurve.Params().N
+// newBn converts n, which must already be reduced modulo N, to the scalar
+// field representation.
+func newBn(n *big.Int) vc.FastBn {
+	bn := fastCurve.NewBn()
+	bn.From(n)
+	return bn
+}
 
+func newBnList(list []*big.Int) []vc.FastBn {
+	res := make([]vc.FastBn, len(list))
+	for i, n := range list {
+		res[i] = newBn(n)
+	}
+	return res
+}
+
+// innerProduct returns the sum of a[i]*b[i] modulo N.
+func innerProduct(a, b []vc.FastBn) vc.FastBn {
+	sum := fastCurve.NewBn().SetInt64(0)
+	tmp := fastCurve.NewBn()
+	for i := range a {
+		sum.Add(sum, tmp.Mul(a[i], b[i]))
+	}
+	return sum
+}
+
+// vectorVInput holds everything CalcLargeVectorV needs, converted to the
+// scalar field once so the workers only do native arithmetic.
+type vectorVInput struct {
+	gamma []vc.FastBn
+	p0    []vc.FastBn
+	p1    []vc.FastBn
+	qB1   []vc.FastBn
+	delta vc.FastBn
+}
+
+func newVectorVInput(rp *RandomParameter, matrixA *MatrixA, b1List []*big.Int) *vectorVInput {
+	in := &vectorVInput{
+		gamma: newBnList(rp.Gamma),
+		p0:    make([]vc.FastBn, D),
+		p1:    make([]vc.FastBn, D),
+		qB1:   make([]vc.FastBn, B1),
+		delta: fastCurve.NewBn().SetInt64(int64(matrixA.Delta)),
+	}
 	for i := int32(0); i < D; i++ {
-		sum := big.NewInt(0)
-		for j := int32(0); j < i; j++ {
-			value := int64(-matrixA.P0[j+D-i])
-			tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j])
-			tmpVal.Mod(tmpVal, N)
-			sum.Add(sum, tmpVal)
-			sum.Mod(sum, N)
-		}
-		for j := i; j < D; j++ {
-			value := int64(matrixA.P0[j-i])
-			tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j])
-			tmpVal.Mod(tmpVal, N)
-			sum.Add(sum, tmpVal)
-			sum.Mod(sum, N)
-		}
-		for j := int32(0); j < i; j++ {
-			value := int64(-matrixA.P1[j+D-i])
-			tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j+D])
-			tmpVal.Mod(tmpVal, N)
-			sum.Add(sum, tmpVal)
-			sum.Mod(sum, N)
+		in.p0[i] = fastCurve.NewBn().SetInt64(int64(matrixA.P0[i]))
+		in.p1[i] = fastCurve.NewBn().SetInt64(int64(matrixA.P1[i]))
+	}
+	q := fastCurve.NewBn().SetInt64(int64(Q))
+	for j := int32(0); j < B1; j++ {
+		in.qB1[j] = fastCurve.NewBn().Mul(q, newBn(b1List[j]))
+	}
+	return in
+}
+
+// fill computes the entries of v that belong to rows [start, end) of the
+// D rows of matrix A.
+func (in *vectorVInput) fill(v []vc.FastBn, start, end int) {
+	d := int(D)
+	b1 := int(B1)
+	tmp := fastCurve.NewBn()
+	for i := start; i < end; i++ {
+		sum := fastCurve.NewBn().SetInt64(0)
+		for j := 0; j < i; j++ {
+			sum.Sub(sum, tmp.Mul(in.p0[j+d-i], in.gamma[j]))
+			sum.Sub(sum, tmp.Mul(in.p1[j+d-i], in.gamma[j+d]))
 		}
-		for j := i; j < D; j++ {
-			value := int64(matrixA.P1[j-i])
-			tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j+D])
-			tmpVal.Mod(tmpVal, N)
-			sum.Add(sum, tmpVal)
-			sum.Mod(sum, N)
+		for j := i; j < d; j++ {
+			sum.Add(sum, tmp.Mul(in.p0[j-i], in.gamma[j]))
+			sum.Add(sum, tmp.Mul(in.p1[j-i], in.gamma[j+d]))
 		}
-		v[i] = new(big.Int).Sub(N, sum)
+		v[i] = sum.Neg(sum)
 	}
-	for i := D; i < 3*D; i++ {
-		v[i] = new(big.Int).Set(rp.Gamma[i-D])
-		v[i].Sub(N, v[i])
+
+	for i := start * 2; i < end*2; i++ {
+		v[d+i] = fastCurve.NewBn().Neg(in.gamma[i])
 	}
-	v = vectorV[3*D:]
 
-	minus2 := new(big.Int).Sub(N, big.NewInt(2))
-	delta := big.NewInt(int64(matrixA.Delta))
-	for i := int32(0); i < D; i++ {
-		v[i*2] = new(big.Int).Mul(rp.Gamma[i], delta)
-		v[i*2].Mod(v[i*2], N)
-		tmp := new(big.Int).Mul(v[i*2], minus2)
-		tmp.Mod(tmp, N)
-		v[i*2+1] = tmp
-	}
-	v = v[2*D:]
-
-	qBig := big.NewInt(int64(Q))
-	for i := int32(0); i < 2*D; i++ {
-		for j := int32(0); j < B1; j++ {
-			tmpVal := new(big.Int).Mul(qBig, rp.Gamma[i])
-			tmpVal.Mod(tmpVal, N)
-			tmpVal.Mul(tmpVal, b1List[j])
-			tmpVal.Mod(tmpVal, N)
-			v[i*B1+j] = tmpVal
+	w := v[3*d:]
+	for i := start; i < end; i++ {
+		w[i*2] = fastCurve.NewBn().Mul(in.gamma[i], in.delta)
+		w[i*2+1] = fastCurve.NewBn().Add(w[i*2], w[i*2])
+		w[i*2+1].Neg(w[i*2+1])
+	}
+
+	w = w[2*d:]
+	for i := start * 2; i < end*2; i++ {
+		for j := 0; j < b1; j++ {
+			w[i*b1+j] = fastCurve.NewBn().Mul(in.gamma[i], in.qB1[j])
 		}
 	}
+}
+
+func CalcLargeVectorV(rp *RandomParameter, matrixA *MatrixA, b1List []*big.Int) []vc.FastBn {
+	vectorV := make([]vc.FastBn, L)
+	newVectorVInput(rp, matrixA, b1List).fill(vectorV, 0, int(D))
 	return vectorV
 }
 
-func CalcLargeVectorZ(rp *RandomParameter, box [][]*big.Int) []*big.Int {
-	z := make([]*big.Int, D*BPrime)
-	for i := 0; i < len(z); i++ {
-		z[i] = big.NewInt(0)
-	}
-	N := fastCurve.Params().N
+func CalcLargeVectorZ(rp *RandomParameter, box [][]*big.Int) []vc.FastBn {
+	z := make([]vc.FastBn, D*BPrime)
+	beta := newBnList(rp.Beta)
+	tmp := fastCurve.NewBn()
 	for i := int32(0); i < D*BPrime; i++ {
+		z[i] = fastCurve.NewBn().SetInt64(0)
 		for j := int32(0); j < 16; j++ {
-			tmpVal := new(big.Int).Mul(box[j][i], rp.Beta[j])
-			z[i].Add(z[i], tmpVal)
-			z[i].Mod(z[i], N)
+			if box[j][i].Sign() == 0 {
+				continue
+			}
+			tmp.From(box[j][i])
+			z[i].Add(z[i], tmp.Mul(tmp, beta[j]))
 		}
 	}
 
 	return z
 }
 
-func CalcVectorV1V2(rp *RandomParameter, v []*big.Int, bitStream []byte) ([]*big.Int, []*big.Int) {
-	v1 := make([]*big.Int, L)
-	v2 := make([]*big.Int, L)
-	N := fastCurve.Params().N
+func CalcVectorV1V2(rp *RandomParameter, v []vc.FastBn, bitStream []byte) ([]vc.FastBn, []vc.FastBn) {
+	v1 := make([]vc.FastBn, L)
+	v2 := make([]vc.FastBn, L)
+	psi := newBn(rp.Psi)
+	psi1 := fastCurve.NewBn().Add(psi, fastCurve.NewBn().SetInt64(1))
+	phi := fastCurve.NewBn()
 	for i := int32(0); i < L; i++ {
-		v1[i] = new(big.Int).Set(v[i])
-		if bitStream[i] == 1 {
-		} else {
-			v1[i].Add(v1[i], rp.Phi[i])
+		phi.From(rp.Phi[i])
+		v1[i] = fastCurve.NewBn()
+		v1[i].CopyFrom(v[i])
+		if bitStream[i] == 0 {
+			v1[i].Add(v1[i], phi)
 		}
-		v1[i].Mod(v1[i], N)
-		tmpVal := new(big.Int).Mul(rp.Phi[i], rp.Psi)
-		v1[i].Add(v1[i], tmpVal)
-		v1[i].Mod(v1[i], N)
+		v1[i].Add(v1[i], phi.Mul(phi, psi))
 	}
-	psi1 := new(big.Int).Add(rp.Psi, big.NewInt(1))
-	psi1.Mod(psi1, N)
 	for i := int32(0); i < L; i++ {
+		v2[i] = fastCurve.NewBn()
 		if bitStream[i] == 0 {
-			v2[i] = new(big.Int).Set(rp.Psi)
+			v2[i].CopyFrom(psi)
 		} else {
-			v2[i] = new(big.Int).Set(psi1)
+			v2[i].CopyFrom(psi1)
 		}
 	}
 	return v1, v2
 }
 
-func CalcLargeVectorVMultiCore(rp *RandomParameter, matrixA *MatrixA, b1List []*big.Int) []*big.Int {
-	vectorV := make([]*big.Int, L)
+func CalcLargeVectorVMultiCore(rp *RandomParameter, matrixA *MatrixA, b1List []*big.Int) []vc.FastBn {
+	vectorV := make([]vc.FastBn, L)
+	in := newVectorVInput(rp, matrixA, b1List)
 
-	N := fastCurve.Params().N
-	minus2 := new(big.Int).Sub(N, big.NewInt(2))
-	delta := big.NewInt(int64(matrixA.Delta))
 	var wg sync.WaitGroup
 	for t := 0; t < coreNum; t++ {
-		v := vectorV[:]
-
 		startIndex := t * int(D) / coreNum
 		endIndex := (t + 1) * int(D) / coreNum
 		wg.Add(1)
 		go func(start, end int) {
 			defer wg.Done()
-
-			for i := int32(start); i < int32(end); i++ {
-				sum := big.NewInt(0)
-				for j := int32(0); j < i; j++ {
-					value := int64(-matrixA.P0[j+D-i])
-					tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j])
-					tmpVal.Mod(tmpVal, N)
-					sum.Add(sum, tmpVal)
-					sum.Mod(sum, N)
-				}
-				for j := i; j < D; j++ {
-					value := int64(matrixA.P0[j-i])
-					tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j])
-					tmpVal.Mod(tmpVal, N)
-					sum.Add(sum, tmpVal)
-					sum.Mod(sum, N)
-				}
-				for j := int32(0); j < i; j++ {
-					value := int64(-matrixA.P1[j+D-i])
-					tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j+D])
-					tmpVal.Mod(tmpVal, N)
-					sum.Add(sum, tmpVal)
-					sum.Mod(sum, N)
-				}
-				for j := i; j < D; j++ {
-					value := int64(matrixA.P1[j-i])
-					tmpVal := new(big.Int).Mul(big.NewInt(value), rp.Gamma[j+D])
-					tmpVal.Mod(tmpVal, N)
-					sum.Add(sum, tmpVal)
-					sum.Mod(sum, N)
-				}
-				v[i] = new(big.Int).Sub(N, sum)
-
-			}
-
-			for i := D + int32(start)*2; i < D+int32(end)*2; i++ {
-				v[i] = new(big.Int).Set(rp.Gamma[i-D])
-				v[i].Sub(N, v[i])
-			}
-
-			v = vectorV[3*D:]
-
-			for i := start; i < end; i++ {
-				v[i*2] = new(big.Int).Mul(rp.Gamma[i], delta)
-				v[i*2].Mod(v[i*2], N)
-				tmp := new(big.Int).Mul(v[i*2], minus2)
-				tmp.Mod(tmp, N)
-				v[i*2+1] = tmp
-			}
-			v = v[2*D:]
-
-			qBig := big.NewInt(int64(Q))
-			for i := int32(start) * 2; i < int32(end)*2; i++ {
-				for j := int32(0); j < B1; j++ {
-					tmpVal := new(big.Int).Mul(qBig, rp.Gamma[i])
-					tmpVal.Mod(tmpVal, N)
-					tmpVal.Mul(tmpVal, b1List[j])
-					tmpVal.Mod(tmpVal, N)
-					v[i*B1+j] = tmpVal
-				}
-			}
-
+			in.fill(vectorV, start, end)
 		}(startIndex, endIndex)
 	}
 	wg.Wait()
@@ -800,20 +672,32 @@ func CalcTSubAs(vectorT *VectorT, vectorAS []int32) []int32 {
 
 func (tumbler *Tumbler) Step4(tx []byte, sigA *adaptor.Signature, yPrime vc.FastPoint,
 	lweCipherList []*lpr.LWECiphertext) (*adaptor.Signature, error) {
-	verified := adaptor.SchnorrPreVerifyAdaptor(sigA, tx, yPrime, tumbler.AlicePublic, sha256.New())
+	var verified bool
+	if tumbler.AliceTable != nil {
+		verified = adaptor.SchnorrPreVerifyAdaptorPrecomputed(sigA, tx, yPrime, tumbler.AliceTable, newHash())
+	} else {
+		verified = adaptor.SchnorrPreVerifyAdaptor(sigA, tx, yPrime, tumbler.AlicePublic, newHash())
+	}
 	if !verified {
-		return nil, fmt.Errorf("Failed to verify adaptor signature of alice\n")
+		return nil, &AdaptorError{Party: "alice"}
 	}
-	plaintext := make([]int32, 64)
-	for i := 0; i < len(plaintext); i++ {
-		plaintext[i] = lpr.LWEDecrypt(lweCipherList[i], tumbler.RLWESecret, Q, T)
+	// the list comes from Bob and may be handed to the key daemon
+	if len(lweCipherList) < 64 {
+		return nil, &MalformedError{What: "LWECipherList", Err: fmt.Errorf("length %d, expected at least 64\n", len(lweCipherList))}
+	}
+	for i, cipher := range lweCipherList[:64] {
+		if cipher == nil {
+			return nil, &MalformedError{What: "LWECipherList", Err: fmt.Errorf("ciphertext %d is missing\n", i)}
+		}
+	}
+	plaintext, err := tumbler.Decrypter.DecryptLWE(lweCipherList[:64])
+	if err != nil {
+		return nil, err
 	}
 	yRight, bn := CalculateY(plaintext)
 
-	yRight.Neg()
-	fastCurve.FastPointAdd(yRight, yRight, yPrime)
-	if !yRight.IsZero() {
-		return nil, fmt.Errorf("Y not verified")
+	if !yRight.Equal(yPrime) {
+		return nil, ErrPuzzleMismatch
 	}
 
 	sigPrime := new(adaptor.Signature)
@@ -0,0 +1,91 @@
+// Package rfc6979 derives signing nonces deterministically from the secret
+// key and the message digest with HMAC_DRBG, as specified by RFC 6979.
+// Additional data, such as fresh randomness, can be mixed in as described in
+// section 3.6, so a broken random source no longer leaks the key.
+package rfc6979
+
+import (
+	"crypto/hmac"
+	"hash"
+	"math/big"
+)
+
+// Generator produces the candidate nonces of RFC 6979 section 3.2 step h, in
+// order. Signers take the next one whenever a candidate is unusable, such as
+// when r or s comes out as zero.
+type Generator struct {
+	newHash func() hash.Hash
+	q       *big.Int
+	k, v    []byte
+	started bool
+}
+
+// New returns the Generator for signing digest with secret x modulo q, using
+// HMAC over newHash. extra is the additional data k' of section 3.6 and may be
+// nil for purely deterministic nonces.
+func New(newHash func() hash.Hash, q, x *big.Int, digest, extra []byte) *Generator {
+	g := &Generator{newHash: newHash, q: q}
+	size := newHash().Size()
+	g.v = make([]byte, size)
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+	g.k = make([]byte, size)
+
+	rlen := (q.BitLen() + 7) / 8
+	key := x.FillBytes(make([]byte, rlen))
+	h1 := g.bits2int(digest)
+	if h1.Cmp(q) >= 0 {
+		h1.Sub(h1, q)
+	}
+	msg := h1.FillBytes(make([]byte, rlen))
+
+	for _, b := range []byte{0x00, 0x01} {
+		g.k = g.mac(g.v, []byte{b}, key, msg, extra)
+		g.v = g.mac(g.v)
+	}
+	return g
+}
+
+// Next returns the next candidate, which always lies in [1, q-1].
+func (g *Generator) Next() *big.Int {
+	rlen := (g.q.BitLen() + 7) / 8
+	for {
+		if g.started {
+			g.k = g.mac(g.v, []byte{0x00})
+			g.v = g.mac(g.v)
+		}
+		g.started = true
+		var t []byte
+		for len(t) < rlen {
+			g.v = g.mac(g.v)
+			t = append(t, g.v...)
+		}
+		k := g.bits2int(t)
+		if k.Sign() > 0 && k.Cmp(g.q) < 0 {
+			return k
+		}
+	}
+}
+
+// Nonce returns the first candidate of New(newHash, q, x, digest, extra).
+func Nonce(newHash func() hash.Hash, q, x *big.Int, digest, extra []byte) *big.Int {
+	return New(newHash, q, x, digest, extra).Next()
+}
+
+func (g *Generator) mac(data ...[]byte) []byte {
+	m := hmac.New(g.newHash, g.k)
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+// bits2int keeps the leftmost bits of b, as many as q has.
+func (g *Generator) bits2int(b []byte) *big.Int {
+	r := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - g.q.BitLen(); excess > 0 {
+		r.Rsh(r, uint(excess))
+	}
+	return r
+}
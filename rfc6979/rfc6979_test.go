@@ -0,0 +1,70 @@
+package rfc6979
+
+import (
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %s", s)
+	}
+	return n
+}
+
+func digest(newHash func() hash.Hash, msg string) []byte {
+	h := newHash()
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// The P-256 vectors of RFC 6979 appendix A.2.5.
+func TestNonceP256(t *testing.T) {
+	q := elliptic.P256().Params().N
+	x := hexInt(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	for _, tt := range []struct {
+		newHash func() hash.Hash
+		msg     string
+		k       string
+	}{
+		{sha1.New, "sample", "882905F1227FD620FBF2ABF21244F0BA83D0DC3A9103DBBEE43A1FB858109DB4"},
+		{sha256.New, "sample", "A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60"},
+		{sha512.New, "sample", "5FA81C63109BADB88C1F367B47DA606DA28CAD69AA22C4FE6AD7DF73A7173AA5"},
+		{sha256.New, "test", "D16B6AE827F17175E040871A1C7EC3500192C4C92677336EC2537ACAEE0008E0"},
+	} {
+		k := Nonce(tt.newHash, q, x, digest(tt.newHash, tt.msg), nil)
+		if k.Cmp(hexInt(t, tt.k)) != 0 {
+			t.Errorf("%q: got %X, want %s", tt.msg, k, tt.k)
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	q := big.NewInt(0xfff1)
+	x := big.NewInt(12345)
+	h := digest(sha256.New, "sample")
+	g := New(sha256.New, q, x, h, nil)
+	seen := make(map[int64]bool)
+	for i := 0; i < 16; i++ {
+		k := g.Next()
+		if k.Sign() <= 0 || k.Cmp(q) >= 0 {
+			t.Fatalf("candidate %d out of range", k)
+		}
+		seen[k.Int64()] = true
+	}
+	if len(seen) < 15 {
+		t.Fatal("candidates repeat")
+	}
+	if Nonce(sha256.New, q, x, h, nil).Cmp(New(sha256.New, q, x, h, nil).Next()) != 0 {
+		t.Fatal("not deterministic")
+	}
+	if Nonce(sha256.New, q, x, h, []byte{1}).Cmp(Nonce(sha256.New, q, x, h, []byte{2})) == 0 {
+		t.Fatal("extra data ignored")
+	}
+}
@@ -0,0 +1,70 @@
+package secp256k1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var big58 = big.NewInt(58)
+
+// base58CheckEncode appends the first 4 bytes of the double SHA-256 of data
+// and encodes the result in Bitcoin's base58 alphabet.
+func base58CheckEncode(data []byte) string {
+	checksum := doubleSHA256(data)
+	payload := append(append([]byte{}, data...), checksum[:4]...)
+
+	n := new(big.Int).SetBytes(payload)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, big58, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// every leading zero byte is a leading '1'
+	for _, b := range payload {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode reverses base58CheckEncode, checking the checksum.
+func base58CheckDecode(s string) ([]byte, error) {
+	n := new(big.Int)
+	zeros := 0
+	for i := 0; i < len(s); i++ {
+		digit := bytes.IndexByte([]byte(base58Alphabet), s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("Invalid base58 character %q\n", s[i])
+		}
+		if digit == 0 && zeros == i {
+			zeros++
+		}
+		n.Mul(n, big58)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	payload := append(make([]byte, zeros), n.Bytes()...)
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("Base58 string too short\n")
+	}
+	data := payload[:len(payload)-4]
+	checksum := doubleSHA256(data)
+	if !bytes.Equal(checksum[:4], payload[len(payload)-4:]) {
+		return nil, fmt.Errorf("Base58 checksum mismatch\n")
+	}
+	return data, nil
+}
+
+func doubleSHA256(data []byte) [32]byte {
+	first := sha256.Sum256(data)
+	return sha256.Sum256(first[:])
+}
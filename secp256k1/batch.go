@@ -0,0 +1,98 @@
+package secp256k1
+
+import (
+	"math/big"
+	vc "volley/curve"
+)
+
+// batchToAffine sets out[i] to in[i] rescaled to Z = 1, sharing one field
+// inversion between all points. None of the points may be at infinity, and
+// out may only alias in if in holds no point twice.
+func batchToAffine(out, in []*point) {
+	if len(in) == 0 {
+		return
+	}
+	prefix := make([][4]uint64, len(in))
+	acc := fieldOne
+	for i, p := range in {
+		prefix[i] = acc
+		p256k1Mul(acc[:], acc[:], p.xyz[8:12])
+	}
+	p256k1Inverse(acc[:], acc[:])
+	var zInv, zInvSq [4]uint64
+	for i := len(in) - 1; i >= 0; i-- {
+		p := in[i]
+		p256k1Mul(zInv[:], acc[:], prefix[i][:])
+		p256k1Mul(acc[:], acc[:], p.xyz[8:12])
+		p256k1Sqr(zInvSq[:], zInv[:], 1)
+		p256k1Mul(out[i].xyz[0:4], p.xyz[0:4], zInvSq[:])
+		p256k1Mul(zInvSq[:], zInvSq[:], zInv[:])
+		p256k1Mul(out[i].xyz[4:8], p.xyz[4:8], zInvSq[:])
+		copy(out[i].xyz[8:12], fieldOne[:])
+	}
+}
+
+// isAffine reports whether p has Z = 1.
+func (p *point) isAffine() bool {
+	return p.xyz[8] == fieldOne[0] && p.xyz[9]|p.xyz[10]|p.xyz[11] == 0
+}
+
+// affineToBig returns the coordinates of a point with Z = 1.
+func (p *point) affineToBig() (x, y *big.Int) {
+	var t [4]uint64
+	buf := make([]byte, 32)
+	p256k1FromMont(t[:], p.xyz[0:4])
+	p256k1LittleToBig(buf, t[:])
+	x = new(big.Int).SetBytes(buf)
+	p256k1FromMont(t[:], p.xyz[4:8])
+	p256k1LittleToBig(buf, t[:])
+	y = new(big.Int).SetBytes(buf)
+	return
+}
+
+// BatchNormalize rescales all points of pList to Z = 1 in place with a single
+// field inversion, which makes the following Back calls cheap. The points
+// must not be in use by other goroutines.
+func (c *curve) BatchNormalize(pList []vc.FastPoint) {
+	in := make([]*point, 0, len(pList))
+	for _, fp := range pList {
+		if p := fp.(*Point); !p.zero && !p.p.isAffine() {
+			in = append(in, p.p)
+		}
+	}
+	// go through a scratch copy, pList may hold the same point twice
+	affine := make([]point, len(in))
+	out := make([]*point, len(in))
+	for i := range out {
+		out[i] = &affine[i]
+	}
+	batchToAffine(out, in)
+	for i, p := range in {
+		*p = affine[i]
+	}
+}
+
+// BatchBack is Back for a whole list with a single field inversion. The
+// points are left untouched.
+func (c *curve) BatchBack(pList []vc.FastPoint) ([]*big.Int, []*big.Int) {
+	xs := make([]*big.Int, len(pList))
+	ys := make([]*big.Int, len(pList))
+	affine := make([]point, len(pList))
+	in := make([]*point, 0, len(pList))
+	out := make([]*point, 0, len(pList))
+	for i, fp := range pList {
+		if p := fp.(*Point); !p.zero {
+			in = append(in, p.p)
+			out = append(out, &affine[i])
+		}
+	}
+	batchToAffine(out, in)
+	for i, fp := range pList {
+		if fp.(*Point).zero {
+			xs[i], ys[i] = big.NewInt(0), big.NewInt(0)
+		} else {
+			xs[i], ys[i] = affine[i].affineToBig()
+		}
+	}
+	return xs, ys
+}
@@ -0,0 +1,58 @@
+package secp256k1
+
+import "testing"
+
+func TestBatchBack(t *testing.T) {
+	InitNAFTables(9)
+	pList, _ := randomMSMInput(t, 20)
+	// mix in non-affine points, infinity and an aliased entry
+	for i := 0; i < 10; i++ {
+		p256k1Curve.FastPointAdd(pList[i], pList[i], pList[19-i])
+	}
+	pList[3] = p256k1Curve.NewPoint()
+	pList[7] = pList[5]
+
+	xs, ys := p256k1Curve.BatchBack(pList)
+	for i, p := range pList {
+		x, y := p.Back()
+		if xs[i].Cmp(x) != 0 || ys[i].Cmp(y) != 0 {
+			t.Fatalf("BatchBack differs from Back at %d", i)
+		}
+	}
+
+	p256k1Curve.BatchNormalize(pList)
+	for i, p := range pList {
+		if !p.IsZero() && !p.(*Point).p.isAffine() {
+			t.Fatalf("point %d not normalized", i)
+		}
+		x, y := p.Back()
+		if xs[i].Cmp(x) != 0 || ys[i].Cmp(y) != 0 {
+			t.Fatalf("BatchNormalize changed point %d", i)
+		}
+	}
+	xs, ys = p256k1Curve.BatchBack(nil)
+	if len(xs) != 0 || len(ys) != 0 {
+		t.Fatal("BatchBack of an empty list")
+	}
+}
+
+func BenchmarkBatchBack(b *testing.B) {
+	InitNAFTables(9)
+	pList, _ := randomMSMInput(b, 65)
+	for i := 0; i < 64; i++ {
+		p256k1Curve.FastPointAdd(pList[i], pList[i], pList[i+1])
+	}
+	pList = pList[:64]
+	b.Run("back", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range pList {
+				p.Back()
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.BatchBack(pList)
+		}
+	})
+}
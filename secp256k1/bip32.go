@@ -0,0 +1,224 @@
+package secp256k1
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedOffset is added to a child index for hardened derivation, which
+// needs the private key.
+const HardenedOffset uint32 = 0x80000000
+
+// Version bytes of mainnet extended keys, the ones behind xprv and xpub.
+var (
+	versionPrivate = [4]byte{0x04, 0x88, 0xad, 0xe4}
+	versionPublic  = [4]byte{0x04, 0x88, 0xb2, 0x1e}
+)
+
+// ErrInvalidChild is returned, with probability about 2^-127, when a child
+// index gives no valid key. BIP-32 says to go on with the next index.
+var ErrInvalidChild = errors.New("Invalid child key, use the next index\n")
+
+// ExtendedKey is a BIP-32 extended key. Private is nil for a public key.
+type ExtendedKey struct {
+	Private *PrivateKey
+	Public  PublicKey
+
+	ChainCode         [32]byte
+	Depth             byte
+	ParentFingerprint [4]byte
+	ChildNumber       uint32
+}
+
+// NewMasterKey derives the master key of a seed of 16 to 64 bytes.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("Seed must be 16 to 64 bytes\n")
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, err := NewPrivateKey(new(big.Int).SetBytes(sum[:32]))
+	if err != nil {
+		return nil, fmt.Errorf("Seed gives no valid master key\n")
+	}
+	k := &ExtendedKey{Private: key, Public: key.PublicKey}
+	copy(k.ChainCode[:], sum[32:])
+	return k, nil
+}
+
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.Private != nil
+}
+
+// Fingerprint is the first 4 bytes of the HASH160 of the compressed public
+// key.
+func (k *ExtendedKey) Fingerprint() [4]byte {
+	sum := sha256.Sum256(MarshalPublicKey(k.Public.ECDSA(), true))
+	hash := ripemd160(sum[:])
+	var fp [4]byte
+	copy(fp[:], hash[:4])
+	return fp
+}
+
+// Neuter returns the public extended key of k.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	public := *k
+	public.Private = nil
+	return &public
+}
+
+// Child derives the child with index i, hardened if i is at least
+// HardenedOffset. Public keys only have non-hardened children, found as
+// IL*G + K.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if k.Depth == 255 {
+		return nil, fmt.Errorf("Maximum depth reached\n")
+	}
+	var data []byte
+	if i >= HardenedOffset {
+		if k.Private == nil {
+			return nil, fmt.Errorf("Hardened child of a public key\n")
+		}
+		data = make([]byte, 33, 37)
+		k.Private.D.FillBytes(data[1:])
+	} else {
+		data = MarshalPublicKey(k.Public.ECDSA(), true)
+	}
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], i)
+	data = append(data, index[:]...)
+
+	mac := hmac.New(sha512.New, k.ChainCode[:])
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	N := p256k1Curve.params.N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	child := &ExtendedKey{
+		Depth:             k.Depth + 1,
+		ParentFingerprint: k.Fingerprint(),
+		ChildNumber:       i,
+	}
+	copy(child.ChainCode[:], sum[32:])
+	if k.Private != nil {
+		il.Add(il, k.Private.D)
+		il.Mod(il, N)
+		if il.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		key, err := NewPrivateKey(il)
+		if err != nil {
+			return nil, err
+		}
+		child.Private = key
+		child.Public = key.PublicKey
+		return child, nil
+	}
+
+	point := p256k1Curve.FastBaseScalar(il.Bytes())
+	parent := p256k1Curve.NewPoint()
+	parent.From(k.Public.X, k.Public.Y)
+	p256k1Curve.FastPointAdd(point, point, parent)
+	if point.IsZero() {
+		return nil, ErrInvalidChild
+	}
+	child.Public.X, child.Public.Y = point.Back()
+	return child, nil
+}
+
+// Derive follows a path such as m/44'/0'/0'/0/7 from k, which is taken to
+// be m. Hardened indexes are marked with ', h or H.
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("Derivation path %q doesn't start with m\n", path)
+	}
+	key := k
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H") {
+			offset = HardenedOffset
+			part = part[:len(part)-1]
+		}
+		i, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid index %q in derivation path\n", part)
+		}
+		key, err = key.Child(uint32(i) + offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// String returns the base58check serialization, xprv... or xpub....
+func (k *ExtendedKey) String() string {
+	data := make([]byte, 78)
+	if k.Private != nil {
+		copy(data[0:4], versionPrivate[:])
+		k.Private.D.FillBytes(data[46:78])
+	} else {
+		copy(data[0:4], versionPublic[:])
+		copy(data[45:78], MarshalPublicKey(k.Public.ECDSA(), true))
+	}
+	data[4] = k.Depth
+	copy(data[5:9], k.ParentFingerprint[:])
+	binary.BigEndian.PutUint32(data[9:13], k.ChildNumber)
+	copy(data[13:45], k.ChainCode[:])
+	return base58CheckEncode(data)
+}
+
+// ParseExtendedKey decodes the output of String.
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	data, err := base58CheckDecode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 78 {
+		return nil, fmt.Errorf("Extended key must be 78 bytes\n")
+	}
+	k := &ExtendedKey{
+		Depth:       data[4],
+		ChildNumber: binary.BigEndian.Uint32(data[9:13]),
+	}
+	copy(k.ParentFingerprint[:], data[5:9])
+	copy(k.ChainCode[:], data[13:45])
+	if k.Depth == 0 && (k.ParentFingerprint != [4]byte{} || k.ChildNumber != 0) {
+		return nil, fmt.Errorf("Master key with a parent\n")
+	}
+
+	var version [4]byte
+	copy(version[:], data[0:4])
+	switch version {
+	case versionPrivate:
+		if data[45] != 0 {
+			return nil, fmt.Errorf("Invalid private key prefix\n")
+		}
+		k.Private, err = NewPrivateKey(new(big.Int).SetBytes(data[46:]))
+		if err != nil {
+			return nil, err
+		}
+		k.Public = k.Private.PublicKey
+	case versionPublic:
+		pub, err := UnmarshalPublicKey(data[45:])
+		if err != nil {
+			return nil, err
+		}
+		k.Public = PublicKey{X: pub.X, Y: pub.Y}
+	default:
+		return nil, fmt.Errorf("Unknown extended key version %x\n", version)
+	}
+	return k, nil
+}
@@ -0,0 +1,104 @@
+package secp256k1_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"volley/secp256k1"
+)
+
+// Test vector 1 of BIP-32.
+var bip32Vectors = []struct {
+	path string
+	xpub string
+	xprv string
+}{
+	{
+		"m",
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+	},
+	{
+		"m/0H",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+	},
+	{
+		"m/0H/1",
+		"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+	},
+	{
+		"m/0H/1/2H",
+		"xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+		"xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM",
+	},
+	{
+		"m/0H/1/2H/2",
+		"xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		"xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334",
+	},
+	{
+		"m/0H/1/2H/2/1000000000",
+		"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+	},
+}
+
+func TestBIP32(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := secp256k1.NewMasterKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range bip32Vectors {
+		key, err := master.Derive(v.path)
+		if err != nil {
+			t.Fatal(v.path, err)
+		}
+		if key.String() != v.xprv {
+			t.Fatalf("%s: xprv %s", v.path, key)
+		}
+		if key.Neuter().String() != v.xpub {
+			t.Fatalf("%s: xpub %s", v.path, key.Neuter())
+		}
+
+		parsed, err := secp256k1.ParseExtendedKey(v.xprv)
+		if err != nil || parsed.String() != v.xprv {
+			t.Fatalf("%s: xprv round trip: %v", v.path, err)
+		}
+		parsed, err = secp256k1.ParseExtendedKey(v.xpub)
+		if err != nil || parsed.String() != v.xpub || parsed.IsPrivate() {
+			t.Fatalf("%s: xpub round trip: %v", v.path, err)
+		}
+	}
+
+	// public derivation gives the public keys of the private children
+	account, _ := master.Derive("m/0H/1")
+	for i := uint32(0); i < 4; i++ {
+		private, err := account.Child(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := account.Neuter().Child(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if public.String() != private.Neuter().String() {
+			t.Fatal("public derivation mismatch at", i)
+		}
+	}
+	if _, err = account.Neuter().Child(secp256k1.HardenedOffset); err == nil {
+		t.Fatal("derived a hardened child of a public key")
+	}
+
+	bad := []byte(bip32Vectors[0].xprv)
+	bad[len(bad)-1] = 'j'
+	if _, err = secp256k1.ParseExtendedKey(string(bad)); err == nil {
+		t.Fatal("accepted a key with a bad checksum")
+	}
+	for _, path := range []string{"0/1", "m/x", "m/2147483648"} {
+		if _, err = master.Derive(path); err == nil {
+			t.Fatal("accepted path", path)
+		}
+	}
+}
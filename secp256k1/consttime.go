@@ -0,0 +1,308 @@
+package secp256k1
+
+import (
+	"crypto/subtle"
+	"math/bits"
+	"sync"
+	vc "volley/curve"
+)
+
+// The constant-time path below is meant for secret scalars: keys, nonces and
+// witnesses. It walks the scalar in fixed 4-bit windows, reads the tables
+// with a full masked scan and adds with the complete formulas of Renes,
+// Costello and Batina (https://eprint.iacr.org/2015/1060, algorithms 7 and 9),
+// so neither the branches nor the memory accesses depend on the scalar.
+
+// fieldP is the field prime in little-endian limbs.
+var fieldP = [4]uint64{0xfffffffefffffc2f, 0xffffffffffffffff, 0xffffffffffffffff, 0xffffffffffffffff}
+
+// fieldB3 is 3*b = 21 in Montgomery form, set in init.
+var fieldB3 [4]uint64
+
+func init() {
+	p256k1Mul(fieldB3[:], []uint64{21, 0, 0, 0}, rr)
+}
+
+// fieldAdd sets r = a + b mod p for a, b < p.
+func fieldAdd(r, a, b *[4]uint64) {
+	var carry, borrow uint64
+	var t, s [4]uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	s[0], borrow = bits.Sub64(t[0], fieldP[0], 0)
+	s[1], borrow = bits.Sub64(t[1], fieldP[1], borrow)
+	s[2], borrow = bits.Sub64(t[2], fieldP[2], borrow)
+	s[3], borrow = bits.Sub64(t[3], fieldP[3], borrow)
+	mask := -(borrow &^ carry)
+	r[0] = t[0]&mask | s[0]&^mask
+	r[1] = t[1]&mask | s[1]&^mask
+	r[2] = t[2]&mask | s[2]&^mask
+	r[3] = t[3]&mask | s[3]&^mask
+}
+
+// fieldSub sets r = a - b mod p for a, b < p.
+func fieldSub(r, a, b *[4]uint64) {
+	var borrow, carry uint64
+	var t [4]uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	mask := -borrow
+	r[0], carry = bits.Add64(t[0], fieldP[0]&mask, 0)
+	r[1], carry = bits.Add64(t[1], fieldP[1]&mask, carry)
+	r[2], carry = bits.Add64(t[2], fieldP[2]&mask, carry)
+	r[3], _ = bits.Add64(t[3], fieldP[3]&mask, carry)
+}
+
+// fieldK0 is -p^-1 mod 2^64.
+const fieldK0 = 0xd838091dd2253531
+
+// madd returns the 128-bit a*b + c + d as hi, lo.
+func madd(a, b, c, d uint64) (hi, lo uint64) {
+	var cc uint64
+	hi, lo = bits.Mul64(a, b)
+	lo, cc = bits.Add64(lo, c, 0)
+	hi += cc
+	lo, cc = bits.Add64(lo, d, 0)
+	hi += cc
+	return
+}
+
+// fieldMul sets r = a * b / 2^256 mod p, like p256k1Mul. The assembly skips
+// reduction steps whose multiplier is zero, which is exactly what happens
+// around the point at infinity, so the constant-time code uses this
+// branch-free version instead.
+func fieldMul(r, a, b *[4]uint64) {
+	var t0, t1, t2, t3, t4, t5, c, cc, m uint64
+	for i := 0; i < 4; i++ {
+		bi := b[i]
+		c, t0 = madd(a[0], bi, t0, 0)
+		c, t1 = madd(a[1], bi, t1, c)
+		c, t2 = madd(a[2], bi, t2, c)
+		c, t3 = madd(a[3], bi, t3, c)
+		t4, cc = bits.Add64(t4, c, 0)
+		t5 = cc
+
+		m = t0 * fieldK0
+		c, _ = madd(m, fieldP[0], t0, 0)
+		c, t0 = madd(m, fieldP[1], t1, c)
+		c, t1 = madd(m, fieldP[2], t2, c)
+		c, t2 = madd(m, fieldP[3], t3, c)
+		t3, cc = bits.Add64(t4, c, 0)
+		t4 = t5 + cc
+	}
+	// t < 2p, subtract p unless that borrows
+	var s0, s1, s2, s3, borrow uint64
+	s0, borrow = bits.Sub64(t0, fieldP[0], 0)
+	s1, borrow = bits.Sub64(t1, fieldP[1], borrow)
+	s2, borrow = bits.Sub64(t2, fieldP[2], borrow)
+	s3, borrow = bits.Sub64(t3, fieldP[3], borrow)
+	_, borrow = bits.Sub64(t4, 0, borrow)
+	mask := -borrow
+	r[0] = t0&mask | s0&^mask
+	r[1] = t1&mask | s1&^mask
+	r[2] = t2&mask | s2&^mask
+	r[3] = t3&mask | s3&^mask
+}
+
+// projPoint is a point in homogeneous projective coordinates, x = X/Z and
+// y = Y/Z, in Montgomery form. The point at infinity is (0, 1, 0).
+type projPoint struct {
+	x, y, z [4]uint64
+}
+
+func (p *projPoint) setInfinity() {
+	p.x = [4]uint64{}
+	p.y = fieldOne
+	p.z = [4]uint64{}
+}
+
+// fromJacobian sets p to the Jacobian point j: (X, Y, Z) -> (XZ, Y, Z^3).
+func (p *projPoint) fromJacobian(j *point) {
+	var z2 [4]uint64
+	copy(p.x[:], j.xyz[0:4])
+	copy(p.y[:], j.xyz[4:8])
+	copy(p.z[:], j.xyz[8:12])
+	fieldMul(&p.x, &p.x, &p.z)
+	fieldMul(&z2, &p.z, &p.z)
+	fieldMul(&p.z, &z2, &p.z)
+}
+
+// toJacobian stores p in j: (X, Y, Z) -> (XZ, YZ^2, Z). It reports whether p
+// is the point at infinity, which only happens for scalars that are 0 mod N.
+func (p *projPoint) toJacobian(j *point) (zero bool) {
+	var z2, t [4]uint64
+	fieldMul(&t, &p.x, &p.z)
+	copy(j.xyz[0:4], t[:])
+	fieldMul(&z2, &p.z, &p.z)
+	fieldMul(&t, &p.y, &z2)
+	copy(j.xyz[4:8], t[:])
+	copy(j.xyz[8:12], p.z[:])
+	return p.z[0]|p.z[1]|p.z[2]|p.z[3] == 0
+}
+
+// projAdd sets r = p + q. It is complete: it also covers p == q, p == -q and
+// either input at infinity. r may alias p or q.
+func projAdd(r, p, q *projPoint) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 [4]uint64
+	fieldMul(&t0, &p.x, &q.x)
+	fieldMul(&t1, &p.y, &q.y)
+	fieldMul(&t2, &p.z, &q.z)
+	fieldAdd(&t3, &p.x, &p.y)
+	fieldAdd(&t4, &q.x, &q.y)
+	fieldMul(&t3, &t3, &t4)
+	fieldAdd(&t4, &t0, &t1)
+	fieldSub(&t3, &t3, &t4)
+	fieldAdd(&t4, &p.y, &p.z)
+	fieldAdd(&x3, &q.y, &q.z)
+	fieldMul(&t4, &t4, &x3)
+	fieldAdd(&x3, &t1, &t2)
+	fieldSub(&t4, &t4, &x3)
+	fieldAdd(&x3, &p.x, &p.z)
+	fieldAdd(&y3, &q.x, &q.z)
+	fieldMul(&x3, &x3, &y3)
+	fieldAdd(&y3, &t0, &t2)
+	fieldSub(&y3, &x3, &y3)
+	fieldAdd(&x3, &t0, &t0)
+	fieldAdd(&t0, &x3, &t0)
+	fieldMul(&t2, &fieldB3, &t2)
+	fieldAdd(&z3, &t1, &t2)
+	fieldSub(&t1, &t1, &t2)
+	fieldMul(&y3, &fieldB3, &y3)
+	fieldMul(&x3, &t4, &y3)
+	fieldMul(&t2, &t3, &t1)
+	fieldSub(&x3, &t2, &x3)
+	fieldMul(&y3, &y3, &t0)
+	fieldMul(&t1, &t1, &z3)
+	fieldAdd(&y3, &t1, &y3)
+	fieldMul(&t0, &t0, &t3)
+	fieldMul(&z3, &z3, &t4)
+	fieldAdd(&z3, &z3, &t0)
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+// projDouble sets r = 2p, infinity included. r may alias p.
+func projDouble(r, p *projPoint) {
+	var t0, t1, t2, x3, y3, z3 [4]uint64
+	fieldMul(&t0, &p.y, &p.y)
+	fieldAdd(&z3, &t0, &t0)
+	fieldAdd(&z3, &z3, &z3)
+	fieldAdd(&z3, &z3, &z3)
+	fieldMul(&t1, &p.y, &p.z)
+	fieldMul(&t2, &p.z, &p.z)
+	fieldMul(&t2, &fieldB3, &t2)
+	fieldMul(&x3, &t2, &z3)
+	fieldAdd(&y3, &t0, &t2)
+	fieldMul(&z3, &t1, &z3)
+	fieldAdd(&t1, &t2, &t2)
+	fieldAdd(&t2, &t1, &t2)
+	fieldSub(&t0, &t0, &t2)
+	fieldMul(&y3, &t0, &y3)
+	fieldAdd(&y3, &x3, &y3)
+	fieldMul(&t1, &p.x, &p.y)
+	fieldMul(&x3, &t0, &t1)
+	fieldAdd(&x3, &x3, &x3)
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+// projSelect sets r = table[d], touching every entry of the table.
+func projSelect(r *projPoint, table *[16]projPoint, d uint64) {
+	*r = projPoint{}
+	for i := range table {
+		mask := -uint64(subtle.ConstantTimeEq(int32(i), int32(d)))
+		for j := 0; j < 4; j++ {
+			r.x[j] |= table[i].x[j] & mask
+			r.y[j] |= table[i].y[j] & mask
+			r.z[j] |= table[i].z[j] & mask
+		}
+	}
+}
+
+// scalarWindow returns bits 4w to 4w+3 of k.
+func scalarWindow(k *[4]uint64, w int) uint64 {
+	return k[w/16] >> (uint(w%16) * 4) & 15
+}
+
+// secretTable fills table with 0*p, 1*p, ..., 15*p.
+func secretTable(table *[16]projPoint, p *projPoint) {
+	table[0].setInfinity()
+	table[1] = *p
+	for i := 2; i < 16; i++ {
+		projAdd(&table[i], &table[i-1], p)
+	}
+}
+
+// baseComb holds d * 16^w * G at [w][d], so k*G is a sum of one entry per
+// window without any doubling.
+var (
+	baseComb     *[64][16]projPoint
+	baseCombOnce sync.Once
+)
+
+func loadBaseComb() *[64][16]projPoint {
+	baseCombOnce.Do(func() {
+		table := new([64][16]projPoint)
+		var g projPoint
+		fromBig(g.x[:], p256k1Curve.params.Gx)
+		fromBig(g.y[:], p256k1Curve.params.Gy)
+		p256k1Mul(g.x[:], g.x[:], rr)
+		p256k1Mul(g.y[:], g.y[:], rr)
+		g.z = fieldOne
+		for w := 0; w < 64; w++ {
+			secretTable(&table[w], &g)
+			for i := 0; i < 4; i++ {
+				projDouble(&g, &g)
+			}
+		}
+		baseComb = table
+	})
+	return baseComb
+}
+
+// FastBaseScalarSecret is the constant-time counterpart of FastBaseScalar.
+func (c *curve) FastBaseScalarSecret(scalar []byte) vc.FastPoint {
+	var k [4]uint64
+	scalarToLimbs(&k, scalar)
+	table := loadBaseComb()
+	var acc, tmp projPoint
+	acc.setInfinity()
+	for w := 0; w < 64; w++ {
+		projSelect(&tmp, &table[w], scalarWindow(&k, w))
+		projAdd(&acc, &acc, &tmp)
+	}
+	r := &Point{p: new(point)}
+	r.zero = acc.toJacobian(r.p)
+	return r
+}
+
+// FastScalarMultSecret is the constant-time counterpart of FastScalarMult.
+// Only the scalar is protected, the point is assumed public.
+func (c *curve) FastScalarMultSecret(fpr, fp vc.FastPoint, scalar []byte) {
+	p := fp.(*Point)
+	pr := fpr.(*Point)
+	pr.table = nil
+	pr.lazy = nil
+	if p.zero {
+		pr.zero = true
+		return
+	}
+	var k [4]uint64
+	scalarToLimbs(&k, scalar)
+	var base projPoint
+	base.fromJacobian(p.p)
+	var table [16]projPoint
+	secretTable(&table, &base)
+	var acc, tmp projPoint
+	acc.setInfinity()
+	for w := 63; w >= 0; w-- {
+		for i := 0; i < 4; i++ {
+			projDouble(&acc, &acc)
+		}
+		projSelect(&tmp, &table, scalarWindow(&k, w))
+		projAdd(&acc, &acc, &tmp)
+	}
+	pr.zero = acc.toJacobian(pr.p)
+}
@@ -0,0 +1,226 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	mrand "math/rand"
+	"os"
+	"sort"
+	"testing"
+	"time"
+	vc "volley/curve"
+)
+
+func TestFieldMul(t *testing.T) {
+	P := p256k1Curve.Params().P
+	for i := 0; i < 1000; i++ {
+		var a, b, want, got [4]uint64
+		if i > 0 {
+			x, err := rand.Int(rand.Reader, P)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, err := rand.Int(rand.Reader, P)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromBig(a[:], x)
+			fromBig(b[:], y)
+		}
+		if i == 1 {
+			fromBig(a[:], new(big.Int).Sub(P, big.NewInt(1)))
+			b = a
+		}
+		p256k1Mul(want[:], a[:], b[:])
+		fieldMul(&got, &a, &b)
+		if got != want {
+			t.Fatalf("fieldMul(%x, %x) = %x, want %x", a, b, got, want)
+		}
+	}
+}
+
+func TestSecretScalarMult(t *testing.T) {
+	InitNAFTables(9)
+	N := p256k1Curve.Params().N
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	edge := [][]byte{{1}, {2}, {15}, {16}, new(big.Int).Sub(N, big.NewInt(1)).Bytes(),
+		new(big.Int).Add(N, big.NewInt(5)).Bytes(), max.Bytes()}
+	pList, kList := randomMSMInput(t, 100)
+	kList = append(edge, kList...)
+	for i, k := range kList {
+		p := pList[i%len(pList)]
+		want := p256k1Curve.NewPoint()
+		p256k1Curve.FastScalarMult(want, p, k)
+		got := p256k1Curve.NewPoint()
+		p256k1Curve.FastScalarMultSecret(got, p, k)
+		if !samePoint(got, want) {
+			t.Fatalf("FastScalarMultSecret mismatch for %x", k)
+		}
+
+		x, y := p256k1Curve.ScalarBaseMult(new(big.Int).Mod(new(big.Int).SetBytes(k), N).Bytes())
+		gx, gy := p256k1Curve.FastBaseScalarSecret(k).Back()
+		if gx.Cmp(x) != 0 || gy.Cmp(y) != 0 {
+			t.Fatalf("FastBaseScalarSecret mismatch for %x", k)
+		}
+	}
+
+	// multiples of N give the point at infinity
+	for _, k := range [][]byte{nil, make([]byte, 32), N.Bytes()} {
+		if !p256k1Curve.FastBaseScalarSecret(k).IsZero() {
+			t.Fatalf("%x*G is not the point at infinity", k)
+		}
+		r := p256k1Curve.NewPoint()
+		p256k1Curve.FastScalarMultSecret(r, pList[0], k)
+		if !r.IsZero() {
+			t.Fatalf("%x*P is not the point at infinity", k)
+		}
+	}
+	r := p256k1Curve.NewPoint()
+	p256k1Curve.FastScalarMultSecret(r, p256k1Curve.NewPoint(), kList[0])
+	if !r.IsZero() {
+		t.Fatal("k*O is not the point at infinity")
+	}
+}
+
+func TestCompleteFormulas(t *testing.T) {
+	pList, _ := randomMSMInput(t, 2)
+	var p, q, neg, r, inf projPoint
+	p.fromJacobian(pList[0].(*Point).p)
+	q.fromJacobian(pList[1].(*Point).p)
+	neg = p
+	fieldSub(&neg.y, &[4]uint64{}, &neg.y)
+	inf.setInfinity()
+
+	check := func(name string, r *projPoint, want vc.FastPoint) {
+		t.Helper()
+		got := &Point{p: new(point)}
+		got.zero = r.toJacobian(got.p)
+		if !samePoint(got, want) {
+			t.Fatalf("%s mismatch", name)
+		}
+	}
+	sum := p256k1Curve.NewPoint()
+	p256k1Curve.FastPointAdd(sum, pList[0], pList[1])
+	projAdd(&r, &p, &q)
+	check("P+Q", &r, sum)
+
+	twice := p256k1Curve.NewPoint()
+	p256k1Curve.FastScalarMult(twice, pList[0], []byte{2})
+	projAdd(&r, &p, &p)
+	check("P+P", &r, twice)
+	projDouble(&r, &p)
+	check("2P", &r, twice)
+	projAdd(&r, &p, &neg)
+	check("P-P", &r, p256k1Curve.NewPoint())
+	projAdd(&r, &inf, &p)
+	check("O+P", &r, pList[0])
+	projAdd(&r, &p, &inf)
+	check("P+O", &r, pList[0])
+	projDouble(&r, &inf)
+	check("2O", &r, p256k1Curve.NewPoint())
+}
+
+// welchT returns Welch's t statistic of two timing samples after dropping
+// everything above the given percentile of the pooled measurements, which
+// mostly removes interrupts and scheduler noise.
+func welchT(a, b []float64, percentile float64) float64 {
+	pooled := append(append([]float64{}, a...), b...)
+	sort.Float64s(pooled)
+	cut := pooled[int(float64(len(pooled)-1)*percentile)]
+	stats := func(s []float64) (mean, variance, n float64) {
+		for _, v := range s {
+			if v <= cut {
+				mean += v
+				n++
+			}
+		}
+		mean /= n
+		for _, v := range s {
+			if v <= cut {
+				variance += (v - mean) * (v - mean)
+			}
+		}
+		return mean, variance / (n - 1), n
+	}
+	ma, va, na := stats(a)
+	mb, vb, nb := stats(b)
+	return (ma - mb) / math.Sqrt(va/na+vb/nb)
+}
+
+// leakage measures f on a fixed, very sparse scalar against random scalars in
+// random order, in the spirit of dudect, and returns the t statistic.
+func leakage(t *testing.T, samples int, f func(k []byte)) float64 {
+	_, random := randomMSMInput(t, samples)
+	fixed := make([]byte, 32)
+	fixed[31] = 1
+	var fixedTimes, randomTimes []float64
+	for i := 0; i < 2*samples; i++ {
+		k := random[i/2]
+		isFixed := mrand.Intn(2) == 0
+		if isFixed {
+			k = fixed
+		}
+		start := time.Now()
+		f(k)
+		elapsed := float64(time.Since(start))
+		if isFixed {
+			fixedTimes = append(fixedTimes, elapsed)
+		} else {
+			randomTimes = append(randomTimes, elapsed)
+		}
+	}
+	return welchT(fixedTimes, randomTimes, 0.9)
+}
+
+// TestSecretScalarMultTiming is statistical and needs a quiet machine, so it
+// only runs with VOLLEY_TIMING_TEST=1 set.
+func TestSecretScalarMultTiming(t *testing.T) {
+	if os.Getenv("VOLLEY_TIMING_TEST") != "1" {
+		t.Skip("set VOLLEY_TIMING_TEST=1 to run the timing test")
+	}
+	InitNAFTables(9)
+	samples := 2000
+	p := p256k1Curve.FastBaseScalar([]byte{7})
+	r := p256k1Curve.NewPoint()
+
+	// the variable-time path is there to show the test can see a leak at all
+	t.Logf("FastScalarMult t = %.1f", leakage(t, samples, func(k []byte) {
+		p256k1Curve.FastScalarMult(r, p, k)
+	}))
+	// dudect treats |t| > 4.5 as a leak; this machine is shared, so allow for
+	// some noise on top
+	const threshold = 10
+	if tv := leakage(t, samples, func(k []byte) { p256k1Curve.FastScalarMultSecret(r, p, k) }); math.Abs(tv) > threshold {
+		t.Errorf("FastScalarMultSecret timing depends on the scalar: t = %.1f", tv)
+	}
+	if tv := leakage(t, samples, func(k []byte) { p256k1Curve.FastBaseScalarSecret(k) }); math.Abs(tv) > threshold {
+		t.Errorf("FastBaseScalarSecret timing depends on the scalar: t = %.1f", tv)
+	}
+}
+
+func BenchmarkSecretScalarMult(b *testing.B) {
+	InitNAFTables(9)
+	pList, kList := randomMSMInput(b, 1)
+	r := p256k1Curve.NewPoint()
+	b.Run("base/fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.FastBaseScalar(kList[0])
+		}
+	})
+	b.Run("base/secret", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.FastBaseScalarSecret(kList[0])
+		}
+	})
+	b.Run("point/fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.FastScalarMult(r, pList[0], kList[0])
+		}
+	})
+	b.Run("point/secret", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.FastScalarMultSecret(r, pList[0], kList[0])
+		}
+	})
+}
@@ -2,7 +2,6 @@ package secp256k1
 
 import (
 	"crypto/elliptic"
-	"fmt"
 	"math/big"
 	"sync"
 )
@@ -11,11 +10,9 @@ var (
 	p256k1Curve *curve = &curve{
 		params: new(elliptic.CurveParams),
 	}
-	rr                   = []uint64{0x000007a2000e90a1, 0x1, 0, 0}
-	ro                   = []uint64{0x896cf21467d7d140, 0x741496c20e7cf878, 0xe697f5e45bcd07c6, 0x9d671cd581c69bc5}
-	a1, a2, b1, b2, beta *big.Int
-	betaField            = []uint64{0x58a4361c8e81894e, 0x3fde1631c4b80af, 0xf8e98978d02e3905, 0x7a4a36aebcbb3d53}
-	closedChannel        chan int
+	rr        = []uint64{0x000007a2000e90a1, 0x1, 0, 0}
+	ro        = []uint64{0x896cf21467d7d140, 0x741496c20e7cf878, 0xe697f5e45bcd07c6, 0x9d671cd581c69bc5}
+	betaField = []uint64{0x58a4361c8e81894e, 0x3fde1631c4b80af, 0xf8e98978d02e3905, 0x7a4a36aebcbb3d53}
 )
 
 type curve struct {
@@ -24,8 +21,8 @@ type curve struct {
 
 type VSCurve interface {
 	elliptic.Curve
-	ComputePrecomputesForPoint(x, y *big.Int) interface{}
-	ScalarMultByPrecomputes(scalar []byte, precomputes interface{}) (x, y *big.Int)
+	ComputePrecomputesForPoint(x, y *big.Int) *Precomputed
+	ScalarMultByPrecomputes(scalar []byte, precomputes *Precomputed) (x, y *big.Int)
 }
 
 type point struct {
@@ -44,14 +41,6 @@ func initP256K1Curve() {
 	p256k1Curve.params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
 	p256k1Curve.params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
 	p256k1Curve.params.BitSize = 256
-
-	a1, _ = new(big.Int).SetString("3086D221A7D46BCDE86C90E49284EB15", 16)
-	b1, _ = new(big.Int).SetString("-E4437ED6010E88286F547FA90ABFE4C3", 16)
-	a2, _ = new(big.Int).SetString("114CA50F7A8E2F3F657C1108D9D44CFD8", 16)
-	b2, _ = new(big.Int).SetString("3086D221A7D46BCDE86C90E49284EB15", 16)
-	beta, _ = new(big.Int).SetString("7AE96A2B657C07106E64479EAC3434E99CF0497512F58995C1396C28719501EE", 16)
-	closedChannel = make(chan int)
-	close(closedChannel)
 }
 
 func (c *curve) Params() *elliptic.CurveParams {
@@ -292,30 +281,6 @@ func init() {
 	//init37WindowsTables()
 }
 
-var p256k1BaseMul func(*point, []uint64)
-
-func InitNAFTables(w int) {
-	p256k1NAF6Tables = nil
-	p256k1NAF7Tables = nil
-	p256k1NAF8Tables = nil
-	p256k1NAF9Tables = nil
-	if w == 6 {
-		initNAF6Tables()
-		p256k1BaseMul = p256k1BaseMulNAF6
-	} else if w == 7 {
-		initNAF7Tables()
-		p256k1BaseMul = p256k1BaseMulNAF7
-	} else if w == 8 {
-		initNAF8Tables()
-		p256k1BaseMul = p256k1BaseMulNAF8
-	} else if w == 9 {
-		initNAF9Tables()
-		p256k1BaseMul = p256k1BaseMulNAF9
-	} else {
-		panic(fmt.Sprintf("Unsupported NAF number %d", w))
-	}
-}
-
 func (p *point) p256StorePoint(r *[17 * 4 * 3]uint64, index int) {
 	copy(r[index*12:], p.xyz[:])
 }
@@ -590,28 +555,10 @@ func (p *point) p256k1ScalarMult(scalar []uint64) {
 	//p256k1MovCond(p.xyz[0:12], t1.xyz[0:12], t0.xyz[0:12], zero)
 }
 
-func (c *curve) ScalarMultByPrecomputes(scalar []byte, precomputes interface{}) (x, y *big.Int) {
-	scalarReversed := make([]uint64, 4)
-	sm2CurveGetScalar(scalarReversed, scalar)
-
+func (c *curve) ScalarMultByPrecomputes(scalar []byte, precomputes *Precomputed) (x, y *big.Int) {
 	var r point
-	if tables, ok := precomputes.(*[43][33 * 8]uint64); ok {
-		r.p256k1ScalarMultByPrecomputesNAF6(scalarReversed, tables)
-		return r.p256k1PointToAffine()
-	}
-	if tables, ok := precomputes.(*[37][65 * 8]uint64); ok {
-		r.p256k1ScalarMultByPrecomputesNAF7(scalarReversed, tables)
-		return r.p256k1PointToAffine()
-	}
-	if tables, ok := precomputes.(*[33][129 * 8]uint64); ok {
-		r.p256k1ScalarMultByPrecomputesNAF8(scalarReversed, tables)
-		return r.p256k1PointToAffine()
-	}
-	if tables, ok := precomputes.(*[29][257 * 8]uint64); ok {
-		r.p256k1ScalarMultByPrecomputesNAF9(scalarReversed, tables)
-		return r.p256k1PointToAffine()
-	}
-	panic("Unsupported precomputes")
+	precomputes.mult(&r, scalar)
+	return r.p256k1PointToAffine()
 }
 
 func (p *point) p256k1ScalarMultByPrecomputesNAF7(scalar []uint64, tables *[37][65 * 8]uint64) {
@@ -794,6 +741,6 @@ func (p *point) p256k1ScalarMultByPrecomputesNAF9(scalar []uint64, tables *[29][
 	}
 }
 
-func ComputePrecomputesForPoint(x, y *big.Int) interface{} {
+func ComputePrecomputesForPoint(x, y *big.Int) *Precomputed {
 	return p256k1Curve.ComputePrecomputesForPoint(x, y)
 }
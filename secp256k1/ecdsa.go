@@ -1,17 +1,13 @@
 package secp256k1
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/ecdsa"
-	"crypto/sha512"
+	"crypto/sha256"
+	"fmt"
 	"hash"
 	"io"
 	"math/big"
-)
-
-const (
-	aesIV = "IV for ECDSA CTR"
+	"volley/rfc6979"
 )
 
 // hashToInt converts a hash value to an integer. Per FIPS 186-4, Section 6.4,
@@ -32,7 +28,24 @@ func hashToInt(hash []byte) *big.Int {
 	return ret
 }
 
-func VerifyECDSA(public *ecdsa.PublicKey, msg, sig []byte, h hash.Hash, precomputes interface{}) bool {
+// halfOrder is N/2, the largest s a low-S signature may have.
+var halfOrder, _ = new(big.Int).SetString("7FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF5D576E7357A4501DDFE92F46681B20A0", 16)
+
+// IsLowS reports whether s is at most N/2, as BIP 146 and Ethereum require.
+func IsLowS(s *big.Int) bool {
+	return s.Cmp(halfOrder) <= 0
+}
+
+// NormalizeS returns s, or N - s if s is above N/2. Both give a valid
+// signature for the same r.
+func NormalizeS(s *big.Int) *big.Int {
+	if IsLowS(s) {
+		return s
+	}
+	return new(big.Int).Sub(p256k1Curve.params.N, s)
+}
+
+func VerifyECDSA(public *ecdsa.PublicKey, msg, sig []byte, h hash.Hash, precomputes *Precomputed) bool {
 	digest := h.Sum(msg)
 	r, s, err := UnmarshalSig(sig)
 	if err != nil {
@@ -41,7 +54,7 @@ func VerifyECDSA(public *ecdsa.PublicKey, msg, sig []byte, h hash.Hash, precompu
 	return VerifyHash(public, digest, r, s, precomputes)
 }
 
-func VerifyHash(public *ecdsa.PublicKey, digest []byte, r, s *big.Int, precomputes interface{}) bool {
+func VerifyHash(public *ecdsa.PublicKey, digest []byte, r, s *big.Int, precomputes *Precomputed) bool {
 	params := p256k1Curve.params
 	N := params.N
 
@@ -75,6 +88,12 @@ func VerifyHash(public *ecdsa.PublicKey, digest []byte, r, s *big.Int, precomput
 	return x.Cmp(r) == 0
 }
 
+// VerifyHashLowS is VerifyHash that also rejects signatures with s above N/2,
+// which anyone can derive from a valid one.
+func VerifyHashLowS(public *ecdsa.PublicKey, digest []byte, r, s *big.Int, precomputes *Precomputed) bool {
+	return IsLowS(s) && VerifyHash(public, digest, r, s, precomputes)
+}
+
 func SignECDSA(rand io.Reader, pri *ecdsa.PrivateKey, msg []byte, h hash.Hash) ([]byte, error) {
 	digest := h.Sum(msg)
 	r, s, err := SignHash(rand, pri, digest)
@@ -84,103 +103,55 @@ func SignECDSA(rand io.Reader, pri *ecdsa.PrivateKey, msg []byte, h hash.Hash) (
 	return MarshalSig(r, s), nil
 }
 
+// SignHash signs digest with a nonce derived from the key and the digest as
+// in RFC 6979, using HMAC-SHA256. Unless rand is nil, 32 bytes read from it
+// are mixed in as additional data, so signatures are randomized but stay safe
+// when rand is broken. With a nil rand signatures are deterministic. s is
+// always normalized to the lower half of N.
 func SignHash(rand io.Reader, pri *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error) {
-	// Randomly read one byte at ~50% rate
-	select {
-	case <-closedChannel:
-	case <-closedChannel:
-		var buf [1]byte
-		rand.Read(buf[:])
-	}
-
-	// Get 256 bits of entropy from rand.
-	entropy := make([]byte, 32)
-	_, err := io.ReadFull(rand, entropy)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Initialize an SHA-512 hash context; digest...
-	md := sha512.New()
-	md.Write(pri.D.Bytes()) // the private key,
-	md.Write(entropy)       // the entropy,
-	md.Write(digest)        // and the input hash;
-	key := md.Sum(nil)[:32] // and compute ChopMD-256(SHA-512),
+	r, s, _, err := signHash(rand, pri, digest)
+	return r, s, err
+}
 
-	// Create an AES-CTR instance to use as a CSPRNG.
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, nil, err
+// signHash is SignHash that also returns the recovery id of the signature.
+func signHash(rand io.Reader, pri *ecdsa.PrivateKey, digest []byte) (r, s *big.Int, v byte, err error) {
+	N := p256k1Curve.params.N
+	if pri.D.Sign() <= 0 || pri.D.Cmp(N) >= 0 {
+		return nil, nil, 0, fmt.Errorf("Private key out of range\n")
 	}
-
-	// Create a CSPRNG that xors a stream of zeros with
-	// the output of the AES-CTR instance.
-	csprng := cipher.StreamReader{
-		R: zeroReader,
-		S: cipher.NewCTR(block, []byte(aesIV)),
+	var extra []byte
+	if rand != nil {
+		extra = make([]byte, 32)
+		if _, err = io.ReadFull(rand, extra); err != nil {
+			return nil, nil, 0, err
+		}
 	}
 
-	N := p256k1Curve.params.N
-	var k, kInv, r, s *big.Int
+	e := hashToInt(digest)
+	nonces := rfc6979.New(sha256.New, N, pri.D, digest, extra)
 	for {
-		for {
-			k, err = randFieldElement(csprng)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			kInv = p256k1Curve.Inverse(k)
-
-			r, _ = p256k1Curve.ScalarBaseMult(k.Bytes())
-			r.Mod(r, N)
-			if r.Sign() != 0 {
-				break
-			}
+		k := nonces.Next()
+		rx, ry := p256k1Curve.FastBaseScalarSecret(k.Bytes()).Back()
+		v = byte(ry.Bit(0))
+		if rx.Cmp(N) >= 0 {
+			v |= 2
+		}
+		r = rx.Mod(rx, N)
+		if r.Sign() == 0 {
+			continue
 		}
-
-		e := hashToInt(digest)
 		s = new(big.Int).Mul(pri.D, r)
 		s.Add(s, e)
-		s.Mul(s, kInv)
-		s.Mod(s, N) // N != 0
-		if s.Sign() != 0 {
-			break
+		s.Mul(s, p256k1Curve.Inverse(k))
+		s.Mod(s, N)
+		if s.Sign() == 0 {
+			continue
 		}
+		// N - s belongs to -R, whose y has the other parity
+		if !IsLowS(s) {
+			s.Sub(N, s)
+			v ^= 1
+		}
+		return r, s, v, nil
 	}
-	return r, s, nil
-}
-
-type zr struct {
-	io.Reader
-}
-
-// Read replaces the contents of dst with zeros.
-func (z *zr) Read(dst []byte) (n int, err error) {
-	for i := range dst {
-		dst[i] = 0
-	}
-	return len(dst), nil
-}
-
-var zeroReader = &zr{}
-
-// randFieldElement returns a random element of the order of the given
-// curve using the procedure given in FIPS 186-4, Appendix B.5.1.
-var one = big.NewInt(1)
-
-func randFieldElement(rand io.Reader) (k *big.Int, err error) {
-	params := p256k1Curve.params
-	// Note that for P-521 this will actually be 63 bits more than the order, as
-	// division rounds down, but the extra bit is inconsequential.
-	b := make([]byte, params.BitSize/8+8) // TODO: use params.N.BitLen()
-	_, err = io.ReadFull(rand, b)
-	if err != nil {
-		return
-	}
-
-	k = new(big.Int).SetBytes(b)
-	n := new(big.Int).Sub(params.N, one)
-	k.Mod(k, n)
-	k.Add(k, one)
-	return
 }
@@ -0,0 +1,135 @@
+// Package eth follows the Ethereum conventions for secp256k1 keys and
+// signatures: Keccak-256 addresses with EIP-55 checksums and the 65-byte
+// r || s || v signatures that the ecrecover precompile accepts.
+package eth
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"volley/secp256k1"
+)
+
+// AddressLength is the length of an address in bytes.
+const AddressLength = 20
+
+// Address is the last 20 bytes of the Keccak-256 digest of a public key.
+type Address [AddressLength]byte
+
+// PubkeyToAddress returns the address of pub.
+func PubkeyToAddress(pub *ecdsa.PublicKey) Address {
+	var buf [64]byte
+	pub.X.FillBytes(buf[:32])
+	pub.Y.FillBytes(buf[32:])
+	var a Address
+	copy(a[:], Keccak256(buf[:])[12:])
+	return a
+}
+
+// Hex returns the EIP-55 mixed-case encoding of a, with the 0x prefix.
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	digest := Keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		// upper case letters whose nibble of the digest of the lower case
+		// form is at least 8
+		nibble := digest[i/2] >> 4
+		if i%2 == 1 {
+			nibble = digest[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// ParseAddress decodes a hex address with or without the 0x prefix. Mixed case
+// input must carry a valid EIP-55 checksum.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	raw := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(raw) != 2*AddressLength {
+		return a, fmt.Errorf("Invalid address length %d\n", len(raw))
+	}
+	if _, err := hex.Decode(a[:], []byte(raw)); err != nil {
+		return a, err
+	}
+	if raw != strings.ToLower(raw) && raw != strings.ToUpper(raw) && a.Hex()[2:] != raw {
+		return a, fmt.Errorf("Invalid address checksum\n")
+	}
+	return a, nil
+}
+
+// TextHash returns the EIP-191 digest that personal_sign signs for msg.
+func TextHash(msg []byte) []byte {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))
+	return Keccak256([]byte(prefix), msg)
+}
+
+// Sign signs a 32-byte digest and returns r || s || v with v 27 or 28. rand is
+// mixed into the RFC 6979 nonce as for secp256k1.SignHash and may be nil.
+func Sign(rand io.Reader, pri *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("Digest must be 32 bytes\n")
+	}
+	r, s, v, err := secp256k1.SignRecoverable(rand, pri, digest)
+	if err != nil {
+		return nil, err
+	}
+	if v > 1 {
+		// R.x above N, which happens with probability about 2^-128
+		return nil, fmt.Errorf("Recovery id %d has no Ethereum encoding\n", v)
+	}
+	return secp256k1.MarshalRecoverableSig(r, s, 27+v), nil
+}
+
+// Ecrecover returns the public key that produced sig over digest. v may be 0,
+// 1, 27 or 28, and s must be in the lower half of N as Ethereum requires.
+func Ecrecover(digest, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("Digest must be 32 bytes\n")
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("Signature must be 65 bytes\n")
+	}
+	buf := make([]byte, 65)
+	copy(buf, sig)
+	if buf[64] == 27 || buf[64] == 28 {
+		buf[64] -= 27
+	}
+	if buf[64] > 1 {
+		return nil, fmt.Errorf("Invalid signature v %d\n", sig[64])
+	}
+	_, s, _, err := secp256k1.UnmarshalRecoverableSig(buf)
+	if err != nil {
+		return nil, err
+	}
+	if !secp256k1.IsLowS(s) {
+		return nil, fmt.Errorf("Signature s is not low\n")
+	}
+	return secp256k1.RecoverPublicKey(digest, buf)
+}
+
+// RecoverAddress returns the address of the signer of digest.
+func RecoverAddress(digest, sig []byte) (Address, error) {
+	pub, err := Ecrecover(digest, sig)
+	if err != nil {
+		return Address{}, err
+	}
+	return PubkeyToAddress(pub), nil
+}
+
+// VerifySender reports whether sig over digest was made by the key of addr.
+func VerifySender(addr Address, digest, sig []byte) bool {
+	signer, err := RecoverAddress(digest, sig)
+	return err == nil && signer == addr
+}
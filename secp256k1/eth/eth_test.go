@@ -0,0 +1,130 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"volley/secp256k1"
+)
+
+func TestKeccak256(t *testing.T) {
+	long := make([]byte, 1000)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	// ShortMsgKAT_256 Len = 2040 from the Keccak submission, longer than
+	// one 136 byte block
+	kat, _ := hex.DecodeString("" +
+		"3a3a819c48efde2ad914fbf00e18ab6bc4f14513ab27d0c178a188b61431e7f5" +
+		"623cb66b23346775d386b50e982c493adbbfc54b9a3cd383382336a1a0b2150a" +
+		"15358f336d03ae18f666c7573d55c4fd181c29e6ccfde63ea35f0adf5885cfc0" +
+		"a3d84a2b2e4dd24496db789e663170cef74798aa1bbcd4574ea0bba40489d764" +
+		"b2f83aadc66b148b4a0cd95246c127d5871c4f11418690a5ddf01246a0c80a43" +
+		"c70088b6183639dcfda4125bd113a8f49ee23ed306faac576c3fb0c1e256671d" +
+		"817fc2534a52f5b439f72e424de376f4c565cca82307dd9ef76da5b7c4eb7e08" +
+		"5172e328807c02d011ffbf33785378d79dc266f6a5be6bb0e4a92eceebaeb1")
+	for _, tt := range []struct {
+		in   []byte
+		want string
+	}{
+		{nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{[]byte("abc"), "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{kat, "348fb774adc970a16b1105669442625e6adaa8257a89effdb5a802f161b862ea"},
+	} {
+		if got := hex.EncodeToString(Keccak256(tt.in)); got != tt.want {
+			t.Errorf("Keccak256(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	// writes of any size give the same digest
+	h := NewKeccak256()
+	for i := 0; i < len(long); i += 7 {
+		end := i + 7
+		if end > len(long) {
+			end = len(long)
+		}
+		h.Write(long[i:end])
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hex.EncodeToString(Keccak256(long)) {
+		t.Fatal("split writes differ")
+	}
+}
+
+func testKey(d int64) *ecdsa.PrivateKey {
+	curve := secp256k1.Curve()
+	pri := &ecdsa.PrivateKey{D: big.NewInt(d)}
+	pri.Curve = curve
+	pri.X, pri.Y = curve.ScalarBaseMult(pri.D.Bytes())
+	return pri
+}
+
+func TestAddress(t *testing.T) {
+	for d, want := range map[int64]string{
+		1: "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf",
+		2: "0x2B5AD5c4795c026514f8317c7a215E218DcCD6cF",
+	} {
+		if got := PubkeyToAddress(&testKey(d).PublicKey).Hex(); got != want {
+			t.Errorf("key %d: got %s, want %s", d, got, want)
+		}
+	}
+
+	// EIP-55 examples
+	for _, s := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		a, err := ParseAddress(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Hex() != s {
+			t.Errorf("Hex() = %s, want %s", a.Hex(), s)
+		}
+		bad := []byte(s)
+		bad[3] ^= 0x20
+		if _, err = ParseAddress(string(bad)); err == nil {
+			t.Errorf("%s: accepted a wrong checksum", bad)
+		}
+	}
+}
+
+func TestTextHash(t *testing.T) {
+	want := "d9eba16ed0ecae432b71fe008c98cc872bb4cc214d3220a36f365326cf807d68"
+	if got := hex.EncodeToString(TextHash([]byte("hello world"))); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestSignAndRecover(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		pri, err := ecdsa.GenerateKey(secp256k1.Curve(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := PubkeyToAddress(&pri.PublicKey)
+		digest := TextHash([]byte("tumbler payment"))
+		sig, err := Sign(rand.Reader, pri, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig[64] != 27 && sig[64] != 28 {
+			t.Fatalf("v = %d", sig[64])
+		}
+		if !VerifySender(addr, digest, sig) {
+			t.Fatal("sender not verified")
+		}
+		// v without the 27 offset is accepted too
+		sig[64] -= 27
+		if !VerifySender(addr, digest, sig) {
+			t.Fatal("sender not verified with v = 0, 1")
+		}
+		digest[0] ^= 1
+		if VerifySender(addr, digest, sig) {
+			t.Fatal("verified another digest")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package eth
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Keccak-256 as used by Ethereum, which predates the SHA-3 standard and pads
+// with 0x01 instead of 0x06.
+const (
+	keccakRate = 136
+	keccakSize = 32
+)
+
+var keccakRC = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotc and keccakPiln drive the combined rho and pi steps.
+var (
+	keccakRotc = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakPiln = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		// rho and pi
+		t := a[1]
+		for i := 0; i < 24; i++ {
+			j := keccakPiln[i]
+			t, a[j] = a[j], bits.RotateLeft64(t, keccakRotc[i])
+		}
+		// chi
+		for y := 0; y < 25; y += 5 {
+			copy(c[:], a[y:y+5])
+			for x := 0; x < 5; x++ {
+				a[y+x] = c[x] ^ (^c[(x+1)%5] & c[(x+2)%5])
+			}
+		}
+		// iota
+		a[0] ^= keccakRC[round]
+	}
+}
+
+type keccak struct {
+	a   [25]uint64
+	buf [keccakRate]byte
+	n   int
+}
+
+// NewKeccak256 returns a new hash.Hash computing the legacy Keccak-256.
+func NewKeccak256() hash.Hash {
+	return new(keccak)
+}
+
+// Keccak256 returns the legacy Keccak-256 digest of the concatenated data.
+func Keccak256(data ...[]byte) []byte {
+	d := new(keccak)
+	for _, b := range data {
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
+
+func (d *keccak) Size() int { return keccakSize }
+
+func (d *keccak) BlockSize() int { return keccakRate }
+
+func (d *keccak) Reset() {
+	*d = keccak{}
+}
+
+func (d *keccak) absorb() {
+	for i := 0; i < keccakRate/8; i++ {
+		d.a[i] ^= binary.LittleEndian.Uint64(d.buf[8*i:])
+	}
+	keccakF1600(&d.a)
+	d.n = 0
+}
+
+func (d *keccak) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := copy(d.buf[d.n:], p)
+		d.n += n
+		p = p[n:]
+		if d.n == keccakRate {
+			d.absorb()
+		}
+	}
+	return written, nil
+}
+
+func (d *keccak) Sum(in []byte) []byte {
+	// pad a copy so the caller can keep writing
+	d0 := *d
+	for i := d0.n; i < keccakRate; i++ {
+		d0.buf[i] = 0
+	}
+	d0.buf[d0.n] ^= 0x01
+	d0.buf[keccakRate-1] ^= 0x80
+	d0.absorb()
+	var out [keccakSize]byte
+	for i := 0; i < keccakSize/8; i++ {
+		binary.LittleEndian.PutUint64(out[8*i:], d0.a[i])
+	}
+	return append(in, out[:]...)
+}
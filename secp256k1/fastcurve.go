@@ -2,7 +2,9 @@ package secp256k1
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/big"
+	"sync"
 	vc "volley/curve"
 )
 
@@ -77,12 +79,42 @@ type Point struct {
 	p     *point
 	zero  bool
 	table *[8]point
+	lazy  *lazyTable
+}
+
+// lazyTable holds an exported table that is decoded on first use. The bytes
+// usually come from a memory-mapped precomputes file, so pages are only
+// faulted in for the points a computation actually touches.
+type lazyTable struct {
+	once   sync.Once
+	data   []byte
+	affine bool
+	table  *[8]point
+}
+
+func (lt *lazyTable) load() *[8]point {
+	lt.once.Do(func() {
+		lt.table = new([8]point)
+		decodeTable(lt.table, lt.data, lt.affine)
+		lt.data = nil
+	})
+	return lt.table
+}
+
+func (pt *Point) precomputed() *[8]point {
+	if pt.table == nil && pt.lazy != nil {
+		return pt.lazy.load()
+	}
+	return pt.table
 }
 
 func (pt *Point) Back() (*big.Int, *big.Int) {
 	if pt.zero {
 		return big.NewInt(0), big.NewInt(0)
 	}
+	if pt.p.isAffine() {
+		return pt.p.affineToBig()
+	}
 	return pt.p.p256k1PointToAffine()
 }
 
@@ -103,6 +135,7 @@ func (pt *Point) CopyFrom(p2 vc.FastPoint) {
 	copy(pt.p.xyz[:], p2.(*Point).p.xyz[:])
 	pt.zero = p2.(*Point).zero
 	pt.table = p2.(*Point).table
+	pt.lazy = p2.(*Point).lazy
 }
 
 func (pt *Point) IsZero() bool {
@@ -112,6 +145,101 @@ func (pt *Point) IsZero() bool {
 func (pt *Point) Neg() {
 	p256k1Neg(pt.p.xyz[4:8])
 	pt.table = nil
+	pt.lazy = nil
+}
+
+// Equal compares the points without converting them to affine coordinates.
+func (pt *Point) Equal(p2 vc.FastPoint) bool {
+	q := p2.(*Point)
+	if pt.zero || q.zero {
+		return pt.zero == q.zero
+	}
+	var z1, z2, a, b [4]uint64
+	p256k1Sqr(z1[:], pt.p.xyz[8:12], 1)
+	p256k1Sqr(z2[:], q.p.xyz[8:12], 1)
+	p256k1Mul(a[:], pt.p.xyz[0:4], z2[:])
+	p256k1Mul(b[:], q.p.xyz[0:4], z1[:])
+	if a != b {
+		return false
+	}
+	p256k1Mul(z1[:], z1[:], pt.p.xyz[8:12])
+	p256k1Mul(z2[:], z2[:], q.p.xyz[8:12])
+	p256k1Mul(a[:], pt.p.xyz[4:8], z2[:])
+	p256k1Mul(b[:], q.p.xyz[4:8], z1[:])
+	return a == b
+}
+
+func (pt *Point) Double() {
+	if !pt.zero {
+		p256k1PointDoubleAsm(pt.p.xyz[:], pt.p.xyz[:])
+	}
+	pt.table = nil
+	pt.lazy = nil
+}
+
+// Sub sets pt = pt - p2.
+func (pt *Point) Sub(p2 vc.FastPoint) {
+	neg := &Point{p: new(point)}
+	neg.CopyFrom(p2)
+	neg.Neg()
+	p256k1Curve.FastPointAdd(pt, pt, neg)
+}
+
+func (pt *Point) SetZero() {
+	for i := range pt.p.xyz {
+		pt.p.xyz[i] = 0
+	}
+	pt.zero = true
+	pt.table = nil
+	pt.lazy = nil
+}
+
+// MarshalCompressed returns the 33-byte SEC1 compressed encoding of pt, or
+// the single byte 0x00 for the point at infinity.
+func (pt *Point) MarshalCompressed() []byte {
+	if pt.zero {
+		return []byte{0}
+	}
+	x, y := pt.Back()
+	data := make([]byte, 33)
+	data[0] = 0x02 | byte(y.Bit(0))
+	x.FillBytes(data[1:])
+	return data
+}
+
+// UnmarshalCompressed decodes the output of MarshalCompressed, checking that
+// the point is on the curve.
+func (pt *Point) UnmarshalCompressed(data []byte) error {
+	if len(data) == 1 && data[0] == 0 {
+		pt.SetZero()
+		return nil
+	}
+	if len(data) != 33 {
+		return fmt.Errorf("Invalid compressed point length %d\n", len(data))
+	}
+	if data[0] != 0x02 && data[0] != 0x03 {
+		return fmt.Errorf("Invalid compressed point prefix 0x%02x\n", data[0])
+	}
+	P := p256k1Curve.params.P
+	x := new(big.Int).SetBytes(data[1:33])
+	if x.Cmp(P) >= 0 {
+		return fmt.Errorf("Compressed point x not below P\n")
+	}
+	// y^2 = x^3 + 7
+	y := new(big.Int).Mul(x, x)
+	y.Mul(y, x)
+	y.Add(y, p256k1Curve.params.B)
+	y.Mod(y, P)
+	if y.ModSqrt(y, P) == nil {
+		return fmt.Errorf("Compressed point not on curve\n")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(P, y)
+	}
+	pt.From(x, y)
+	pt.table = nil
+	pt.lazy = nil
+	return nil
 }
 
 func (pt *Point) GenTable(affine bool) {
@@ -139,11 +267,25 @@ func (pt *Point) GenTable(affine bool) {
 		}
 	}
 	pt.table = &table
+	pt.lazy = nil
 }
 
 func (pt *Point) ExportTable(affine bool) []byte {
 	if pt.table == nil {
-		pt.GenTable(affine)
+		if pt.lazy != nil {
+			pt.table = pt.lazy.load()
+		} else {
+			pt.GenTable(affine)
+		}
+	}
+	if affine {
+		// a table generated or imported in Jacobian form has to be redone
+		for i := range pt.table {
+			if !pt.table[i].isAffine() {
+				pt.GenTable(true)
+				break
+			}
+		}
 	}
 	var exportBytes []byte
 	if affine {
@@ -168,20 +310,36 @@ func (pt *Point) ImportTable(precomputes []byte, affine bool) {
 	if pt.table == nil {
 		pt.table = new([8]point)
 	}
+	decodeTable(pt.table, precomputes, affine)
+	pt.lazy = nil
+}
+
+// MapTable is the lazy counterpart of ImportTable: it keeps a reference to
+// precomputes and decodes the table the first time it is needed. The slice
+// must stay valid and unmodified for the lifetime of the point.
+func (pt *Point) MapTable(precomputes []byte, affine bool) {
+	pt.table = nil
+	pt.lazy = &lazyTable{
+		data:   precomputes,
+		affine: affine,
+	}
+}
+
+func decodeTable(table *[8]point, precomputes []byte, affine bool) {
 	if affine {
 		for i := 0; i < 8; i++ {
 			for j := 0; j < 8; j++ {
-				pt.table[i].xyz[j] = binary.BigEndian.Uint64(precomputes[i*64+j*8:])
+				table[i].xyz[j] = binary.BigEndian.Uint64(precomputes[i*64+j*8:])
 			}
-			pt.table[i].xyz[8] = 0x1000003d1
-			pt.table[i].xyz[9] = 0
-			pt.table[i].xyz[10] = 0
-			pt.table[i].xyz[11] = 0
+			table[i].xyz[8] = 0x1000003d1
+			table[i].xyz[9] = 0
+			table[i].xyz[10] = 0
+			table[i].xyz[11] = 0
 		}
 	} else {
 		for i := 0; i < 8; i++ {
 			for j := 0; j < 12; j++ {
-				pt.table[i].xyz[j] = binary.BigEndian.Uint64(precomputes[i*96+j*8:])
+				table[i].xyz[j] = binary.BigEndian.Uint64(precomputes[i*96+j*8:])
 			}
 		}
 	}
@@ -201,20 +359,29 @@ func (c *curve) FastPointAdd(fpr, fp1, fp2 vc.FastPoint) {
 	if p1.zero {
 		copy(pr.p.xyz[:], p2.p.xyz[:])
 		pr.zero = p2.zero
+		pr.table = nil
+		pr.lazy = nil
 		return
 	}
 	if p2.zero {
 		copy(pr.p.xyz[:], p1.p.xyz[:])
 		pr.zero = p1.zero
+		pr.table = nil
+		pr.lazy = nil
 		return
 	}
 
-	sign := p256k1PointAddAsm(pr.p.xyz[:], p1.p.xyz[:], p2.p.xyz[:])
+	// add into a temporary, pr may alias p2 which is still needed when the
+	// points turn out to be equal
+	var sum point
+	sign := p256k1PointAddAsm(sum.xyz[:], p1.p.xyz[:], p2.p.xyz[:])
 	if sign == 3 {
-		p256k1PointDoubleAsm(pr.p.xyz[:], p2.p.xyz[:])
+		p256k1PointDoubleAsm(sum.xyz[:], p2.p.xyz[:])
 	}
+	copy(pr.p.xyz[:], sum.xyz[:])
 	pr.zero = sign == 2
 	pr.table = nil
+	pr.lazy = nil
 }
 
 func (c *curve) FastBaseScalar(scalar []byte) vc.FastPoint {
@@ -227,6 +394,8 @@ func (c *curve) FastBaseScalar(scalar []byte) vc.FastPoint {
 		table: nil,
 	}
 	p256k1BaseMul(r.p, scalarReversed)
+	// multiples of N come out as (0, 0); no point on the curve has x = 0
+	r.zero = scalarIsZero(r.p.xyz[0:4])
 	return r
 }
 
@@ -237,24 +406,21 @@ func (c *curve) FastScalarMult(fpr, fp vc.FastPoint, scalar []byte) {
 		pr.zero = true
 		return
 	}
-	zInv := make([]uint64, 4)
-	zInvSq := make([]uint64, 4)
-	p256k1Inverse(zInv, p.p.xyz[8:12])
-	p256k1Sqr(zInvSq, zInv, 1)
-	p256k1Mul(zInv, zInv, zInvSq)
-
-	p256k1Mul(pr.p.xyz[0:4], p.p.xyz[0:4], zInvSq)
-	p256k1Mul(pr.p.xyz[4:8], p.p.xyz[4:8], zInv)
-	pr.p.xyz[8] = 0x1000003d1
-	pr.p.xyz[9] = 0
-	pr.p.xyz[10] = 0
-	pr.p.xyz[11] = 0
-	pr.ScalarMultKoblitz(scalar)
+	if useGLV {
+		pr.zero = glvPolynomial(pr.p, []*point{p.p}, [][]byte{scalar})
+	} else {
+		c.wnafPolynomial(pr, []vc.FastPoint{p}, [][]byte{scalar})
+	}
 	pr.table = nil
+	pr.lazy = nil
 }
 
 func (c *curve) FastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
 	num := len(scalarList)
+	if num >= pippengerThreshold {
+		pippenger(result, pList, scalarList, 1, useGLV && num <= glvMaxPippenger)
+		return
+	}
 	sum := FastCurve().NewPoint()
 	tmp := FastCurve().NewPoint()
 	start := 0
@@ -275,6 +441,31 @@ func (c *curve) FastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalar
 }
 
 func (c *curve) fastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	if useGLV && len(scalarList) <= glvMaxInterleaved {
+		c.glvFastPolynomial(result, pList, scalarList)
+	} else {
+		c.wnafPolynomial(result, pList, scalarList)
+	}
+}
+
+func (c *curve) glvFastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	points := make([]*point, 0, len(scalarList))
+	scalars := make([][]byte, 0, len(scalarList))
+	for i := range scalarList {
+		if p := pList[i].(*Point); !p.zero {
+			points = append(points, p.p)
+			scalars = append(scalars, scalarList[i])
+		}
+	}
+	p := result.(*Point)
+	p.zero = glvPolynomial(p.p, points, scalars)
+	p.table = nil
+	p.lazy = nil
+}
+
+// wnafPolynomial is the plain width-5 NAF multi-scalar multiplication, with a
+// full length doubling chain.
+func (c *curve) wnafPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
 	num := len(scalarList)
 	tables := make([][]point, num)
 	nafList := make([][257]int8, num)
@@ -339,6 +530,7 @@ func (c *curve) fastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalar
 		}
 	}
 	p.table = nil
+	p.lazy = nil
 }
 
 func (pt *Point) ScalarMultKoblitz(scalar []byte) {
@@ -483,6 +675,10 @@ func (c *curve) FastOrderMul(r, a, b vc.FastBn) {
 
 func (c *curve) FasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte, affine bool) {
 	num := len(scalarList)
+	if num >= pippengerThresholdPrecomputed {
+		pippenger(result, pList, scalarList, 1, useGLV && num <= glvMaxPippenger)
+		return
+	}
 	sum := FastCurve().NewPoint()
 	tmp := FastCurve().NewPoint()
 	start := 0
@@ -505,12 +701,14 @@ func (c *curve) FasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scal
 func (c *curve) fasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte, affine bool) {
 	num := len(scalarList)
 	nafList := make([][257]int8, num)
+	tables := make([]*[8]point, num)
 	scalar := make([]byte, 32)
 	for i := 0; i < num; i++ {
 		diff := 32 - len(scalarList[i])
 		copy(scalar[diff:], scalarList[i])
 		copy(scalar[:diff], zero32[:diff])
 		nafList[i] = nonAdjacentFormBE256(scalar, 5)
+		tables[i] = pList[i].(*Point).precomputed()
 	}
 	var tmp point
 	p := result.(*Point)
@@ -521,7 +719,7 @@ func (c *curve) fasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scal
 		}
 		for n := 0; n < num; n++ {
 			v := nafList[n][i]
-			currentTable := pList[n].(*Point).table
+			currentTable := tables[n]
 			if v > 0 {
 				if zero {
 					copy(p.p.xyz[:], currentTable[v/2].xyz[:])
@@ -584,4 +782,5 @@ func (c *curve) fasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scal
 		}
 	}
 	p.table = nil
+	p.lazy = nil
 }
@@ -83,6 +83,56 @@ func TestFastPoly(t *testing.T) {
 	fmt.Println(ry.Cmp(sumY))
 }
 
+func TestMapTable(t *testing.T) {
+	InitNAFTables(9)
+	num := 200
+
+	kList := make([][]byte, num)
+	imported := make([]vc.FastPoint, num)
+	mapped := make([]vc.FastPoint, num)
+	n1 := new(big.Int).Sub(p256k1Curve.Params().N, big.NewInt(1))
+	for i := 0; i < num; i++ {
+		d, err := rand.Int(rand.Reader, n1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := p256k1Curve.ScalarBaseMult(d.Bytes())
+		src := p256k1Curve.NewPoint()
+		src.From(x, y)
+		table := src.ExportTable(true)
+
+		imported[i] = p256k1Curve.NewPoint()
+		imported[i].From(x, y)
+		imported[i].ImportTable(table, true)
+		mapped[i] = p256k1Curve.NewPoint()
+		mapped[i].From(x, y)
+		mapped[i].MapTable(table, true)
+
+		k, err := rand.Int(rand.Reader, n1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kList[i] = k.Bytes()
+	}
+
+	r1 := p256k1Curve.NewPoint()
+	r2 := p256k1Curve.NewPoint()
+	p256k1Curve.FasterPolynomial(r1, imported, kList, true)
+	p256k1Curve.FasterPolynomial(r2, mapped, kList, true)
+	x1, y1 := r1.Back()
+	x2, y2 := r2.Back()
+	if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+		t.Fatal("mapped table gives a different result")
+	}
+
+	// a copy shares the lazy table and still exports the same bytes
+	cp := p256k1Curve.NewPoint()
+	cp.CopyFrom(mapped[0])
+	if string(cp.ExportTable(true)) != string(imported[0].ExportTable(true)) {
+		t.Fatal("mapped table exports different bytes")
+	}
+}
+
 func BenchmarkFastPoly(t *testing.B) {
 	InitNAFTables(9)
 	num := 65536
@@ -150,29 +200,10 @@ func BenchmarkFastPolyMulti(b *testing.B) {
 		mx, my := p256k1Curve.ScalarMult(x, y, kList[i])
 		sumX, sumY = p256k1Curve.Add(sumX, sumY, mx, my)
 	}
-	core := 16
 	result := p256k1Curve.NewPoint()
 	b.ResetTimer()
 	for x := 0; x < b.N; x++ {
-		var wg sync.WaitGroup
-		r := make([]vc.FastPoint, core)
-		for t := 0; t < core; t++ {
-			s := num * t / core
-			e := num * (t + 1) / core
-			wg.Add(1)
-			go func(start, end, index int) {
-				defer wg.Done()
-				r[index] = p256k1Curve.NewPoint()
-				p256k1Curve.FastPolynomial(r[index], pList[start:end], kList[start:end])
-			}(s, e, t)
-
-		}
-		wg.Wait()
-
-		result.CopyFrom(r[0])
-		for i := 1; i < core; i++ {
-			p256k1Curve.FastPointAdd(result, result, r[i])
-		}
+		fastPolyMulti(result, pList, kList)
 	}
 	b.StopTimer()
 	rx, ry := result.Back()
@@ -180,6 +211,31 @@ func BenchmarkFastPolyMulti(b *testing.B) {
 	fmt.Println(ry.Cmp(sumY))
 }
 
+// fastPolyMulti splits FastPolynomial over 16 goroutines.
+func fastPolyMulti(result vc.FastPoint, pList []vc.FastPoint, kList [][]byte) {
+	core := 16
+	num := len(pList)
+	var wg sync.WaitGroup
+	r := make([]vc.FastPoint, core)
+	for t := 0; t < core; t++ {
+		s := num * t / core
+		e := num * (t + 1) / core
+		wg.Add(1)
+		go func(start, end, index int) {
+			defer wg.Done()
+			r[index] = p256k1Curve.NewPoint()
+			p256k1Curve.FastPolynomial(r[index], pList[start:end], kList[start:end])
+		}(s, e, t)
+
+	}
+	wg.Wait()
+
+	result.CopyFrom(r[0])
+	for i := 1; i < core; i++ {
+		p256k1Curve.FastPointAdd(result, result, r[i])
+	}
+}
+
 func TestRR(t *testing.T) {
 	InitNAFTables(9)
 	a, _ := rand.Int(rand.Reader, p256k1Curve.params.N)
@@ -194,3 +250,76 @@ func TestRR(t *testing.T) {
 	fmt.Println(res)
 
 }
+
+func TestPointOps(t *testing.T) {
+	InitNAFTables(9)
+	pList, _ := randomMSMInput(t, 3)
+	p, q := pList[0], pList[1]
+
+	// same point with a different Z
+	sum := p256k1Curve.NewPoint()
+	p256k1Curve.FastPointAdd(sum, p, q)
+	diff := p256k1Curve.NewPoint()
+	diff.CopyFrom(sum)
+	diff.Sub(q)
+	if !diff.Equal(p) || diff.Equal(q) || !samePoint(diff, p) {
+		t.Fatal("(P + Q) - Q != P")
+	}
+
+	twice := p256k1Curve.NewPoint()
+	twice.CopyFrom(p)
+	twice.Double()
+	want := p256k1Curve.NewPoint()
+	p256k1Curve.FastScalarMult(want, p, []byte{2})
+	if !twice.Equal(want) {
+		t.Fatal("Double differs from 2*P")
+	}
+	aliased := p256k1Curve.NewPoint()
+	aliased.CopyFrom(p)
+	p256k1Curve.FastPointAdd(aliased, aliased, aliased)
+	if !aliased.Equal(want) {
+		t.Fatal("P + P with aliased operands differs from 2*P")
+	}
+
+	zero := p256k1Curve.NewPoint()
+	zero.CopyFrom(p)
+	zero.Sub(p)
+	if !zero.IsZero() || !zero.Equal(p256k1Curve.NewPoint()) || zero.Equal(p) || p.Equal(zero) {
+		t.Fatal("P - P is not the point at infinity")
+	}
+	zero.CopyFrom(p)
+	zero.SetZero()
+	if !zero.IsZero() {
+		t.Fatal("SetZero")
+	}
+	zero.Double()
+	if !zero.IsZero() {
+		t.Fatal("2*O is not the point at infinity")
+	}
+
+	for _, point := range []vc.FastPoint{p, sum, twice, zero} {
+		data := point.MarshalCompressed()
+		decoded := p256k1Curve.NewPoint()
+		if err := decoded.UnmarshalCompressed(data); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(point) {
+			t.Fatal("compressed encoding does not round trip")
+		}
+	}
+	if data := zero.MarshalCompressed(); len(data) != 1 || data[0] != 0 {
+		t.Fatal("unexpected encoding of the point at infinity")
+	}
+
+	bad := p.MarshalCompressed()
+	bad[0] = 0x04
+	overP := append([]byte{0x02}, p256k1Curve.params.P.Bytes()...)
+	// x = 5 gives x^3 + 7 = 132, which is not a square mod P
+	offCurve := make([]byte, 33)
+	offCurve[0], offCurve[32] = 0x02, 5
+	for _, data := range [][]byte{nil, {0x02}, bad, overP, offCurve, p.MarshalCompressed()[:32]} {
+		if err := p256k1Curve.NewPoint().UnmarshalCompressed(data); err == nil {
+			t.Fatalf("UnmarshalCompressed accepted %x", data)
+		}
+	}
+}
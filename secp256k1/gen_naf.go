@@ -0,0 +1,27 @@
+//go:build ignore
+// +build ignore
+
+// gen_naf.go writes naf9.bin, the window 9 base point tables embedded by the
+// secp256k1_embed build tag.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"volley/secp256k1"
+)
+
+func main() {
+	params := secp256k1.FastCurve().Params()
+	pc, err := secp256k1.NewPrecomputed(params.Gx, params.Gy, 9)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := pc.MarshalBinary()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("naf9.bin", data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,233 @@
+package secp256k1
+
+import (
+	"math/bits"
+)
+
+// Constants of the GLV decomposition as used by libsecp256k1: every scalar k
+// is split into k1 + k2*lambda mod N with |k1|, |k2| < 2^128, where
+// lambda*(x, y) = (beta*x, y). g1 and g2 are round(2^384 * b2 / N) and
+// round(2^384 * -b1 / N), all limbs little-endian.
+var (
+	glvG1      = [4]uint64{0xe893209a45dbb031, 0x3daa8a1471e8ca7f, 0xe86c90e49284eb15, 0x3086d221a7d46bcd}
+	glvG2      = [4]uint64{0x1571b4ae8ac47f71, 0x221208ac9df506c6, 0x6f547fa90abfe4c4, 0xe4437ed6010e8828}
+	glvMinusB1 = [4]uint64{0x6f547fa90abfe4c3, 0xe4437ed6010e8828, 0, 0}
+	glvMinusB2 = [4]uint64{0xd765cda83db1562c, 0x8a280ac50774346d, 0xfffffffffffffffe, 0xffffffffffffffff}
+	glvLambda  = [4]uint64{0xdf02967c1b23bd72, 0x122e22ea20816678, 0xa5261c028812645a, 0x5363ad4cc05c30e0}
+
+	// Montgomery forms, so that one p256k1OrdMul with a plain value gives a
+	// plain product.
+	glvMinusB1Mont, glvMinusB2Mont, glvMinusLambdaMont [4]uint64
+)
+
+// Input sizes up to which splitting the scalars pays off. Past them the saved
+// doublings are amortized over many points and the extra tables, or the twice
+// as many bucket additions, cost more than they save.
+const (
+	glvMaxInterleaved = 16
+	glvMaxPippenger   = 1024
+)
+
+func init() {
+	p256k1OrdMul(glvMinusB1Mont[:], glvMinusB1[:], ro)
+	p256k1OrdMul(glvMinusB2Mont[:], glvMinusB2[:], ro)
+	var minusLambda [4]uint64
+	ordSub(&minusLambda, &[4]uint64{}, &glvLambda)
+	p256k1OrdMul(glvMinusLambdaMont[:], minusLambda[:], ro)
+}
+
+// scalarToLimbs converts a big-endian scalar to little-endian limbs. Inputs
+// longer than 32 bytes are reduced modulo N.
+func scalarToLimbs(out *[4]uint64, in []byte) {
+	if len(in) > 32 {
+		sm2CurveGetScalar(out[:], in)
+		return
+	}
+	var buf [32]byte
+	copy(buf[32-len(in):], in)
+	p256k1BigToLittle(out[:], buf[:])
+}
+
+// mulShift384 returns round(a * b / 2^384).
+func mulShift384(a, b *[4]uint64) [4]uint64 {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+4] = carry
+	}
+	var r [4]uint64
+	var c uint64
+	r[0], c = bits.Add64(t[6], t[5]>>63, 0)
+	r[1] = t[7] + c
+	return r
+}
+
+// splitScalar decomposes k into k1 + k2*lambda mod N and returns |k1|, |k2|
+// together with their signs.
+func splitScalar(k *[4]uint64) (k1, k2 [4]uint64, neg1, neg2 bool) {
+	kr := *k
+	ordReduceOnce(&kr)
+	c1 := mulShift384(&kr, &glvG1)
+	c2 := mulShift384(&kr, &glvG2)
+	p256k1OrdMul(c1[:], c1[:], glvMinusB1Mont[:])
+	p256k1OrdMul(c2[:], c2[:], glvMinusB2Mont[:])
+	ordAdd(&k2, &c1, &c2)
+	p256k1OrdMul(k1[:], k2[:], glvMinusLambdaMont[:])
+	ordAdd(&k1, &k1, &kr)
+
+	// anything above N/2 is a small negative number
+	if k1[3]>>63 != 0 {
+		ordSub(&k1, &[4]uint64{}, &k1)
+		neg1 = true
+	}
+	if k2[3]>>63 != 0 {
+		ordSub(&k2, &[4]uint64{}, &k2)
+		neg2 = true
+	}
+	return
+}
+
+func scalarBitLen(k *[4]uint64) int {
+	for i := 3; i >= 0; i-- {
+		if k[i] != 0 {
+			return 64*i + bits.Len64(k[i])
+		}
+	}
+	return 0
+}
+
+func limbsToBytes(k *[4]uint64) []byte {
+	out := make([]byte, 32)
+	p256k1LittleToBig(out, k[:])
+	i := 0
+	for i < len(out) && out[i] == 0 {
+		i++
+	}
+	return out[i:]
+}
+
+// splitK is splitScalar in the byte oriented form ScalarMultKoblitz expects.
+func splitK(k []byte) ([]byte, []byte, int, int) {
+	var kk [4]uint64
+	scalarToLimbs(&kk, k)
+	k1, k2, neg1, neg2 := splitScalar(&kk)
+	sign1, sign2 := 1, 1
+	if neg1 {
+		sign1 = -1
+	}
+	if neg2 {
+		sign2 = -1
+	}
+	return limbsToBytes(&k1), limbsToBytes(&k2), sign1, sign2
+}
+
+// endomorphism sets r to lambda*p, which only scales the X coordinate.
+func endomorphism(r, p *point) {
+	copy(r.xyz[:], p.xyz[:])
+	p256k1Mul(r.xyz[0:4], r.xyz[0:4], betaField)
+}
+
+// wnafTable fills table with p, 3p, ..., 15p in Jacobian coordinates.
+func wnafTable(table *[8]point, p *point) {
+	var p2 point
+	p256k1PointDoubleAsm(p2.xyz[:], p.xyz[:])
+	copy(table[0].xyz[:], p.xyz[:])
+	for j := 1; j < 8; j++ {
+		p256k1PointAddAsm(table[j].xyz[:], table[j-1].xyz[:], p2.xyz[:])
+	}
+}
+
+// wnafAdd adds v*table to p for a width-5 NAF digit v, tracking infinity in
+// zero.
+func wnafAdd(p *point, zero *bool, table *[8]point, v int8) {
+	var tmp point
+	if v > 0 {
+		copy(tmp.xyz[:], table[v/2].xyz[:])
+	} else {
+		copy(tmp.xyz[:], table[-v/2].xyz[:])
+		p256k1Neg(tmp.xyz[4:8])
+	}
+	jacobianAdd(p, zero, &tmp)
+}
+
+// glvPolynomial sets p to sum(scalarList[i] * points[i]) with interleaved
+// width-5 NAF, splitting every scalar in two halves of about 128 bits so the
+// doubling chain is half as long. It reports whether p is the point at
+// infinity.
+func glvPolynomial(p *point, points []*point, scalarList [][]byte) (zero bool) {
+	num := len(scalarList)
+	tables := make([][2][8]point, num)
+	nafList := make([][2][257]int8, num)
+	top := 0
+	var buf [32]byte
+	for i := 0; i < num; i++ {
+		var k [4]uint64
+		scalarToLimbs(&k, scalarList[i])
+		k1, k2, neg1, neg2 := splitScalar(&k)
+		wnafTable(&tables[i][0], points[i])
+		for j := range tables[i][1] {
+			endomorphism(&tables[i][1][j], &tables[i][0][j])
+		}
+		for half, kh := range [2]*[4]uint64{&k1, &k2} {
+			p256k1LittleToBig(buf[:], kh[:])
+			nafList[i][half] = nonAdjacentFormBE256(buf[:], 5)
+			if half == 0 && neg1 || half == 1 && neg2 {
+				for j := range nafList[i][half] {
+					nafList[i][half][j] = -nafList[i][half][j]
+				}
+			}
+			if t := scalarBitLen(kh) + 1; t > top {
+				top = t
+			}
+		}
+	}
+
+	if top > 256 {
+		top = 256
+	}
+	zero = true
+	for i := top; i >= 0; i-- {
+		if !zero {
+			p256k1PointDoubleAsm(p.xyz[:], p.xyz[:])
+		}
+		for n := 0; n < num; n++ {
+			for half := 0; half < 2; half++ {
+				if v := nafList[n][half][i]; v != 0 {
+					wnafAdd(p, &zero, &tables[n][half], v)
+				}
+			}
+		}
+	}
+	if zero {
+		for i := 0; i < 12; i++ {
+			p.xyz[i] = 0
+		}
+	}
+	return zero
+}
+
+// glvExpand turns n affine points and scalars into 2n points and half-size
+// scalars: (k, P) becomes (|k1|, ±P) and (|k2|, ±lambda*P), the signs being
+// returned in neg.
+func glvExpand(affine []point, scalars [][4]uint64) ([]point, [][4]uint64, []bool) {
+	num := len(affine)
+	outPoints := make([]point, 2*num)
+	outScalars := make([][4]uint64, 2*num)
+	neg := make([]bool, 2*num)
+	copy(outPoints, affine)
+	for i := 0; i < num; i++ {
+		endomorphism(&outPoints[num+i], &affine[i])
+		outScalars[i], outScalars[num+i], neg[i], neg[num+i] = splitScalar(&scalars[i])
+	}
+	return outPoints, outScalars, neg
+}
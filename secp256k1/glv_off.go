@@ -0,0 +1,6 @@
+//go:build secp256k1_noglv
+// +build secp256k1_noglv
+
+package secp256k1
+
+const useGLV = false
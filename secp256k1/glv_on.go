@@ -0,0 +1,9 @@
+//go:build !secp256k1_noglv
+// +build !secp256k1_noglv
+
+package secp256k1
+
+// useGLV enables the endomorphism based scalar multiplication for the input
+// sizes where it is faster, see glvMaxInterleaved. Build with the
+// secp256k1_noglv tag to fall back to plain wNAF.
+const useGLV = true
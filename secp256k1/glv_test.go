@@ -0,0 +1,130 @@
+package secp256k1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	vc "volley/curve"
+)
+
+func TestSplitScalar(t *testing.T) {
+	N := p256k1Curve.Params().N
+	lambda := new(big.Int).SetBytes(limbsToBytes(&glvLambda))
+	bound := new(big.Int).Lsh(big.NewInt(1), 129)
+	edge := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(N, big.NewInt(1)), new(big.Int).Rsh(N, 1),
+		new(big.Int).Set(N), new(big.Int).Add(N, big.NewInt(7)), lambda}
+	for i := 0; i < 2000; i++ {
+		var k *big.Int
+		if i < len(edge) {
+			k = edge[i]
+		} else {
+			k = randomScalar(t)
+		}
+		var kk [4]uint64
+		buf := make([]byte, 32)
+		k.FillBytes(buf)
+		scalarToLimbs(&kk, buf)
+		k1, k2, neg1, neg2 := splitScalar(&kk)
+		a := new(big.Int).SetBytes(limbsToBytes(&k1))
+		b := new(big.Int).SetBytes(limbsToBytes(&k2))
+		if a.Cmp(bound) >= 0 || b.Cmp(bound) >= 0 {
+			t.Fatalf("split of %x is too long", k)
+		}
+		if neg1 {
+			a.Neg(a)
+		}
+		if neg2 {
+			b.Neg(b)
+		}
+		sum := new(big.Int).Mul(b, lambda)
+		sum.Add(sum, a)
+		sum.Sub(sum, k)
+		if sum.Mod(sum, N).Sign() != 0 {
+			t.Fatalf("k1 + k2*lambda != k for %x", k)
+		}
+	}
+}
+
+func samePoint(p1, p2 vc.FastPoint) bool {
+	if p1.IsZero() || p2.IsZero() {
+		return p1.IsZero() == p2.IsZero()
+	}
+	x1, y1 := p1.Back()
+	x2, y2 := p2.Back()
+	return x1.Cmp(x2) == 0 && y1.Cmp(y2) == 0
+}
+
+func TestGLVCrossCheck(t *testing.T) {
+	InitNAFTables(9)
+	N := p256k1Curve.Params().N
+	for _, num := range []int{1, 2, 5, 64} {
+		pList, kList := randomMSMInput(t, num)
+		if num == 5 {
+			kList[1] = new(big.Int).Sub(N, big.NewInt(1)).Bytes()
+			kList[2] = nil
+			pList[3] = pList[4]
+		}
+		r1 := p256k1Curve.NewPoint()
+		r2 := p256k1Curve.NewPoint()
+		p256k1Curve.glvFastPolynomial(r1, pList, kList)
+		p256k1Curve.wnafPolynomial(r2, pList, kList)
+		if !samePoint(r1, r2) {
+			t.Fatalf("%d points: GLV and wNAF disagree", num)
+		}
+
+		pippenger(r1, pList, kList, 1, true)
+		pippenger(r2, pList, kList, 1, false)
+		if !samePoint(r1, r2) {
+			t.Fatalf("%d points: GLV and plain bucket method disagree", num)
+		}
+	}
+
+	pList, kList := randomMSMInput(t, 20)
+	for i := range pList {
+		r := p256k1Curve.NewPoint()
+		p256k1Curve.FastScalarMult(r, pList[i], kList[i])
+		px, py := pList[i].Back()
+		x, y := p256k1Curve.ScalarMult(px, py, kList[i])
+		rx, ry := r.Back()
+		if rx.Cmp(x) != 0 || ry.Cmp(y) != 0 {
+			t.Fatal("FastScalarMult mismatch")
+		}
+	}
+}
+
+func BenchmarkGLV(b *testing.B) {
+	InitNAFTables(9)
+	pList, kList := randomMSMInput(b, 1<<12)
+	r := p256k1Curve.NewPoint()
+	b.Run("scalarmult/glv", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.glvFastPolynomial(r, pList[:1], kList[:1])
+		}
+	})
+	b.Run("scalarmult/wnaf", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p256k1Curve.wnafPolynomial(r, pList[:1], kList[:1])
+		}
+	})
+	for _, num := range []int{1 << 6, 1 << 8, 1 << 10, 1 << 12} {
+		for _, glv := range []bool{true, false} {
+			b.Run(fmt.Sprintf("pippenger/%d/glv=%v", num, glv), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					pippenger(r, pList[:num], kList[:num], 1, glv)
+				}
+			})
+		}
+	}
+	for _, num := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("polynomial/%d/glv", num), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p256k1Curve.glvFastPolynomial(r, pList[:num], kList[:num])
+			}
+		})
+		b.Run(fmt.Sprintf("polynomial/%d/wnaf", num), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p256k1Curve.wnafPolynomial(r, pList[:num], kList[:num])
+			}
+		})
+	}
+}
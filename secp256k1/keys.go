@@ -0,0 +1,156 @@
+package secp256k1
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// PublicKey is a secp256k1 public key.
+type PublicKey struct {
+	X, Y *big.Int
+}
+
+// PrivateKey is a secp256k1 private key. It implements crypto.Signer, so it
+// can be used where the standard library takes one.
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// SignerOpts selects the hash for PrivateKey.Sign and whether the signature
+// is the 64-byte compact encoding instead of ASN.1 DER.
+type SignerOpts struct {
+	Hash    crypto.Hash
+	Compact bool
+}
+
+func (o *SignerOpts) HashFunc() crypto.Hash {
+	return o.Hash
+}
+
+// GenerateKey returns a new key with D drawn uniformly from [1, N-1].
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	N := p256k1Curve.params.N
+	b := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		d := new(big.Int).SetBytes(b)
+		if d.Sign() > 0 && d.Cmp(N) < 0 {
+			return NewPrivateKey(d)
+		}
+	}
+}
+
+// NewPrivateKey derives the public key of d, which must lie in [1, N-1].
+func NewPrivateKey(d *big.Int) (*PrivateKey, error) {
+	if d.Sign() <= 0 || d.Cmp(p256k1Curve.params.N) >= 0 {
+		return nil, fmt.Errorf("Private key out of range\n")
+	}
+	x, y := p256k1Curve.FastBaseScalarSecret(d.Bytes()).Back()
+	return &PrivateKey{PublicKey: PublicKey{X: x, Y: y}, D: new(big.Int).Set(d)}, nil
+}
+
+// PrivateKeyFromECDSA converts key, checking that it is a secp256k1 key.
+func PrivateKeyFromECDSA(key *ecdsa.PrivateKey) (*PrivateKey, error) {
+	pri, err := NewPrivateKey(key.D)
+	if err != nil {
+		return nil, err
+	}
+	if key.X != nil && (key.X.Cmp(pri.X) != 0 || key.Y.Cmp(pri.Y) != 0) {
+		return nil, fmt.Errorf("Public key doesn't match private key\n")
+	}
+	return pri, nil
+}
+
+// ECDSA returns key as an *ecdsa.PrivateKey for the functions of this
+// package that take one, such as MarshalPKCS8PrivateKey.
+func (key *PrivateKey) ECDSA() *ecdsa.PrivateKey {
+	return &ecdsa.PrivateKey{PublicKey: *key.PublicKey.ECDSA(), D: key.D}
+}
+
+func (key *PrivateKey) Public() crypto.PublicKey {
+	return &key.PublicKey
+}
+
+func (key *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key.D.Bytes(), other.D.Bytes()) == 1
+}
+
+// Sign signs digest as SignHash does. The signature is ASN.1 DER unless opts
+// is a *SignerOpts with Compact set. If opts names a hash, digest must have
+// its size.
+func (key *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != 0 && len(digest) != opts.HashFunc().Size() {
+		return nil, fmt.Errorf("Digest length %d doesn't match the hash\n", len(digest))
+	}
+	r, s, err := SignHash(rand, key.ECDSA(), digest)
+	if err != nil {
+		return nil, err
+	}
+	if o, ok := opts.(*SignerOpts); ok && o.Compact {
+		return MarshalCompactSig(r, s), nil
+	}
+	return MarshalSig(r, s), nil
+}
+
+// NewPublicKey checks that (x, y) is on the curve.
+func NewPublicKey(x, y *big.Int) (*PublicKey, error) {
+	if !p256k1Curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("Public key not on curve\n")
+	}
+	return &PublicKey{X: new(big.Int).Set(x), Y: new(big.Int).Set(y)}, nil
+}
+
+func (pub *PublicKey) ECDSA() *ecdsa.PublicKey {
+	return &ecdsa.PublicKey{Curve: p256k1Curve, X: pub.X, Y: pub.Y}
+}
+
+func (pub *PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return pub.X.Cmp(other.X) == 0 && pub.Y.Cmp(other.Y) == 0
+}
+
+// Verify checks sig over digest. sig may be ASN.1 DER or the 64-byte compact
+// encoding. Signatures with s above N/2 are accepted.
+func (pub *PublicKey) Verify(digest, sig []byte) bool {
+	r, s, err := UnmarshalSig(sig)
+	if err != nil && len(sig) == 64 {
+		r, s, err = UnmarshalCompactSig(sig)
+	}
+	if err != nil {
+		return false
+	}
+	return VerifyHash(pub.ECDSA(), digest, r, s, nil)
+}
+
+// ECDH returns the x coordinate of D*pub, 32 bytes, as in SEC 1 section
+// 3.3.1. The multiplication is the constant-time FastScalarMultSecret.
+func ECDH(pri *PrivateKey, pub *PublicKey) ([]byte, error) {
+	if !p256k1Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("Public key not on curve\n")
+	}
+	point := p256k1Curve.NewPoint()
+	point.From(pub.X, pub.Y)
+	shared := p256k1Curve.NewPoint()
+	p256k1Curve.FastScalarMultSecret(shared, point, pri.D.Bytes())
+	if shared.IsZero() {
+		return nil, fmt.Errorf("Shared secret is infinity\n")
+	}
+	x, _ := shared.Back()
+	secret := make([]byte, 32)
+	x.FillBytes(secret)
+	return secret, nil
+}
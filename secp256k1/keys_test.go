@@ -0,0 +1,95 @@
+package secp256k1_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"volley/secp256k1"
+)
+
+var _ crypto.Signer = (*secp256k1.PrivateKey)(nil)
+
+func TestPrivateKeySigner(t *testing.T) {
+	key, err := secp256k1.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public().(*secp256k1.PublicKey)
+	digest := sha256.Sum256([]byte("signed through crypto.Signer"))
+
+	der, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = secp256k1.UnmarshalSig(der); err != nil {
+		t.Fatal("signature is not DER: ", err)
+	}
+	compact, err := key.Sign(rand.Reader, digest[:], &secp256k1.SignerOpts{Hash: crypto.SHA256, Compact: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compact) != 64 {
+		t.Fatal("compact signature has length", len(compact))
+	}
+	if !pub.Verify(digest[:], der) || !pub.Verify(digest[:], compact) {
+		t.Fatal("signature not verified")
+	}
+	digest[0] ^= 1
+	if pub.Verify(digest[:], der) || pub.Verify(digest[:], compact) {
+		t.Fatal("signature of another digest verified")
+	}
+	if _, err = key.Sign(rand.Reader, digest[:20], crypto.SHA256); err == nil {
+		t.Fatal("signed a digest of the wrong length")
+	}
+
+	converted, err := secp256k1.PrivateKeyFromECDSA(key.ECDSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !converted.Equal(key) || !converted.PublicKey.Equal(pub) {
+		t.Fatal("key changed in the conversion to ecdsa")
+	}
+}
+
+func TestECDH(t *testing.T) {
+	one, _ := secp256k1.NewPrivateKey(big.NewInt(1))
+	two, _ := secp256k1.NewPrivateKey(big.NewInt(2))
+	shared, err := secp256k1.ECDH(one, &two.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the x coordinate of 2G
+	want, _ := hex.DecodeString("c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	if !bytes.Equal(shared, want) {
+		t.Fatalf("ECDH(1, 2G) = %x", shared)
+	}
+
+	a, err := secp256k1.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := secp256k1.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab, err := secp256k1.ECDH(a, &b.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba, err := secp256k1.ECDH(b, &a.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ab, ba) {
+		t.Fatal("shared secrets differ")
+	}
+
+	bad := &secp256k1.PublicKey{X: big.NewInt(1), Y: big.NewInt(1)}
+	if _, err = secp256k1.ECDH(a, bad); err == nil {
+		t.Fatal("ECDH accepted a point off the curve")
+	}
+}
@@ -0,0 +1,238 @@
+package secp256k1
+
+import (
+	"runtime"
+	"sync"
+	vc "volley/curve"
+)
+
+// Input sizes from which FastPolynomial and FasterPolynomial switch from
+// per-point wNAF to the bucket method. Points with precomputed tables stay on
+// wNAF longer since they skip building the tables.
+const (
+	pippengerThreshold            = 128
+	pippengerThresholdPrecomputed = 512
+)
+
+var fieldOne = [4]uint64{0x1000003d1, 0, 0, 0}
+
+// pippengerWindow picks the window size c minimizing the number of point
+// additions, roughly (bitLen/c) * (n + 2^c) for n points with signed digits.
+func pippengerWindow(n, bitLen int) uint {
+	best := uint(2)
+	bestCost := -1
+	for c := uint(2); c <= 16; c++ {
+		cost := (bitLen/int(c) + 1) * (n + 1<<c)
+		if bestCost < 0 || cost < bestCost {
+			best = c
+			bestCost = cost
+		}
+	}
+	return best
+}
+
+// jacobianAdd sets r = r + a. Both points carry their own infinity flag.
+func jacobianAdd(r *point, rZero *bool, a *point) {
+	if *rZero {
+		copy(r.xyz[:], a.xyz[:])
+		*rZero = false
+		return
+	}
+	sign := p256k1PointAddAsm(r.xyz[:], r.xyz[:], a.xyz[:])
+	if sign == 3 {
+		p256k1PointDoubleAsm(r.xyz[:], a.xyz[:])
+	} else if sign == 2 {
+		*rZero = true
+	}
+}
+
+// affineAdd sets r = r + a, or r = r - a if neg is set. a must have Z = 1.
+func affineAdd(r *point, rZero *bool, a *point, neg bool) {
+	if *rZero {
+		copy(r.xyz[:], a.xyz[:])
+		if neg {
+			p256k1Neg(r.xyz[4:8])
+		}
+		*rZero = false
+		return
+	}
+	sign := 0
+	if neg {
+		sign = 1
+	}
+	p256k1PointAddAffineAsm(r.xyz[:], r.xyz[:], a.xyz[:], sign)
+	x := r.xyz[0:4]
+	z := r.xyz[8:12]
+	if x[0]|x[1]|x[2]|x[3] == 0 {
+		copy(r.xyz[:], a.xyz[:])
+		if neg {
+			p256k1Neg(r.xyz[4:8])
+		}
+		p256k1PointDoubleAsm(r.xyz[:], r.xyz[:])
+	} else if z[0]|z[1]|z[2]|z[3] == 0 {
+		*rZero = true
+	}
+}
+
+// toAffine converts the non-zero points of pList to Z = 1 with a single field
+// inversion. Entries for points at infinity are left empty.
+func toAffine(pList []vc.FastPoint) []point {
+	affine := make([]point, len(pList))
+	in := make([]*point, 0, len(pList))
+	out := make([]*point, 0, len(pList))
+	for i, fp := range pList {
+		if p := fp.(*Point); !p.zero {
+			in = append(in, p.p)
+			out = append(out, &affine[i])
+		}
+	}
+	batchToAffine(out, in)
+	return affine
+}
+
+// signedDigits splits every scalar into windows of c bits with digits in
+// [-2^(c-1), 2^(c-1)], negated where neg is set. Digits of point i are
+// stored at i*windows.
+func signedDigits(scalars [][4]uint64, neg []bool, c uint, windows int) []int32 {
+	digits := make([]int32, len(scalars)*windows)
+	full := uint64(1) << c
+	half := full >> 1
+	for i := range scalars {
+		k := &scalars[i]
+		carry := uint64(0)
+		for w := 0; w < windows; w++ {
+			bit := uint(w) * c
+			var v uint64
+			if limb := bit / 64; limb < 4 {
+				v = k[limb] >> (bit % 64)
+				if bit%64+c > 64 && limb < 3 {
+					v |= k[limb+1] << (64 - bit%64)
+				}
+			}
+			v = v&(full-1) + carry
+			carry = 0
+			if v > half {
+				carry = 1
+				digits[i*windows+w] = int32(v) - int32(full)
+			} else {
+				digits[i*windows+w] = int32(v)
+			}
+		}
+		if neg != nil && neg[i] {
+			for w := 0; w < windows; w++ {
+				digits[i*windows+w] = -digits[i*windows+w]
+			}
+		}
+	}
+	return digits
+}
+
+// pippengerWindowSum returns the sum of digit*P over all points for window w.
+func pippengerWindowSum(r *point, affine []point, digits []int32, windows, w int, buckets []point,
+	bucketZero []bool) (zero bool) {
+	for i := range bucketZero {
+		bucketZero[i] = true
+	}
+	for i := range affine {
+		d := digits[i*windows+w]
+		if d > 0 {
+			affineAdd(&buckets[d-1], &bucketZero[d-1], &affine[i], false)
+		} else if d < 0 {
+			affineAdd(&buckets[-d-1], &bucketZero[-d-1], &affine[i], true)
+		}
+	}
+	// sum_j j*B_j computed as a running sum from the top bucket down
+	var running point
+	runningZero := true
+	zero = true
+	for j := len(buckets) - 1; j >= 0; j-- {
+		if !bucketZero[j] {
+			jacobianAdd(&running, &runningZero, &buckets[j])
+		}
+		if !runningZero {
+			jacobianAdd(r, &zero, &running)
+		}
+	}
+	return zero
+}
+
+// pippenger computes sum(scalarList[i] * pList[i]) with the bucket method,
+// spreading the windows over the given number of goroutines. With glv every
+// point is paired with its endomorphism image so the scalars are half as
+// long.
+func pippenger(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte, workers int, glv bool) {
+	num := len(scalarList)
+	affine := toAffine(pList[:num])
+	scalars := make([][4]uint64, num)
+	for i, fp := range pList[:num] {
+		if !fp.(*Point).zero {
+			scalarToLimbs(&scalars[i], scalarList[i])
+		}
+	}
+	var neg []bool
+	if glv {
+		affine, scalars, neg = glvExpand(affine, scalars)
+	}
+	bitLen := 0
+	for i := range scalars {
+		if l := scalarBitLen(&scalars[i]); l > bitLen {
+			bitLen = l
+		}
+	}
+	c := pippengerWindow(len(affine), bitLen)
+	windows := bitLen/int(c) + 1
+	digits := signedDigits(scalars, neg, c, windows)
+
+	sums := make([]point, windows)
+	sumZero := make([]bool, windows)
+	if workers > windows {
+		workers = windows
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for t := 0; t < workers; t++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			buckets := make([]point, 1<<(c-1))
+			bucketZero := make([]bool, len(buckets))
+			for w := index; w < windows; w += workers {
+				sumZero[w] = pippengerWindowSum(&sums[w], affine, digits, windows, w, buckets, bucketZero)
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	var acc point
+	accZero := true
+	for w := windows - 1; w >= 0; w-- {
+		if !accZero {
+			for i := uint(0); i < c; i++ {
+				p256k1PointDoubleAsm(acc.xyz[:], acc.xyz[:])
+			}
+		}
+		if !sumZero[w] {
+			jacobianAdd(&acc, &accZero, &sums[w])
+		}
+	}
+
+	p := result.(*Point)
+	copy(p.p.xyz[:], acc.xyz[:])
+	p.zero = accZero
+	if accZero {
+		for i := 0; i < 12; i++ {
+			p.p.xyz[i] = 0
+		}
+	}
+	p.table = nil
+	p.lazy = nil
+}
+
+// MultiScalarMult sets result to sum(scalarList[i] * pList[i]) using the
+// bucket method on all available cores. The window size follows the input
+// size.
+func (c *curve) MultiScalarMult(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	pippenger(result, pList, scalarList, runtime.GOMAXPROCS(0), useGLV && len(scalarList) <= glvMaxPippenger)
+}
@@ -0,0 +1,119 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"testing"
+	vc "volley/curve"
+)
+
+func randomMSMInput(t testing.TB, num int) ([]vc.FastPoint, [][]byte) {
+	N := p256k1Curve.Params().N
+	pList := make([]vc.FastPoint, num)
+	kList := make([][]byte, num)
+	for i := 0; i < num; i++ {
+		d, err := rand.Int(rand.Reader, N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pList[i] = p256k1Curve.FastBaseScalar(d.Bytes())
+		k, err := rand.Int(rand.Reader, N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kList[i] = k.Bytes()
+	}
+	return pList, kList
+}
+
+func referenceMSM(pList []vc.FastPoint, kList [][]byte) (x, y *big.Int) {
+	for i := range pList {
+		if pList[i].IsZero() || new(big.Int).SetBytes(kList[i]).Sign() == 0 {
+			continue
+		}
+		px, py := pList[i].Back()
+		mx, my := p256k1Curve.ScalarMult(px, py, kList[i])
+		if x == nil {
+			x, y = mx, my
+		} else {
+			x, y = p256k1Curve.Add(x, y, mx, my)
+		}
+	}
+	return
+}
+
+func checkMSM(t *testing.T, name string, pList []vc.FastPoint, kList [][]byte) {
+	t.Helper()
+	wantX, wantY := referenceMSM(pList, kList)
+	r := p256k1Curve.NewPoint()
+	p256k1Curve.MultiScalarMult(r, pList, kList)
+	x, y := r.Back()
+	if r.IsZero() || x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+		t.Fatalf("%s: result mismatch", name)
+	}
+}
+
+func TestMultiScalarMult(t *testing.T) {
+	InitNAFTables(9)
+	for _, num := range []int{1, 2, 7, 100, 600, 3000} {
+		pList, kList := randomMSMInput(t, num)
+		checkMSM(t, fmt.Sprint(num, " points"), pList, kList)
+	}
+
+	pList, kList := randomMSMInput(t, 50)
+	N := p256k1Curve.Params().N
+	// repeated points land in the same bucket and force a doubling
+	for i := 10; i < 20; i++ {
+		pList[i] = pList[0]
+		kList[i] = kList[0]
+	}
+	kList[20] = nil
+	kList[21] = new(big.Int).Sub(N, big.NewInt(1)).Bytes()
+	kList[22] = make([]byte, 32)
+	pList[23] = p256k1Curve.NewPoint()
+	checkMSM(t, "edge cases", pList, kList)
+
+	// P and -P with the same scalar cancel out
+	neg := p256k1Curve.NewPoint()
+	neg.CopyFrom(pList[1])
+	neg.Neg()
+	r := p256k1Curve.NewPoint()
+	p256k1Curve.MultiScalarMult(r, []vc.FastPoint{pList[1], neg}, [][]byte{kList[1], kList[1]})
+	if !r.IsZero() {
+		t.Fatal("P - P is not the point at infinity")
+	}
+
+	// the wNAF and bucket paths of FastPolynomial agree
+	pList, kList = randomMSMInput(t, pippengerThreshold)
+	r1 := p256k1Curve.NewPoint()
+	r2 := p256k1Curve.NewPoint()
+	p256k1Curve.FastPolynomial(r1, pList, kList)
+	p256k1Curve.FastPolynomial(r2, pList[:pippengerThreshold-1], kList[:pippengerThreshold-1])
+	tmp := p256k1Curve.NewPoint()
+	p256k1Curve.FastScalarMult(tmp, pList[pippengerThreshold-1], kList[pippengerThreshold-1])
+	p256k1Curve.FastPointAdd(r2, r2, tmp)
+	x1, y1 := r1.Back()
+	x2, y2 := r2.Back()
+	if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+		t.Fatal("FastPolynomial paths disagree")
+	}
+}
+
+func BenchmarkMultiScalarMult(b *testing.B) {
+	InitNAFTables(9)
+	for _, num := range []int{1 << 10, 1 << 14, 1 << 16} {
+		pList, kList := randomMSMInput(b, num)
+		result := p256k1Curve.NewPoint()
+		b.Run(fmt.Sprintf("fastpoly/%dk", num>>10), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fastPolyMulti(result, pList, kList)
+			}
+		})
+		b.Run(fmt.Sprintf("pippenger/%dk", num>>10), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p256k1Curve.MultiScalarMult(result, pList, kList)
+			}
+		})
+	}
+}
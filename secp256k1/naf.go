@@ -1,6 +1,134 @@
 package secp256k1
 
-var p256k1NAF6Tables *[43][33 * 8]uint64
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+//go:generate go run gen_naf.go
+
+// The base point tables are built on first use, once per window, so nothing
+// has to be initialized before multiplying. With the secp256k1_embed build
+// tag the window 9 tables are decoded from naf9.bin instead of being built.
+var (
+	p256k1NAF6Tables *[43][33 * 8]uint64
+	p256k1NAF7Tables *[37][65 * 8]uint64
+	p256k1NAF8Tables *[33][129 * 8]uint64
+	p256k1NAF9Tables *[29][257 * 8]uint64
+
+	nafTablesOnce [4]sync.Once
+)
+
+// baseWindow is the window picked by InitNAFTables, 0 until it is called.
+var baseWindow int32
+
+// nafBasePoint is G in the Montgomery domain.
+var nafBasePoint = point{
+	xyz: [12]uint64{
+		0xd7362e5a487e2097, 0x231e295329bc66db, 0x979f48c033fd129c, 0x9981e643e9089f48,
+		0xb15ea6d2d3dbabe2, 0x8dfc5d5d1f1dc64d, 0x70b6b59aac19c136, 0xcf3f851fd4a582d6,
+		0x1000003d1, 0x0, 0x0, 0x0,
+	},
+}
+
+func loadNAF6Tables() *[43][33 * 8]uint64 {
+	nafTablesOnce[0].Do(func() {
+		p256k1NAF6Tables = p256k1Curve.generateNAF6Tables(&nafBasePoint)
+	})
+	return p256k1NAF6Tables
+}
+
+func loadNAF7Tables() *[37][65 * 8]uint64 {
+	nafTablesOnce[1].Do(func() {
+		p256k1NAF7Tables = p256k1Curve.generateNAF7Tables(&nafBasePoint)
+	})
+	return p256k1NAF7Tables
+}
+
+func loadNAF8Tables() *[33][129 * 8]uint64 {
+	nafTablesOnce[2].Do(func() {
+		p256k1NAF8Tables = p256k1Curve.generateNAF8Tables(&nafBasePoint)
+	})
+	return p256k1NAF8Tables
+}
+
+func loadNAF9Tables() *[29][257 * 8]uint64 {
+	nafTablesOnce[3].Do(func() {
+		if p256k1NAF9Tables = embeddedNAF9Tables(); p256k1NAF9Tables == nil {
+			p256k1NAF9Tables = p256k1Curve.generateNAF9Tables(&nafBasePoint)
+		}
+	})
+	return p256k1NAF9Tables
+}
+
+// NAFTablesSize returns the memory taken by the base point tables of window
+// w in bytes, or 0 if w is not supported.
+func NAFTablesSize(w int) int {
+	switch w {
+	case 6:
+		return 43 * 33 * 8 * 8
+	case 7:
+		return 37 * 65 * 8 * 8
+	case 8:
+		return 33 * 129 * 8 * 8
+	case 9:
+		return 29 * 257 * 8 * 8
+	}
+	return 0
+}
+
+// WindowForMemory returns the largest window whose base point tables fit in
+// budget bytes, or 6 if none does.
+func WindowForMemory(budget int) int {
+	for w := 9; w > 6; w-- {
+		if NAFTablesSize(w) <= budget {
+			return w
+		}
+	}
+	return 6
+}
+
+// BaseWindow returns the window of the base point tables in use.
+func BaseWindow() int {
+	if w := atomic.LoadInt32(&baseWindow); w != 0 {
+		return int(w)
+	}
+	return DefaultWindow
+}
+
+// InitNAFTables builds the base point tables of window w, from 6 to 9, and
+// uses them from then on. Calling it is optional and safe from several
+// goroutines; without it the DefaultWindow tables are built on first use.
+// Tables of a window picked before stay in memory.
+func InitNAFTables(w int) {
+	switch w {
+	case 6:
+		loadNAF6Tables()
+	case 7:
+		loadNAF7Tables()
+	case 8:
+		loadNAF8Tables()
+	case 9:
+		loadNAF9Tables()
+	default:
+		panic(fmt.Sprintf("Unsupported NAF number %d", w))
+	}
+	atomic.StoreInt32(&baseWindow, int32(w))
+}
+
+func p256k1BaseMul(p *point, scalar []uint64) {
+	switch BaseWindow() {
+	case 6:
+		p.p256k1ScalarMultByPrecomputesNAF6(scalar, loadNAF6Tables())
+	case 7:
+		p.p256k1ScalarMultByPrecomputesNAF7(scalar, loadNAF7Tables())
+	case 8:
+		p.p256k1ScalarMultByPrecomputesNAF8(scalar, loadNAF8Tables())
+	default:
+		p.p256k1ScalarMultByPrecomputesNAF9(scalar, loadNAF9Tables())
+	}
+}
 
 func (c *curve) generateNAF6Tables(p *point) *[43][33 * 8]uint64 {
 	tables := new([43][33 * 8]uint64)
@@ -43,69 +171,6 @@ func (c *curve) generateNAF6Tables(p *point) *[43][33 * 8]uint64 {
 	return tables
 }
 
-func initNAF6Tables() {
-	basePoint := &point{
-		xyz: [12]uint64{
-			0xd7362e5a487e2097, 0x231e295329bc66db, 0x979f48c033fd129c, 0x9981e643e9089f48,
-			0xb15ea6d2d3dbabe2, 0x8dfc5d5d1f1dc64d, 0x70b6b59aac19c136, 0xcf3f851fd4a582d6,
-			0x1000003d1, 0x0, 0x0, 0x0,
-		},
-	}
-	p256k1NAF6Tables = p256k1Curve.generateNAF6Tables(basePoint)
-}
-
-func p256k1BaseMulNAF6(p *point, scalar []uint64) {
-	//precomputeOnce.Do(initTable)
-
-	wvalue := (scalar[0] << 1) & 0x7f
-	sel, sign := boothW6(uint(wvalue))
-	//sm2CurveSelectBaseBeta(p.xyz[0:8], sm2Curve37WindowsTables[0][0:], sel)
-	copy(p.xyz[0:8], p256k1NAF6Tables[0][sel*8:])
-	if sign != 0 {
-		p256k1Neg(p.xyz[4:8])
-	}
-
-	// (This is one, in the Montgomery domain.)
-	p.xyz[8] = 0x1000003d1
-	p.xyz[9] = 0x0
-	p.xyz[10] = 0x0
-	p.xyz[11] = 0x0
-
-	t0 := make([]uint64, 8)
-
-	index := uint(5)
-	zero := sel
-	//var t1 p256Point
-	for i := 1; i < 43; i++ {
-		if index < 192 {
-			wvalue = ((scalar[index/64] >> (index % 64)) + (scalar[index/64+1] << (64 - (index % 64)))) & 0x7f
-		} else {
-			wvalue = (scalar[index/64] >> (index % 64)) & 0x7f
-		}
-		index += 6
-		sel, sign = boothW6(uint(wvalue))
-		copy(t0[0:8], p256k1NAF6Tables[i][sel*8:])
-		//sm2CurveSelectBaseBeta(t0.xyz[0:8], sm2Curve37WindowsTables[i][0:], sel)
-		if zero == 0 {
-			if sign == 1 {
-				p256k1Neg(t0[4:8])
-			}
-			copy(p.xyz[:], t0[0:8])
-			p.xyz[8] = 0x1000003d1
-			p.xyz[9] = 0
-			p.xyz[10] = 0
-			p.xyz[11] = 0
-			//fmt.Println("happen 1")
-		} else if sel != 0 {
-			p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign)
-		}
-		//p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign, sel, zero)
-		zero |= sel
-	}
-}
-
-var p256k1NAF7Tables *[37][65 * 8]uint64
-
 func (c *curve) generateNAF7Tables(p *point) *[37][65 * 8]uint64 {
 	tables := new([37][65 * 8]uint64)
 
@@ -147,69 +212,6 @@ func (c *curve) generateNAF7Tables(p *point) *[37][65 * 8]uint64 {
 	return tables
 }
 
-func initNAF7Tables() {
-	basePoint := &point{
-		xyz: [12]uint64{
-			0xd7362e5a487e2097, 0x231e295329bc66db, 0x979f48c033fd129c, 0x9981e643e9089f48,
-			0xb15ea6d2d3dbabe2, 0x8dfc5d5d1f1dc64d, 0x70b6b59aac19c136, 0xcf3f851fd4a582d6,
-			0x1000003d1, 0x0, 0x0, 0x0,
-		},
-	}
-	p256k1NAF7Tables = p256k1Curve.generateNAF7Tables(basePoint)
-}
-
-func p256k1BaseMulNAF7(p *point, scalar []uint64) {
-	//precomputeOnce.Do(initTable)
-
-	wvalue := (scalar[0] << 1) & 0xff
-	sel, sign := boothW7(uint(wvalue))
-	//sm2CurveSelectBaseBeta(p.xyz[0:8], sm2Curve37WindowsTables[0][0:], sel)
-	copy(p.xyz[0:8], p256k1NAF7Tables[0][sel*8:])
-	if sign != 0 {
-		p256k1Neg(p.xyz[4:8])
-	}
-
-	// (This is one, in the Montgomery domain.)
-	p.xyz[8] = 0x1000003d1
-	p.xyz[9] = 0x0
-	p.xyz[10] = 0x0
-	p.xyz[11] = 0x0
-
-	t0 := make([]uint64, 8)
-
-	index := uint(6)
-	zero := sel
-	//var t1 p256Point
-	for i := 1; i < 37; i++ {
-		if index < 192 {
-			wvalue = ((scalar[index/64] >> (index % 64)) + (scalar[index/64+1] << (64 - (index % 64)))) & 0xff
-		} else {
-			wvalue = (scalar[index/64] >> (index % 64)) & 0xff
-		}
-		index += 7
-		sel, sign = boothW7(uint(wvalue))
-		copy(t0[0:8], p256k1NAF7Tables[i][sel*8:])
-		//sm2CurveSelectBaseBeta(t0.xyz[0:8], sm2Curve37WindowsTables[i][0:], sel)
-		if zero == 0 {
-			if sign == 1 {
-				p256k1Neg(t0[4:8])
-			}
-			copy(p.xyz[:], t0[0:8])
-			p.xyz[8] = 0x1000003d1
-			p.xyz[9] = 0
-			p.xyz[10] = 0
-			p.xyz[11] = 0
-			//fmt.Println("happen 1")
-		} else if sel != 0 {
-			p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign)
-		}
-		//p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign, sel, zero)
-		zero |= sel
-	}
-}
-
-var p256k1NAF8Tables *[33][129 * 8]uint64
-
 func (c *curve) generateNAF8Tables(p *point) *[33][129 * 8]uint64 {
 	tables := new([33][129 * 8]uint64)
 
@@ -251,70 +253,6 @@ func (c *curve) generateNAF8Tables(p *point) *[33][129 * 8]uint64 {
 	return tables
 }
 
-func initNAF8Tables() {
-
-	basePoint := &point{
-		xyz: [12]uint64{
-			0xd7362e5a487e2097, 0x231e295329bc66db, 0x979f48c033fd129c, 0x9981e643e9089f48,
-			0xb15ea6d2d3dbabe2, 0x8dfc5d5d1f1dc64d, 0x70b6b59aac19c136, 0xcf3f851fd4a582d6,
-			0x1000003d1, 0x0, 0x0, 0x0,
-		},
-	}
-	p256k1NAF8Tables = p256k1Curve.generateNAF8Tables(basePoint)
-}
-
-func p256k1BaseMulNAF8(p *point, scalar []uint64) {
-	//precomputeOnce.Do(initTable)
-
-	wvalue := (scalar[0] << 1) & 0x1ff
-	sel, sign := boothW8(uint(wvalue))
-	//sm2CurveSelectBaseBeta(p.xyz[0:8], sm2Curve37WindowsTables[0][0:], sel)
-	copy(p.xyz[0:8], p256k1NAF8Tables[0][sel*8:])
-	if sign != 0 {
-		p256k1Neg(p.xyz[4:8])
-	}
-
-	// (This is one, in the Montgomery domain.)
-	p.xyz[8] = 0x1000003d1
-	p.xyz[9] = 0x0
-	p.xyz[10] = 0x0
-	p.xyz[11] = 0x0
-
-	t0 := make([]uint64, 8)
-
-	index := uint(7)
-	zero := sel
-	//var t1 p256Point
-	for i := 1; i < 33; i++ {
-		if index < 192 {
-			wvalue = ((scalar[index/64] >> (index % 64)) + (scalar[index/64+1] << (64 - (index % 64)))) & 0x1ff
-		} else {
-			wvalue = (scalar[index/64] >> (index % 64)) & 0x1ff
-		}
-		index += 8
-		sel, sign = boothW8(uint(wvalue))
-		copy(t0[0:8], p256k1NAF8Tables[i][sel*8:])
-		//sm2CurveSelectBaseBeta(t0.xyz[0:8], sm2Curve37WindowsTables[i][0:], sel)
-		if zero == 0 {
-			if sign == 1 {
-				p256k1Neg(t0[4:8])
-			}
-			copy(p.xyz[:], t0[0:8])
-			p.xyz[8] = 0x1000003d1
-			p.xyz[9] = 0
-			p.xyz[10] = 0
-			p.xyz[11] = 0
-			//fmt.Println("happen 1")
-		} else if sel != 0 {
-			p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign)
-		}
-		//p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign, sel, zero)
-		zero |= sel
-	}
-}
-
-var p256k1NAF9Tables *[29][257 * 8]uint64
-
 func (c *curve) generateNAF9Tables(p *point) *[29][257 * 8]uint64 {
 	tables := new([29][257 * 8]uint64)
 
@@ -355,65 +293,3 @@ func (c *curve) generateNAF9Tables(p *point) *[29][257 * 8]uint64 {
 	}
 	return tables
 }
-
-func initNAF9Tables() {
-
-	basePoint := &point{
-		xyz: [12]uint64{
-			0xd7362e5a487e2097, 0x231e295329bc66db, 0x979f48c033fd129c, 0x9981e643e9089f48,
-			0xb15ea6d2d3dbabe2, 0x8dfc5d5d1f1dc64d, 0x70b6b59aac19c136, 0xcf3f851fd4a582d6,
-			0x1000003d1, 0x0, 0x0, 0x0,
-		},
-	}
-	p256k1NAF9Tables = p256k1Curve.generateNAF9Tables(basePoint)
-}
-
-func p256k1BaseMulNAF9(p *point, scalar []uint64) {
-	//precomputeOnce.Do(initTable)
-
-	wvalue := (scalar[0] << 1) & 0x3ff
-	sel, sign := boothW9(uint(wvalue))
-	//sm2CurveSelectBaseBeta(p.xyz[0:8], sm2Curve37WindowsTables[0][0:], sel)
-	copy(p.xyz[0:8], p256k1NAF9Tables[0][sel*8:])
-	if sign != 0 {
-		p256k1Neg(p.xyz[4:8])
-	}
-
-	// (This is one, in the Montgomery domain.)
-	p.xyz[8] = 0x1000003d1
-	p.xyz[9] = 0x0
-	p.xyz[10] = 0x0
-	p.xyz[11] = 0x0
-
-	t0 := make([]uint64, 8)
-
-	index := uint(8)
-	zero := sel
-	//var t1 p256Point
-	for i := 1; i < 29; i++ {
-		if index < 192 {
-			wvalue = ((scalar[index/64] >> (index % 64)) + (scalar[index/64+1] << (64 - (index % 64)))) & 0x3ff
-		} else {
-			wvalue = (scalar[index/64] >> (index % 64)) & 0x3ff
-		}
-		index += 9
-		sel, sign = boothW9(uint(wvalue))
-		copy(t0[0:8], p256k1NAF9Tables[i][sel*8:])
-		//sm2CurveSelectBaseBeta(t0.xyz[0:8], sm2Curve37WindowsTables[i][0:], sel)
-		if zero == 0 {
-			if sign == 1 {
-				p256k1Neg(t0[4:8])
-			}
-			copy(p.xyz[:], t0[0:8])
-			p.xyz[8] = 0x1000003d1
-			p.xyz[9] = 0
-			p.xyz[10] = 0
-			p.xyz[11] = 0
-			//fmt.Println("happen 1")
-		} else if sel != 0 {
-			p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign)
-		}
-		//p256k1PointAddAffineAsm(p.xyz[0:12], p.xyz[0:12], t0[0:8], sign, sel, zero)
-		zero |= sel
-	}
-}
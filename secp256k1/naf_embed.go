@@ -0,0 +1,23 @@
+//go:build secp256k1_embed
+// +build secp256k1_embed
+
+package secp256k1
+
+import (
+	_ "embed"
+	"fmt"
+)
+
+// naf9Data is the base point Precomputed of window 9 as written by
+// gen_naf.go.
+//
+//go:embed naf9.bin
+var naf9Data []byte
+
+func embeddedNAF9Tables() *[29][257 * 8]uint64 {
+	var pc Precomputed
+	if err := pc.UnmarshalBinary(naf9Data); err != nil || pc.window != 9 {
+		panic(fmt.Sprintf("Bad embedded NAF tables: %v", err))
+	}
+	return pc.naf9
+}
@@ -0,0 +1,12 @@
+//go:build secp256k1_embed
+// +build secp256k1_embed
+
+package secp256k1
+
+import "testing"
+
+func TestEmbeddedNAFTables(t *testing.T) {
+	if *embeddedNAF9Tables() != *p256k1Curve.generateNAF9Tables(&nafBasePoint) {
+		t.Fatal("naf9.bin is stale, run go generate")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !secp256k1_embed
+// +build !secp256k1_embed
+
+package secp256k1
+
+func embeddedNAF9Tables() *[29][257 * 8]uint64 {
+	return nil
+}
@@ -0,0 +1,50 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+)
+
+func TestBaseTablesConcurrent(t *testing.T) {
+	defer InitNAFTables(9)
+	var wg sync.WaitGroup
+	errs := make(chan []byte, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			InitNAFTables(w)
+			for j := 0; j < 20; j++ {
+				k := make([]byte, 32)
+				rand.Read(k)
+				if !samePoint(p256k1Curve.FastBaseScalar(k), p256k1Curve.FastBaseScalarSecret(k)) {
+					errs <- k
+					return
+				}
+			}
+		}(6 + i%4)
+	}
+	wg.Wait()
+	close(errs)
+	for k := range errs {
+		t.Errorf("FastBaseScalar mismatch for %x", k)
+	}
+}
+
+func TestWindowForMemory(t *testing.T) {
+	for _, tt := range []struct{ budget, window int }{
+		{0, 6},
+		{NAFTablesSize(7) - 1, 6},
+		{NAFTablesSize(7), 7},
+		{NAFTablesSize(9) - 1, 8},
+		{1 << 30, 9},
+	} {
+		if w := WindowForMemory(tt.budget); w != tt.window {
+			t.Errorf("WindowForMemory(%d) = %d, want %d", tt.budget, w, tt.window)
+		}
+	}
+	if NAFTablesSize(5) != 0 {
+		t.Error("NAFTablesSize accepted window 5")
+	}
+}
@@ -0,0 +1,251 @@
+package secp256k1
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	vc "volley/curve"
+)
+
+// DefaultWindow is the window of the base point tables, and of the tables
+// built by ComputePrecomputesForPoint, until InitNAFTables picks another one.
+const DefaultWindow = 9
+
+// Precomputed holds the Booth window tables of a fixed point, in the same
+// layout as the base point tables, so multiplying it by a scalar costs no
+// doubling. Exactly one of the tables is set, matching window.
+type Precomputed struct {
+	window int
+	naf6   *[43][33 * 8]uint64
+	naf7   *[37][65 * 8]uint64
+	naf8   *[33][129 * 8]uint64
+	naf9   *[29][257 * 8]uint64
+}
+
+// NewPrecomputed builds the tables of (x, y) for a window between 6 and 9.
+// Larger windows take more memory and time to build but multiply faster.
+func NewPrecomputed(x, y *big.Int, window int) (*Precomputed, error) {
+	if !p256k1Curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("Point is not on the curve\n")
+	}
+	var p point
+	fromBig(p.xyz[0:4], x)
+	fromBig(p.xyz[4:8], y)
+	p256k1Mul(p.xyz[0:4], p.xyz[0:4], rr)
+	p256k1Mul(p.xyz[4:8], p.xyz[4:8], rr)
+	copy(p.xyz[8:12], fieldOne[:])
+
+	pc := &Precomputed{window: window}
+	switch window {
+	case 6:
+		pc.naf6 = p256k1Curve.generateNAF6Tables(&p)
+	case 7:
+		pc.naf7 = p256k1Curve.generateNAF7Tables(&p)
+	case 8:
+		pc.naf8 = p256k1Curve.generateNAF8Tables(&p)
+	case 9:
+		pc.naf9 = p256k1Curve.generateNAF9Tables(&p)
+	default:
+		return nil, fmt.Errorf("Unsupported window %d\n", window)
+	}
+	return pc, nil
+}
+
+// Window returns the window the tables were built for.
+func (pc *Precomputed) Window() int {
+	return pc.window
+}
+
+// rows returns the tables one window at a time.
+func (pc *Precomputed) rows() [][]uint64 {
+	var rows [][]uint64
+	switch pc.window {
+	case 6:
+		for i := range pc.naf6 {
+			rows = append(rows, pc.naf6[i][:])
+		}
+	case 7:
+		for i := range pc.naf7 {
+			rows = append(rows, pc.naf7[i][:])
+		}
+	case 8:
+		for i := range pc.naf8 {
+			rows = append(rows, pc.naf8[i][:])
+		}
+	case 9:
+		for i := range pc.naf9 {
+			rows = append(rows, pc.naf9[i][:])
+		}
+	}
+	return rows
+}
+
+// mult sets r to scalar times the point and reports whether r is infinity.
+func (pc *Precomputed) mult(r *point, scalar []byte) bool {
+	scalarReversed := make([]uint64, 4)
+	sm2CurveGetScalar(scalarReversed, scalar)
+	switch pc.window {
+	case 6:
+		r.p256k1ScalarMultByPrecomputesNAF6(scalarReversed, pc.naf6)
+	case 7:
+		r.p256k1ScalarMultByPrecomputesNAF7(scalarReversed, pc.naf7)
+	case 8:
+		r.p256k1ScalarMultByPrecomputesNAF8(scalarReversed, pc.naf8)
+	case 9:
+		r.p256k1ScalarMultByPrecomputesNAF9(scalarReversed, pc.naf9)
+	}
+	// like the base tables, multiples of N come out as (0, 0)
+	return scalarIsZero(r.xyz[0:4])
+}
+
+// Point returns the point the tables were built for.
+func (pc *Precomputed) Point() vc.FastPoint {
+	r := &Point{p: new(point)}
+	// entry 1 of the first window is the point itself, with Z = 1
+	copy(r.p.xyz[0:8], pc.rows()[0][8:16])
+	copy(r.p.xyz[8:12], fieldOne[:])
+	return r
+}
+
+// ScalarMult sets fpr to scalar times the point.
+func (pc *Precomputed) ScalarMult(fpr vc.FastPoint, scalar []byte) {
+	r := fpr.(*Point)
+	r.zero = pc.mult(r.p, scalar)
+	r.table = nil
+	r.lazy = nil
+}
+
+// MarshalBinary encodes the window followed by every table entry as
+// big-endian words, coordinates in the Montgomery domain like ExportTable.
+func (pc *Precomputed) MarshalBinary() ([]byte, error) {
+	rows := pc.rows()
+	if rows == nil {
+		return nil, fmt.Errorf("Empty precomputed tables\n")
+	}
+	data := make([]byte, 1+len(rows)*len(rows[0])*8)
+	data[0] = byte(pc.window)
+	offset := 1
+	for _, row := range rows {
+		for _, v := range row {
+			binary.BigEndian.PutUint64(data[offset:], v)
+			offset += 8
+		}
+	}
+	return data, nil
+}
+
+// belowP reports whether the field element v is fully reduced.
+func belowP(v []uint64) bool {
+	for i := 3; i >= 0; i-- {
+		if v[i] != fieldP[i] {
+			return v[i] < fieldP[i]
+		}
+	}
+	return false
+}
+
+// check verifies that entry j of window i is j*2^(window*i) times the point,
+// walking every window with additions, which is much cheaper than building
+// the tables since no entry is converted to affine form.
+func (pc *Precomputed) check() error {
+	rows := pc.rows()
+	base := make([]uint64, 12)
+	acc := make([]uint64, 12)
+	copy(base, rows[0][8:16])
+	copy(base[8:12], fieldOne[:])
+	var z2, z3, a [4]uint64
+	for i, row := range rows {
+		if i != 0 {
+			for k := 0; k < pc.window; k++ {
+				p256k1PointDoubleAsm(base, base)
+			}
+		}
+		for _, v := range row[0:8] {
+			if v != 0 {
+				return fmt.Errorf("Precomputed entry 0 of window %d is not empty\n", i)
+			}
+		}
+		copy(acc, base)
+		for j := 1; j < len(row)/8; j++ {
+			if j == 2 {
+				p256k1PointDoubleAsm(acc, base)
+			} else if j > 2 {
+				p256k1PointAddAsm(acc, acc, base)
+			}
+			entry := row[j*8 : j*8+8]
+			if !belowP(entry[0:4]) || !belowP(entry[4:8]) {
+				return fmt.Errorf("Precomputed entry %d of window %d is not reduced\n", j, i)
+			}
+			// (x, y) == (X/Z^2, Y/Z^3)
+			p256k1Sqr(z2[:], acc[8:12], 1)
+			p256k1Mul(z3[:], z2[:], acc[8:12])
+			p256k1Mul(a[:], entry[0:4], z2[:])
+			ok := a == [4]uint64{acc[0], acc[1], acc[2], acc[3]}
+			p256k1Mul(a[:], entry[4:8], z3[:])
+			if !ok || a != [4]uint64{acc[4], acc[5], acc[6], acc[7]} {
+				return fmt.Errorf("Precomputed entry %d of window %d is not the right multiple\n", j, i)
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary decodes the output of MarshalBinary. The point must be on
+// the curve and every entry the matching multiple of it, so tables read from
+// a file others can write give the same results as freshly built ones.
+func (pc *Precomputed) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("Empty precomputed tables\n")
+	}
+	decoded := &Precomputed{window: int(data[0])}
+	switch decoded.window {
+	case 6:
+		decoded.naf6 = new([43][33 * 8]uint64)
+	case 7:
+		decoded.naf7 = new([37][65 * 8]uint64)
+	case 8:
+		decoded.naf8 = new([33][129 * 8]uint64)
+	case 9:
+		decoded.naf9 = new([29][257 * 8]uint64)
+	default:
+		return fmt.Errorf("Unsupported window %d\n", decoded.window)
+	}
+	rows := decoded.rows()
+	if len(data) != 1+len(rows)*len(rows[0])*8 {
+		return fmt.Errorf("Invalid precomputed tables length %d for window %d\n", len(data), decoded.window)
+	}
+	data = data[1:]
+	for _, row := range rows {
+		for j := range row {
+			row[j] = binary.BigEndian.Uint64(data)
+			data = data[8:]
+		}
+	}
+	x, y := decoded.Point().Back()
+	if !p256k1Curve.IsOnCurve(x, y) {
+		return fmt.Errorf("Precomputed point is not on the curve\n")
+	}
+	if err := decoded.check(); err != nil {
+		return err
+	}
+	*pc = *decoded
+	return nil
+}
+
+// NewPrecomputed implements curve.Precomputer.
+func (c *curve) NewPrecomputed(p vc.FastPoint, window int) (vc.Precomputed, error) {
+	if p.IsZero() {
+		return nil, fmt.Errorf("Cannot precompute the point at infinity\n")
+	}
+	x, y := p.Back()
+	return NewPrecomputed(x, y, window)
+}
+
+// UnmarshalPrecomputed implements curve.Precomputer.
+func (c *curve) UnmarshalPrecomputed(data []byte) (vc.Precomputed, error) {
+	pc := new(Precomputed)
+	if err := pc.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
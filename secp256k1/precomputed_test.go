@@ -0,0 +1,129 @@
+package secp256k1
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+)
+
+func TestPrecomputed(t *testing.T) {
+	InitNAFTables(9)
+	pList, kList := randomMSMInput(t, 4)
+	for window := 6; window <= 9; window++ {
+		x, y := pList[0].Back()
+		pc, err := NewPrecomputed(x, y, window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := pc.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(Precomputed)
+		if err = decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Window() != window || !decoded.Point().Equal(pList[0]) {
+			t.Fatalf("window %d: decoded tables are for another point", window)
+		}
+
+		for _, k := range append(kList, []byte{1}, p256k1Curve.params.N.Bytes()) {
+			want := p256k1Curve.NewPoint()
+			p256k1Curve.FastScalarMult(want, pList[0], k)
+			got := p256k1Curve.NewPoint()
+			decoded.ScalarMult(got, k)
+			if !samePoint(got, want) {
+				t.Fatalf("window %d: ScalarMult mismatch for %x", window, k)
+			}
+			wx, wy := want.Back()
+			gx, gy := p256k1Curve.ScalarMultByPrecomputes(k, decoded)
+			if gx.Cmp(wx) != 0 || gy.Cmp(wy) != 0 {
+				t.Fatalf("window %d: ScalarMultByPrecomputes mismatch for %x", window, k)
+			}
+		}
+
+		// u1*G + u2*P against the plain combined multiplication
+		u1, u2 := kList[1], kList[2]
+		wx, wy := p256k1Curve.CombinedMult(x, y, u1, u2)
+		gx, gy := p256k1Curve.CombinedMultByPrecomputes(u1, u2, decoded)
+		if gx.Cmp(wx) != 0 || gy.Cmp(wy) != 0 {
+			t.Fatalf("window %d: CombinedMultByPrecomputes mismatch", window)
+		}
+
+		if err = decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+			t.Fatalf("window %d: accepted truncated tables", window)
+		}
+		// entry 1 of the first window is the point itself
+		data[1+8*8+1] ^= 1
+		if err = decoded.UnmarshalBinary(data); err == nil {
+			t.Fatalf("window %d: accepted tables for a point off the curve", window)
+		}
+		// valid points in the wrong place of a later window
+		data, _ = pc.MarshalBinary()
+		rowLen := (len(data) - 1) / len(pc.rows())
+		last := data[len(data)-rowLen:]
+		tmp := make([]byte, 64)
+		copy(tmp, last[64:128])
+		copy(last[64:128], last[128:192])
+		copy(last[128:192], tmp)
+		if err = decoded.UnmarshalBinary(data); err == nil {
+			t.Fatalf("window %d: accepted tables with swapped entries", window)
+		}
+	}
+
+	x, y := pList[1].Back()
+	if _, err := NewPrecomputed(x, y, 5); err == nil {
+		t.Fatal("accepted window 5")
+	}
+	if _, err := NewPrecomputed(x, new(big.Int).Add(y, big.NewInt(1)), 9); err == nil {
+		t.Fatal("accepted a point off the curve")
+	}
+	if err := new(Precomputed).UnmarshalBinary([]byte{10}); err == nil {
+		t.Fatal("accepted window 10")
+	}
+}
+
+func BenchmarkPrecomputed(b *testing.B) {
+	InitNAFTables(9)
+	pList, kList := randomMSMInput(b, 1)
+	x, y := pList[0].Back()
+	r := p256k1Curve.NewPoint()
+	for window := 6; window <= 9; window++ {
+		pc, err := NewPrecomputed(x, y, window)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("window%d", window), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pc.ScalarMult(r, kList[0])
+			}
+		})
+	}
+	b.Run("build9", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPrecomputed(x, y, 9)
+		}
+	})
+}
+
+// TestNAF9File checks in the default build that naf9.bin, embedded by the
+// secp256k1_embed tag, still matches the tables and format of MarshalBinary.
+func TestNAF9File(t *testing.T) {
+	data, err := os.ReadFile("naf9.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := NewPrecomputed(p256k1Curve.Params().Gx, p256k1Curve.Params().Gy, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := pc.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatal("naf9.bin is stale, run go generate")
+	}
+}
@@ -0,0 +1,81 @@
+package secp256k1
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// SignRecoverable is SignHash that also returns the recovery id v, from 0 to
+// 3, which lets RecoverPublicKey find the public key from the signature. Bit 0
+// of v is the parity of the y coordinate of R, bit 1 is set when the x
+// coordinate of R was not below N.
+func SignRecoverable(rand io.Reader, pri *ecdsa.PrivateKey, digest []byte) (r, s *big.Int, v byte, err error) {
+	return signHash(rand, pri, digest)
+}
+
+// MarshalRecoverableSig encodes a signature as r || s || v, 65 bytes.
+func MarshalRecoverableSig(r, s *big.Int, v byte) []byte {
+	sig := make([]byte, 65)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = v
+	return sig
+}
+
+// UnmarshalRecoverableSig decodes the output of MarshalRecoverableSig.
+func UnmarshalRecoverableSig(sig []byte) (r, s *big.Int, v byte, err error) {
+	if len(sig) != 65 {
+		return nil, nil, 0, fmt.Errorf("Recoverable signature must be 65 bytes\n")
+	}
+	if sig[64] > 3 {
+		return nil, nil, 0, fmt.Errorf("Invalid recovery id %d\n", sig[64])
+	}
+	r, s, err = UnmarshalCompactSig(sig[:64])
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return r, s, sig[64], nil
+}
+
+// RecoverPublicKey returns the public key that signed digest, given the
+// 65-byte output of MarshalRecoverableSig. It computes r^-1 * (s*R - e*G)
+// with one CombinedMult.
+func RecoverPublicKey(digest, sig []byte) (*ecdsa.PublicKey, error) {
+	r, s, v, err := UnmarshalRecoverableSig(sig)
+	if err != nil {
+		return nil, err
+	}
+	params := p256k1Curve.params
+	N := params.N
+
+	rx := new(big.Int).Set(r)
+	if v&2 != 0 {
+		rx.Add(rx, N)
+		if rx.Cmp(params.P) >= 0 {
+			return nil, fmt.Errorf("Invalid recovery id %d\n", v)
+		}
+	}
+	var buf [33]byte
+	buf[0] = 0x02 | v&1
+	rx.FillBytes(buf[1:])
+	R := p256k1Curve.NewPoint().(*Point)
+	if err = R.UnmarshalCompressed(buf[:]); err != nil {
+		return nil, err
+	}
+	x, y := R.Back()
+
+	rInv := new(big.Int).ModInverse(r, N)
+	u1 := hashToInt(digest)
+	u1.Neg(u1)
+	u1.Mul(u1, rInv)
+	u1.Mod(u1, N)
+	u2 := rInv.Mul(rInv, s)
+	u2.Mod(u2, N)
+	qx, qy := p256k1Curve.CombinedMult(x, y, u1.Bytes(), u2.Bytes())
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, fmt.Errorf("Recovered public key is infinity\n")
+	}
+	return &ecdsa.PublicKey{Curve: p256k1Curve, X: qx, Y: qy}, nil
+}
@@ -0,0 +1,181 @@
+package secp256k1
+
+import (
+	"math/big"
+	"math/bits"
+	vc "volley/curve"
+)
+
+// ordN is the group order in little-endian limbs.
+var ordN = [4]uint64{0xbfd25e8cd0364141, 0xbaaedce6af48a03b, 0xfffffffffffffffe, 0xffffffffffffffff}
+
+// ordAdd sets r = a + b mod N for a, b < N.
+func ordAdd(r, a, b *[4]uint64) {
+	var carry, borrow uint64
+	var t, s [4]uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	s[0], borrow = bits.Sub64(t[0], ordN[0], 0)
+	s[1], borrow = bits.Sub64(t[1], ordN[1], borrow)
+	s[2], borrow = bits.Sub64(t[2], ordN[2], borrow)
+	s[3], borrow = bits.Sub64(t[3], ordN[3], borrow)
+	// keep t only if the sum didn't overflow and subtracting N borrowed
+	mask := -(borrow &^ carry)
+	r[0] = t[0]&mask | s[0]&^mask
+	r[1] = t[1]&mask | s[1]&^mask
+	r[2] = t[2]&mask | s[2]&^mask
+	r[3] = t[3]&mask | s[3]&^mask
+}
+
+// ordSub sets r = a - b mod N for a, b < N.
+func ordSub(r, a, b *[4]uint64) {
+	var borrow, carry uint64
+	var t [4]uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	mask := -borrow
+	r[0], carry = bits.Add64(t[0], ordN[0]&mask, 0)
+	r[1], carry = bits.Add64(t[1], ordN[1]&mask, carry)
+	r[2], carry = bits.Add64(t[2], ordN[2]&mask, carry)
+	r[3], _ = bits.Add64(t[3], ordN[3]&mask, carry)
+}
+
+// ordReduceOnce subtracts N from a if a >= N. Any 256-bit value is below 2N.
+func ordReduceOnce(a *[4]uint64) {
+	var borrow uint64
+	var s [4]uint64
+	s[0], borrow = bits.Sub64(a[0], ordN[0], 0)
+	s[1], borrow = bits.Sub64(a[1], ordN[1], borrow)
+	s[2], borrow = bits.Sub64(a[2], ordN[2], borrow)
+	s[3], borrow = bits.Sub64(a[3], ordN[3], borrow)
+	mask := -borrow
+	a[0] = a[0]&mask | s[0]&^mask
+	a[1] = a[1]&mask | s[1]&^mask
+	a[2] = a[2]&mask | s[2]&^mask
+	a[3] = a[3]&mask | s[3]&^mask
+}
+
+// ordInverse sets r to the Montgomery inverse of the Montgomery value a, that
+// is (aR)^-1 * R^2 = a^-1 * R. a must not be zero.
+func ordInverse(r, a *[4]uint64) {
+	var k uint64
+	p256k1OrdMontInversePhase1(r[:], a[:], &k)
+	if k > 256 {
+		k = 512 - k
+		var ex [4]uint64
+		ex[k/64] = 1 << (k % 64)
+		p256k1OrdMul(r[:], r[:], ex[:])
+	}
+	// r is now (aR)^-1 * R = a^-1, move it back into the Montgomery domain
+	p256k1OrdMul(r[:], r[:], ro)
+}
+
+func (bn *Bn) SetInt64(v int64) vc.FastBn {
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-v)
+	}
+	bn.data = [4]uint64{u, 0, 0, 0}
+	p256k1OrdMul(bn.data[:], bn.data[:], ro)
+	if v < 0 {
+		ordSub(&bn.data, &[4]uint64{}, &bn.data)
+	}
+	return bn
+}
+
+// SetBytes sets bn to the big-endian value in, reduced modulo N.
+func (bn *Bn) SetBytes(in []byte) vc.FastBn {
+	if len(in) > 32 {
+		bn.From(new(big.Int).SetBytes(in))
+		return bn
+	}
+	var buf [32]byte
+	copy(buf[32-len(in):], in)
+	p256k1BigToLittle(bn.data[:], buf[:])
+	ordReduceOnce(&bn.data)
+	p256k1OrdMul(bn.data[:], bn.data[:], ro)
+	return bn
+}
+
+// Big returns the value of bn as a new big.Int.
+func (bn *Bn) Big() *big.Int {
+	return new(big.Int).SetBytes(bn.Back())
+}
+
+func (bn *Bn) Add(a, b vc.FastBn) vc.FastBn {
+	ordAdd(&bn.data, &a.(*Bn).data, &b.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Sub(a, b vc.FastBn) vc.FastBn {
+	ordSub(&bn.data, &a.(*Bn).data, &b.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Neg(a vc.FastBn) vc.FastBn {
+	ordSub(&bn.data, &[4]uint64{}, &a.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Mul(a, b vc.FastBn) vc.FastBn {
+	p256k1OrdMul(bn.data[:], a.(*Bn).data[:], b.(*Bn).data[:])
+	return bn
+}
+
+func (bn *Bn) Square(a vc.FastBn) vc.FastBn {
+	p256k1OrdSqr(bn.data[:], a.(*Bn).data[:], 1)
+	return bn
+}
+
+// Inverse sets bn to a^-1 mod N. The inverse of zero is zero.
+func (bn *Bn) Inverse(a vc.FastBn) vc.FastBn {
+	in := &a.(*Bn).data
+	if scalarIsZero(in[:]) {
+		bn.data = [4]uint64{}
+		return bn
+	}
+	ordInverse(&bn.data, in)
+	return bn
+}
+
+func (bn *Bn) IsZero() bool {
+	return scalarIsZero(bn.data[:])
+}
+
+func (bn *Bn) Equal(b vc.FastBn) bool {
+	d := &b.(*Bn).data
+	return (bn.data[0]^d[0])|(bn.data[1]^d[1])|(bn.data[2]^d[2])|(bn.data[3]^d[3]) == 0
+}
+
+// BatchInverse replaces every element of list by its inverse using
+// Montgomery's trick, at the cost of one inversion and 3(n-1)
+// multiplications. Zero elements are left as zero.
+func (c *curve) BatchInverse(list []vc.FastBn) {
+	if len(list) == 0 {
+		return
+	}
+	prefix := make([][4]uint64, len(list))
+	acc := [4]uint64{0x402da1732fc9bebf, 0x4551231950b75fc4, 0x1, 0}
+	for i, b := range list {
+		prefix[i] = acc
+		d := &b.(*Bn).data
+		if !scalarIsZero(d[:]) {
+			p256k1OrdMul(acc[:], acc[:], d[:])
+		}
+	}
+	ordInverse(&acc, &acc)
+	for i := len(list) - 1; i >= 0; i-- {
+		d := &list[i].(*Bn).data
+		if scalarIsZero(d[:]) {
+			continue
+		}
+		var inv [4]uint64
+		p256k1OrdMul(inv[:], acc[:], prefix[i][:])
+		p256k1OrdMul(acc[:], acc[:], d[:])
+		*d = inv
+	}
+}
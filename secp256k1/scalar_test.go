@@ -0,0 +1,117 @@
+package secp256k1
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+	vc "volley/curve"
+)
+
+func randomScalar(t testing.TB) *big.Int {
+	k, err := rand.Int(rand.Reader, p256k1Curve.Params().N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func checkBn(t *testing.T, op string, got vc.FastBn, want *big.Int) {
+	t.Helper()
+	want = new(big.Int).Mod(want, p256k1Curve.Params().N)
+	if got.Big().Cmp(want) != 0 {
+		t.Fatalf("%s: got %x, want %x", op, got.Big(), want)
+	}
+}
+
+func TestScalarArithmetic(t *testing.T) {
+	N := p256k1Curve.Params().N
+	nMinus1 := new(big.Int).Sub(N, big.NewInt(1))
+	edge := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), nMinus1, new(big.Int).Rsh(N, 1)}
+
+	for i := 0; i < 2000; i++ {
+		var a, b *big.Int
+		if i < len(edge)*len(edge) {
+			a, b = edge[i/len(edge)], edge[i%len(edge)]
+		} else {
+			a, b = randomScalar(t), randomScalar(t)
+		}
+		x := p256k1Curve.NewBn()
+		x.From(a)
+		y := p256k1Curve.NewBn()
+		y.From(b)
+		r := p256k1Curve.NewBn()
+
+		checkBn(t, "Add", r.Add(x, y), new(big.Int).Add(a, b))
+		checkBn(t, "Sub", r.Sub(x, y), new(big.Int).Sub(a, b))
+		checkBn(t, "Neg", r.Neg(x), new(big.Int).Neg(a))
+		checkBn(t, "Mul", r.Mul(x, y), new(big.Int).Mul(a, b))
+		checkBn(t, "Square", r.Square(x), new(big.Int).Mul(a, a))
+		if a.Sign() != 0 {
+			checkBn(t, "Inverse", r.Inverse(x), new(big.Int).ModInverse(a, N))
+		}
+		checkBn(t, "SetBytes", r.SetBytes(a.Bytes()), a)
+		if x.Equal(y) != (a.Cmp(b) == 0) || x.IsZero() != (a.Sign() == 0) {
+			t.Fatal("Equal/IsZero mismatch")
+		}
+
+		// in-place operation
+		r.CopyFrom(x)
+		checkBn(t, "Add in place", r.Add(r, r), new(big.Int).Lsh(a, 1))
+	}
+
+	r := p256k1Curve.NewBn()
+	checkBn(t, "SetInt64", r.SetInt64(-5), big.NewInt(-5))
+	checkBn(t, "SetInt64", r.SetInt64(65536), big.NewInt(65536))
+	over := new(big.Int).Add(N, big.NewInt(3))
+	checkBn(t, "SetBytes over N", r.SetBytes(over.Bytes()), over)
+	wide := make([]byte, 40)
+	rand.Read(wide)
+	checkBn(t, "SetBytes wide", r.SetBytes(wide), new(big.Int).SetBytes(wide))
+}
+
+func TestBatchInverse(t *testing.T) {
+	N := p256k1Curve.Params().N
+	values := make([]*big.Int, 100)
+	list := make([]vc.FastBn, len(values))
+	for i := range values {
+		values[i] = randomScalar(t)
+		if i%17 == 0 {
+			values[i].SetInt64(0)
+		}
+		list[i] = p256k1Curve.NewBn()
+		list[i].From(values[i])
+	}
+	p256k1Curve.BatchInverse(list)
+	for i, v := range values {
+		if v.Sign() == 0 {
+			if !list[i].IsZero() {
+				t.Fatal("inverse of zero is not zero")
+			}
+			continue
+		}
+		checkBn(t, "BatchInverse", list[i], new(big.Int).ModInverse(v, N))
+	}
+}
+
+func BenchmarkScalarMulBig(b *testing.B) {
+	N := p256k1Curve.Params().N
+	x, y := randomScalar(b), randomScalar(b)
+	r := new(big.Int)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Mul(x, y)
+		r.Mod(r, N)
+	}
+}
+
+func BenchmarkScalarMul(b *testing.B) {
+	x := p256k1Curve.NewBn()
+	x.From(randomScalar(b))
+	y := p256k1Curve.NewBn()
+	y.From(randomScalar(b))
+	r := p256k1Curve.NewBn()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Mul(x, y)
+	}
+}
@@ -111,3 +111,160 @@ func TestSignAndVerifyECDSAOnPrecomputes(t *testing.T) {
 		}
 	}
 }
+
+// Widely used secp256k1 RFC 6979 vectors with SHA-256, as found in
+// python-ecdsa and trezor-crypto.
+func TestSignHashRFC6979(t *testing.T) {
+	curve := secp256k1.Curve()
+	N := curve.Params().N
+	for _, tt := range []struct {
+		d, msg, k string
+	}{
+		{"1", "Satoshi Nakamoto", "8F8A276C19F4149656B280621E358CCE24F5F52542772691EE69063B74F15D15"},
+		{"1", "All those moments will be lost in time, like tears in rain. Time to die...",
+			"38AA22D72376B4DBC472E06C3BA403EE0A394DA63FC58D88686C611ABA98D6B3"},
+		{"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364140", "Satoshi Nakamoto",
+			"33A19B60E25FB6F4435AF53A3D42D493644827367E6453928554F43E49AA6F90"},
+		{"F8B8AF8CE3C7CCA5E300D33939540C10D45CE001B8F252BFBC57BA0342904181", "Alan Turing",
+			"525A82B70E67874398067543FD84C83D30C175FDC45FDEEE082FE13B1D7CFDF1"},
+	} {
+		pri := new(ecdsa.PrivateKey)
+		pri.Curve = curve
+		pri.D, _ = new(big.Int).SetString(tt.d, 16)
+		pri.X, pri.Y = curve.ScalarBaseMult(pri.D.Bytes())
+		k, _ := new(big.Int).SetString(tt.k, 16)
+		wantR, _ := curve.ScalarBaseMult(k.Bytes())
+		wantR.Mod(wantR, N)
+
+		digest := sha256.Sum256([]byte(tt.msg))
+		r, s, err := secp256k1.SignHash(nil, pri, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Cmp(wantR) != 0 {
+			t.Errorf("%q: nonce is not the RFC 6979 one", tt.msg)
+		}
+		if !secp256k1.VerifyHash(&pri.PublicKey, digest[:], r, s, nil) {
+			t.Errorf("%q: not verified", tt.msg)
+		}
+
+		// hedged signatures differ every time but still verify
+		r1, s1, err := secp256k1.SignHash(rand.Reader, pri, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r1.Cmp(r) == 0 || !secp256k1.VerifyHash(&pri.PublicKey, digest[:], r1, s1, nil) {
+			t.Errorf("%q: bad hedged signature", tt.msg)
+		}
+	}
+}
+
+func TestUnmarshalSigStrict(t *testing.T) {
+	r, s := big.NewInt(0x7f), big.NewInt(0x80)
+	sig := secp256k1.MarshalSig(r, s)
+	// r is a single byte, s needs a sign byte
+	want := []byte{0x30, 0x07, 0x02, 0x01, 0x7f, 0x02, 0x02, 0x00, 0x80}
+	if string(sig) != string(want) {
+		t.Fatalf("MarshalSig = %x, want %x", sig, want)
+	}
+	if r1, s1, err := secp256k1.UnmarshalSig(sig); err != nil || r1.Cmp(r) != 0 || s1.Cmp(s) != 0 {
+		t.Fatalf("round trip failed: %v", err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":        {},
+		"short":        {0x30, 0x06, 0x02, 0x01, 0x7f, 0x02, 0x01},
+		"not sequence": {0x31, 0x07, 0x02, 0x01, 0x7f, 0x02, 0x02, 0x00, 0x80},
+		"long length":  {0x30, 0x08, 0x02, 0x01, 0x7f, 0x02, 0x02, 0x00, 0x80},
+		"trailing":     {0x30, 0x07, 0x02, 0x01, 0x7f, 0x02, 0x02, 0x00, 0x80, 0x00},
+		"r padded":     {0x30, 0x08, 0x02, 0x02, 0x00, 0x7f, 0x02, 0x02, 0x00, 0x80},
+		"s negative":   {0x30, 0x06, 0x02, 0x01, 0x7f, 0x02, 0x01, 0x80},
+		"r empty":      {0x30, 0x06, 0x02, 0x00, 0x02, 0x02, 0x00, 0x80},
+		"r too long":   {0x30, 0x07, 0x02, 0x7f, 0x7f, 0x02, 0x02, 0x00, 0x80},
+		"s not int":    {0x30, 0x07, 0x02, 0x01, 0x7f, 0x03, 0x02, 0x00, 0x80},
+		"s overrun":    {0x30, 0x07, 0x02, 0x01, 0x7f, 0x02, 0x03, 0x00, 0x80},
+	} {
+		if _, _, err := secp256k1.UnmarshalSig(bad); err == nil {
+			t.Errorf("%s: accepted %x", name, bad)
+		}
+	}
+}
+
+func TestCompactSigAndLowS(t *testing.T) {
+	curve := secp256k1.Curve()
+	N := curve.Params().N
+	pri, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		digest := make([]byte, 32)
+		rand.Read(digest)
+		r, s, err := secp256k1.SignHash(rand.Reader, pri, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !secp256k1.IsLowS(s) || !secp256k1.VerifyHashLowS(&pri.PublicKey, digest, r, s, nil) {
+			t.Fatal("SignHash returned a high s")
+		}
+		high := new(big.Int).Sub(N, s)
+		if !secp256k1.VerifyHash(&pri.PublicKey, digest, r, high, nil) {
+			t.Fatal("N - s does not verify")
+		}
+		if secp256k1.VerifyHashLowS(&pri.PublicKey, digest, r, high, nil) {
+			t.Fatal("VerifyHashLowS accepted a high s")
+		}
+		if secp256k1.NormalizeS(high).Cmp(s) != 0 {
+			t.Fatal("NormalizeS did not undo N - s")
+		}
+
+		compact := secp256k1.MarshalCompactSig(r, s)
+		r1, s1, err := secp256k1.UnmarshalCompactSig(compact)
+		if err != nil || r1.Cmp(r) != 0 || s1.Cmp(s) != 0 {
+			t.Fatalf("compact round trip failed: %v", err)
+		}
+	}
+	if _, _, err := secp256k1.UnmarshalCompactSig(secp256k1.MarshalCompactSig(big.NewInt(1), N)); err == nil {
+		t.Fatal("UnmarshalCompactSig accepted s = N")
+	}
+	if _, _, err := secp256k1.UnmarshalCompactSig(make([]byte, 63)); err == nil {
+		t.Fatal("UnmarshalCompactSig accepted 63 bytes")
+	}
+}
+
+func TestRecoverPublicKey(t *testing.T) {
+	secp256k1.InitNAFTables(9)
+	curve := secp256k1.Curve()
+	for i := 0; i < 200; i++ {
+		pri, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digest := make([]byte, 32)
+		rand.Read(digest)
+		r, s, v, err := secp256k1.SignRecoverable(rand.Reader, pri, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !secp256k1.VerifyHashLowS(&pri.PublicKey, digest, r, s, nil) {
+			t.Fatal("Not verified")
+		}
+		sig := secp256k1.MarshalRecoverableSig(r, s, v)
+		pub, err := secp256k1.RecoverPublicKey(digest, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.X.Cmp(pri.X) != 0 || pub.Y.Cmp(pri.Y) != 0 {
+			t.Fatalf("recovered the wrong key with v = %d", v)
+		}
+
+		// the other parity recovers some other key
+		sig[64] ^= 1
+		if pub, err = secp256k1.RecoverPublicKey(digest, sig); err == nil && pub.X.Cmp(pri.X) == 0 {
+			t.Fatal("recovered the key with a wrong v")
+		}
+	}
+	if _, err := secp256k1.RecoverPublicKey(make([]byte, 32), make([]byte, 65)); err == nil {
+		t.Fatal("recovered from a zero signature")
+	}
+}
@@ -65,37 +65,14 @@ func (c *curve) CombinedMult(bigX, bigY *big.Int, baseScalar, scalar []byte) (x,
 
 }
 
-func (c *curve) CombinedMultByPrecomputes(baseScalar, scalar []byte, precomputes interface{}) (x,
-	y *big.Int) {
+func (c *curve) CombinedMultByPrecomputes(baseScalar, scalar []byte, precomputes *Precomputed) (x, y *big.Int) {
 	scalarReversed := make([]uint64, 4)
 	var r1, r2 point
 	sm2CurveGetScalar(scalarReversed, baseScalar)
 	r1IsInfinity := scalarIsZero(scalarReversed)
 	p256k1BaseMul(&r1, scalarReversed)
 
-	sm2CurveGetScalar(scalarReversed, scalar)
-	r2IsInfinity := scalarIsZero(scalarReversed)
-
-	supported := false
-	if tables, ok := precomputes.(*[43][33 * 8]uint64); ok {
-		r2.p256k1ScalarMultByPrecomputesNAF6(scalarReversed, tables)
-		supported = true
-	}
-	if tables, ok := precomputes.(*[37][65 * 8]uint64); ok {
-		r2.p256k1ScalarMultByPrecomputesNAF7(scalarReversed, tables)
-		supported = true
-	}
-	if tables, ok := precomputes.(*[33][129 * 8]uint64); ok {
-		r2.p256k1ScalarMultByPrecomputesNAF8(scalarReversed, tables)
-		supported = true
-	}
-	if tables, ok := precomputes.(*[29][257 * 8]uint64); ok {
-		r2.p256k1ScalarMultByPrecomputesNAF9(scalarReversed, tables)
-		supported = true
-	}
-	if !supported {
-		panic("Unsupported precomputes")
-	}
+	r2IsInfinity := precomputes.mult(&r2, scalar)
 	var sum, double point
 	if !r1IsInfinity && !r2IsInfinity {
 		addSign := p256k1PointAddAsm(sum.xyz[:], r1.xyz[:], r2.xyz[:])
@@ -112,29 +89,12 @@ func (c *curve) CombinedMultByPrecomputes(baseScalar, scalar []byte, precomputes
 
 }
 
-func (c *curve) ComputePrecomputesForPoint(x, y *big.Int) interface{} {
-	var p point
-	fromBig(p.xyz[0:4], x)
-	fromBig(p.xyz[4:8], y)
-	p256k1Mul(p.xyz[0:4], p.xyz[0:4], rr[:])
-	p256k1Mul(p.xyz[4:8], p.xyz[4:8], rr[:])
-
-	p.xyz[8] = 0x1000003d1
-	p.xyz[9] = 0x0
-	p.xyz[10] = 0x0
-	p.xyz[11] = 0x0
-
-	if p256k1NAF6Tables != nil {
-		return p256k1Curve.generateNAF6Tables(&p)
-	}
-	if p256k1NAF7Tables != nil {
-		return p256k1Curve.generateNAF7Tables(&p)
-	}
-	if p256k1NAF8Tables != nil {
-		return p256k1Curve.generateNAF8Tables(&p)
-	}
-	if p256k1NAF9Tables != nil {
-		return p256k1Curve.generateNAF9Tables(&p)
+// ComputePrecomputesForPoint builds the tables of (x, y) with the window of
+// the base point tables. It returns nil if the point is not on the curve.
+func (c *curve) ComputePrecomputesForPoint(x, y *big.Int) *Precomputed {
+	pc, err := NewPrecomputed(x, y, BaseWindow())
+	if err != nil {
+		return nil
 	}
-	return nil
+	return pc
 }
@@ -0,0 +1,154 @@
+package taproot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Signature hash types of BIP-341. SigHashDefault commits to the same data
+// as SigHashAll.
+const (
+	SigHashDefault      byte = 0x00
+	SigHashAll          byte = 0x01
+	SigHashNone         byte = 0x02
+	SigHashSingle       byte = 0x03
+	SigHashAnyoneCanPay byte = 0x80
+)
+
+// OutPoint names an output of an earlier transaction. Hash is the txid in
+// the byte order of the serialization, the reverse of the usual hex form.
+type OutPoint struct {
+	Hash  [32]byte
+	Index uint32
+}
+
+type Input struct {
+	PrevOut  OutPoint
+	Sequence uint32
+}
+
+type Output struct {
+	Value  int64
+	Script []byte
+}
+
+// Transaction holds the fields of a transaction the signature hash commits
+// to. Witnesses and input scripts are left out, they are not signed.
+type Transaction struct {
+	Version  int32
+	LockTime uint32
+	Inputs   []Input
+	Outputs  []Output
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+// writeScript writes script with its CompactSize length.
+func writeScript(buf *bytes.Buffer, script []byte) {
+	n := len(script)
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(0xfd)
+		buf.WriteByte(byte(n))
+		buf.WriteByte(byte(n >> 8))
+	default:
+		buf.WriteByte(0xfe)
+		writeUint32(buf, uint32(n))
+	}
+	buf.Write(script)
+}
+
+func writeOutPoint(buf *bytes.Buffer, o OutPoint) {
+	buf.Write(o.Hash[:])
+	writeUint32(buf, o.Index)
+}
+
+func writeOutput(buf *bytes.Buffer, o Output) {
+	writeUint64(buf, uint64(o.Value))
+	writeScript(buf, o.Script)
+}
+
+// KeySpendSigHash returns the BIP-341 signature hash for spending input
+// index of tx with the key path. prevouts are the outputs spent by the
+// inputs, in the same order. No annex is supported.
+func KeySpendSigHash(tx *Transaction, prevouts []Output, index int, hashType byte) ([32]byte, error) {
+	var digest [32]byte
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay, SigHashNone | SigHashAnyoneCanPay, SigHashSingle | SigHashAnyoneCanPay:
+	default:
+		return digest, fmt.Errorf("Invalid signature hash type 0x%02x\n", hashType)
+	}
+	if len(prevouts) != len(tx.Inputs) {
+		return digest, fmt.Errorf("%d spent outputs for %d inputs\n", len(prevouts), len(tx.Inputs))
+	}
+	if index < 0 || index >= len(tx.Inputs) {
+		return digest, fmt.Errorf("Input %d out of range\n", index)
+	}
+	anyoneCanPay := hashType&SigHashAnyoneCanPay != 0
+	outputType := hashType & 3
+	if outputType == SigHashSingle && index >= len(tx.Outputs) {
+		return digest, fmt.Errorf("No output %d for SIGHASH_SINGLE\n", index)
+	}
+
+	var msg bytes.Buffer
+	// epoch
+	msg.WriteByte(0)
+	msg.WriteByte(hashType)
+	writeUint32(&msg, uint32(tx.Version))
+	writeUint32(&msg, tx.LockTime)
+
+	if !anyoneCanPay {
+		var outpoints, amounts, scripts, sequences bytes.Buffer
+		for i, in := range tx.Inputs {
+			writeOutPoint(&outpoints, in.PrevOut)
+			writeUint64(&amounts, uint64(prevouts[i].Value))
+			writeScript(&scripts, prevouts[i].Script)
+			writeUint32(&sequences, in.Sequence)
+		}
+		for _, b := range []*bytes.Buffer{&outpoints, &amounts, &scripts, &sequences} {
+			sum := sha256.Sum256(b.Bytes())
+			msg.Write(sum[:])
+		}
+	}
+	if outputType != SigHashNone && outputType != SigHashSingle {
+		var outputs bytes.Buffer
+		for _, out := range tx.Outputs {
+			writeOutput(&outputs, out)
+		}
+		sum := sha256.Sum256(outputs.Bytes())
+		msg.Write(sum[:])
+	}
+
+	// spend type: key path, no annex
+	msg.WriteByte(0)
+	if anyoneCanPay {
+		writeOutPoint(&msg, tx.Inputs[index].PrevOut)
+		writeOutput(&msg, prevouts[index])
+		writeUint32(&msg, tx.Inputs[index].Sequence)
+	} else {
+		writeUint32(&msg, uint32(index))
+	}
+
+	if outputType == SigHashSingle {
+		var output bytes.Buffer
+		writeOutput(&output, tx.Outputs[index])
+		sum := sha256.Sum256(output.Bytes())
+		msg.Write(sum[:])
+	}
+	return TaggedHash("TapSighash", msg.Bytes()), nil
+}
@@ -0,0 +1,188 @@
+package taproot_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"volley/secp256k1/taproot"
+)
+
+func sighashTx() (*taproot.Transaction, []taproot.Output) {
+	script := taproot.OutputScript([32]byte{1})
+	tx := &taproot.Transaction{
+		Version:  2,
+		LockTime: 0,
+		Inputs: []taproot.Input{
+			{PrevOut: taproot.OutPoint{Hash: [32]byte{0xaa}, Index: 0}, Sequence: 0xffffffff},
+			{PrevOut: taproot.OutPoint{Hash: [32]byte{0xbb}, Index: 3}, Sequence: 0xfffffffd},
+		},
+		Outputs: []taproot.Output{
+			{Value: 50000, Script: script},
+			{Value: 20000, Script: script},
+		},
+	}
+	prevouts := []taproot.Output{
+		{Value: 40000, Script: script},
+		{Value: 35000, Script: script},
+	}
+	return tx, prevouts
+}
+
+func TestKeySpendSigHash(t *testing.T) {
+	hashTypes := []byte{
+		taproot.SigHashDefault, taproot.SigHashAll, taproot.SigHashNone, taproot.SigHashSingle,
+		taproot.SigHashAll | taproot.SigHashAnyoneCanPay, taproot.SigHashNone | taproot.SigHashAnyoneCanPay,
+		taproot.SigHashSingle | taproot.SigHashAnyoneCanPay,
+	}
+	seen := make(map[[32]byte]bool)
+	for _, hashType := range hashTypes {
+		tx, prevouts := sighashTx()
+		base, err := taproot.KeySpendSigHash(tx, prevouts, 0, hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the hash type is committed, so every type gives another hash
+		if seen[base] {
+			t.Fatalf("hash type 0x%02x repeats a hash", hashType)
+		}
+		seen[base] = true
+
+		anyoneCanPay := hashType&taproot.SigHashAnyoneCanPay != 0
+		outputType := hashType & 3
+		changes := []struct {
+			name      string
+			change    func(tx *taproot.Transaction, prevouts []taproot.Output)
+			committed bool
+		}{
+			{"version", func(tx *taproot.Transaction, _ []taproot.Output) { tx.Version = 1 }, true},
+			{"lock time", func(tx *taproot.Transaction, _ []taproot.Output) { tx.LockTime = 1 }, true},
+			{"own amount", func(_ *taproot.Transaction, p []taproot.Output) { p[0].Value++ }, true},
+			{"own sequence", func(tx *taproot.Transaction, _ []taproot.Output) { tx.Inputs[0].Sequence = 0 }, true},
+			{"other input", func(tx *taproot.Transaction, _ []taproot.Output) { tx.Inputs[1].PrevOut.Index = 4 },
+				!anyoneCanPay},
+			{"other amount", func(_ *taproot.Transaction, p []taproot.Output) { p[1].Value++ }, !anyoneCanPay},
+			{"own output", func(tx *taproot.Transaction, _ []taproot.Output) { tx.Outputs[0].Value++ },
+				outputType != taproot.SigHashNone},
+			{"other output", func(tx *taproot.Transaction, _ []taproot.Output) { tx.Outputs[1].Value++ },
+				outputType != taproot.SigHashNone && outputType != taproot.SigHashSingle},
+		}
+		for _, c := range changes {
+			tx, prevouts := sighashTx()
+			c.change(tx, prevouts)
+			digest, err := taproot.KeySpendSigHash(tx, prevouts, 0, hashType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (digest != base) != c.committed {
+				t.Errorf("hash type 0x%02x: %s committed is %v, want %v", hashType, c.name, digest != base,
+					c.committed)
+			}
+		}
+	}
+
+	tx, prevouts := sighashTx()
+	if _, err := taproot.KeySpendSigHash(tx, prevouts, 0, 0x04); err == nil {
+		t.Fatal("accepted an invalid hash type")
+	}
+	if _, err := taproot.KeySpendSigHash(tx, prevouts[:1], 0, taproot.SigHashAll); err == nil {
+		t.Fatal("accepted missing spent outputs")
+	}
+	tx.Outputs = tx.Outputs[:1]
+	if _, err := taproot.KeySpendSigHash(tx, prevouts, 1, taproot.SigHashSingle); err == nil {
+		t.Fatal("SIGHASH_SINGLE without a matching output")
+	}
+}
+
+// parseTx decodes a transaction without witnesses in the network format.
+func parseTx(t *testing.T, s string) *taproot.Transaction {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func(n int) []byte {
+		if len(data) < n {
+			t.Fatal("truncated transaction")
+		}
+		b := data[:n]
+		data = data[n:]
+		return b
+	}
+	// all counts and scripts of the vector are below 0xfd
+	compactSize := func() int { return int(next(1)[0]) }
+
+	tx := &taproot.Transaction{Version: int32(binary.LittleEndian.Uint32(next(4)))}
+	tx.Inputs = make([]taproot.Input, compactSize())
+	for i := range tx.Inputs {
+		copy(tx.Inputs[i].PrevOut.Hash[:], next(32))
+		tx.Inputs[i].PrevOut.Index = binary.LittleEndian.Uint32(next(4))
+		next(compactSize())
+		tx.Inputs[i].Sequence = binary.LittleEndian.Uint32(next(4))
+	}
+	tx.Outputs = make([]taproot.Output, compactSize())
+	for i := range tx.Outputs {
+		tx.Outputs[i].Value = int64(binary.LittleEndian.Uint64(next(8)))
+		tx.Outputs[i].Script = next(compactSize())
+	}
+	tx.LockTime = binary.LittleEndian.Uint32(next(4))
+	if len(data) != 0 {
+		t.Fatal("trailing transaction data")
+	}
+	return tx
+}
+
+// From the keyPathSpending test vectors of BIP-341.
+func TestKeySpendSigHashVectors(t *testing.T) {
+	tx := parseTx(t, "02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000"+
+		"d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d2"+
+		"8eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310"+
+		"fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c00000000"+
+		"00feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c0"+
+		"9c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b69"+
+		"4bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82a"+
+		"f10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb000000002"+
+		"0ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d")
+	spent := []struct {
+		script string
+		amount int64
+	}{
+		{"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", 420000000},
+		{"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", 462000000},
+		{"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac", 294000000},
+		{"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e", 504000000},
+		{"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605", 630000000},
+		{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc", 378000000},
+		{"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831", 672000000},
+		{"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5", 546000000},
+		{"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220", 588000000},
+	}
+	prevouts := make([]taproot.Output, len(spent))
+	for i, s := range spent {
+		script, err := hex.DecodeString(s.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prevouts[i] = taproot.Output{Value: s.amount, Script: script}
+	}
+	vectors := []struct {
+		index    int
+		hashType byte
+		sigHash  string
+	}{
+		{0, 0x03, "2514a6272f85cfa0f45eb907fcb0d121b808ed37c6ea160a5a9046ed5526d555"},
+		{1, 0x83, "325a644af47e8a5a2591cda0ab0723978537318f10e6a63d4eed783b96a71a4d"},
+		{3, 0x01, "bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669"},
+		{4, 0x00, "4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef"},
+		{6, 0x02, "15f25c298eb5cdc7eb1d638dd2d45c97c4c59dcaec6679cfc16ad84f30876b85"},
+		{7, 0x82, "cd292de50313804dabe4685e83f923d2969577191a3e1d2882220dca88cbeb10"},
+		{8, 0x81, "cccb739eca6c13a8a89e6e5cd317ffe55669bbda23f2fd37b0f18755e008edd2"},
+	}
+	for _, v := range vectors {
+		digest, err := taproot.KeySpendSigHash(tx, prevouts, v.index, v.hashType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != decodeHex32(t, v.sigHash) {
+			t.Errorf("input %d, hash type 0x%02x: got %x", v.index, v.hashType, digest)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package taproot implements the BIP-341 pieces needed to spend a Taproot
+// output with the key path: tweaking keys with the TapTweak tagged hash,
+// x-only keys, and the signature hash of a spending transaction.
+package taproot
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"volley/secp256k1"
+)
+
+// TaggedHash is SHA256(SHA256(tag) || SHA256(tag) || msg...) as in BIP-340.
+func TaggedHash(tag string, msg ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msg {
+		h.Write(m)
+	}
+	var digest [32]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// XOnly returns the 32-byte x coordinate of pub, the form of keys in
+// Taproot outputs.
+func XOnly(pub *secp256k1.PublicKey) [32]byte {
+	var x [32]byte
+	pub.X.FillBytes(x[:])
+	return x
+}
+
+// LiftX returns the point with x coordinate x and even y.
+func LiftX(x [32]byte) (*secp256k1.PublicKey, error) {
+	var compressed [33]byte
+	compressed[0] = 0x02
+	copy(compressed[1:], x[:])
+	pub, err := secp256k1.UnmarshalPublicKey(compressed[:])
+	if err != nil {
+		return nil, err
+	}
+	return &secp256k1.PublicKey{X: pub.X, Y: pub.Y}, nil
+}
+
+// tweak returns t = H_TapTweak(x || merkleRoot), failing if it is not below
+// N. merkleRoot is empty for an output without scripts.
+func tweak(x [32]byte, merkleRoot []byte) (*big.Int, error) {
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("Merkle root must be 32 bytes\n")
+	}
+	digest := TaggedHash("TapTweak", x[:], merkleRoot)
+	t := new(big.Int).SetBytes(digest[:])
+	if t.Cmp(secp256k1.Curve().Params().N) >= 0 {
+		return nil, fmt.Errorf("Tweak out of range\n")
+	}
+	return t, nil
+}
+
+// OutputKey returns the output key Q = P + t*G of the internal key, where P
+// is the internal key with even y. It is the public key of the private key
+// TweakPrivateKey returns.
+func OutputKey(internal *secp256k1.PublicKey, merkleRoot []byte) (*secp256k1.PublicKey, error) {
+	p, err := LiftX(XOnly(internal))
+	if err != nil {
+		return nil, err
+	}
+	t, err := tweak(XOnly(p), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	curve := secp256k1.FastCurve()
+	q := curve.FastBaseScalar(t.Bytes())
+	point := curve.NewPoint()
+	point.From(p.X, p.Y)
+	curve.FastPointAdd(q, q, point)
+	if q.IsZero() {
+		return nil, fmt.Errorf("Tweaked key is infinity\n")
+	}
+	x, y := q.Back()
+	return &secp256k1.PublicKey{X: x, Y: y}, nil
+}
+
+// TweakPublicKey returns the x-only output key of the internal key, and
+// whether it has odd y.
+func TweakPublicKey(internal *secp256k1.PublicKey, merkleRoot []byte) (output [32]byte, odd bool, err error) {
+	q, err := OutputKey(internal, merkleRoot)
+	if err != nil {
+		return output, false, err
+	}
+	return XOnly(q), q.Y.Bit(0) == 1, nil
+}
+
+// TweakPrivateKey returns the key of the output TweakPublicKey gives for the
+// public key of key. The secret is negated first if the internal key has odd
+// y, so the result matches the x-only output key.
+func TweakPrivateKey(key *secp256k1.PrivateKey, merkleRoot []byte) (*secp256k1.PrivateKey, error) {
+	N := secp256k1.Curve().Params().N
+	d := new(big.Int).Set(key.D)
+	if key.Y.Bit(0) == 1 {
+		d.Sub(N, d)
+	}
+	t, err := tweak(XOnly(&key.PublicKey), merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t)
+	d.Mod(d, N)
+	return secp256k1.NewPrivateKey(d)
+}
+
+// OutputScript returns the scriptPubKey of a Taproot output, OP_1 followed by
+// the 32-byte output key.
+func OutputScript(output [32]byte) []byte {
+	return append([]byte{0x51, 0x20}, output[:]...)
+}
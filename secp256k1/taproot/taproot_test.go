@@ -0,0 +1,87 @@
+package taproot_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"volley/secp256k1"
+	"volley/secp256k1/taproot"
+)
+
+func decodeHex32(t *testing.T, s string) [32]byte {
+	var b [32]byte
+	data, err := hex.DecodeString(s)
+	if err != nil || len(data) != 32 {
+		t.Fatal("bad test hex ", s)
+	}
+	copy(b[:], data)
+	return b
+}
+
+// From the scriptPubKey test vectors of BIP-341.
+func TestTweakPublicKey(t *testing.T) {
+	vectors := []struct {
+		internal   string
+		merkleRoot string
+		output     string
+	}{
+		{
+			"d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d",
+			"",
+			"53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+		},
+		{
+			"187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			"5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+			"147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+		},
+	}
+	for _, v := range vectors {
+		internal, err := taproot.LiftX(decodeHex32(t, v.internal))
+		if err != nil {
+			t.Fatal(err)
+		}
+		merkleRoot, _ := hex.DecodeString(v.merkleRoot)
+		output, _, err := taproot.TweakPublicKey(internal, merkleRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != decodeHex32(t, v.output) {
+			t.Fatalf("output key %x, want %s", output, v.output)
+		}
+	}
+}
+
+func TestTweakPrivateKey(t *testing.T) {
+	merkleRoot := make([]byte, 32)
+	merkleRoot[0] = 1
+	for i := 0; i < 8; i++ {
+		key, err := secp256k1.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := merkleRoot[:32*(i%2)]
+		output, odd, err := taproot.TweakPublicKey(&key.PublicKey, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tweaked, err := taproot.TweakPrivateKey(key, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taproot.XOnly(&tweaked.PublicKey) != output || (tweaked.Y.Bit(0) == 1) != odd {
+			t.Fatal("tweaked private key doesn't match the output key")
+		}
+		q, err := taproot.OutputKey(&key.PublicKey, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !q.Equal(&tweaked.PublicKey) {
+			t.Fatal("tweaked private key doesn't match the full output key")
+		}
+	}
+	if _, _, err := taproot.TweakPublicKey(&secp256k1.PublicKey{X: secp256k1.Curve().Params().Gx,
+		Y: secp256k1.Curve().Params().Gy}, make([]byte, 31)); err == nil {
+		t.Fatal("accepted a short merkle root")
+	}
+}
@@ -81,76 +81,93 @@ func scalarIsZero(scalar []uint64) bool {
 	return (scalar[0] | scalar[1] | scalar[2] | scalar[3]) == 0
 }
 
-// MarshalSig 将Signature转换为ASN1标准
+// MarshalSig 将Signature转换为ASN1标准, with minimal DER integers
 func MarshalSig(r, s *big.Int) []byte {
-	rBytes := r.Bytes()
-	sBytes := s.Bytes()
-	rLength := len(rBytes)
-	sLength := len(sBytes)
-	length := rLength + sLength + 4
-	if rBytes[0]&0x80 != 0 {
-		length++
-		rLength++
-	}
-	if sBytes[0]&0x80 != 0 {
-		length++
-		sLength++
-	}
-	sig := make([]byte, length+2)
-	sig[0] = 0x30
-	sig[1] = byte(length)
-	sig[2] = 0x02
-	sig[3] = byte(rLength)
-	offset := 4 + rLength - len(rBytes)
-	copy(sig[offset:], rBytes)
-	offset += len(rBytes)
-	sig[offset] = 0x02
-	sig[offset+1] = byte(sLength)
-	offset += 2 + sLength - len(sBytes)
-	copy(sig[offset:], sBytes)
-	return sig
+	rBytes := derInt(r)
+	sBytes := derInt(s)
+	sig := make([]byte, 0, 6+len(rBytes)+len(sBytes))
+	sig = append(sig, 0x30, byte(4+len(rBytes)+len(sBytes)))
+	sig = append(sig, 0x02, byte(len(rBytes)))
+	sig = append(sig, rBytes...)
+	sig = append(sig, 0x02, byte(len(sBytes)))
+	return append(sig, sBytes...)
 }
 
-// UnmarshalSig 对ASN1编码的签名进行解码
-func UnmarshalSig(sig []byte) (r, s *big.Int, err error) {
-	defer func() {
-		ex := recover()
-		if ex != nil {
-			s = nil
-			err = fmt.Errorf("Signature length error fatal\n")
-		}
-	}()
+// derInt returns the shortest two's complement encoding of a non-negative n.
+func derInt(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
 
+// UnmarshalSig 对ASN1编码的签名进行解码. The encoding must be strict DER as
+// required by BIP 66: no extra bytes, no padding of the integers beyond the
+// sign byte and no negative integers.
+func UnmarshalSig(sig []byte) (r, s *big.Int, err error) {
+	if len(sig) < 8 || len(sig) > 72 {
+		return nil, nil, fmt.Errorf("Signature length error\n")
+	}
 	if sig[0] != 0x30 {
 		return nil, nil, fmt.Errorf("Signature is not a sequence\n")
 	}
-	length := int(sig[1])
-	if length+2 != len(sig) {
+	if int(sig[1])+2 != len(sig) {
 		return nil, nil, fmt.Errorf("Signature length error\n")
 	}
-	if sig[2] != 0x02 {
-		return nil, nil, fmt.Errorf("R of signature is not an integer\n")
+	rBytes, rest, err := parseDERInt(sig[2:], "R")
+	if err != nil {
+		return nil, nil, err
 	}
-	rLength := int(sig[3])
-	rBytes := sig[4 : 4+rLength]
-	if (rBytes[0] & 0x80) != 0 {
-		return nil, nil, fmt.Errorf("R of signature is negative\n")
+	sBytes, rest, err := parseDERInt(rest, "S")
+	if err != nil {
+		return nil, nil, err
 	}
-	r = new(big.Int).SetBytes(rBytes)
-	offset := 4 + rLength
-	if sig[offset] != 0x02 {
-		return nil, nil, fmt.Errorf("S of signature is not an integer\n")
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("Signature length error\n")
 	}
-	sLength := int(sig[offset+1])
-	offset += 2
-	sBytes := sig[offset : offset+sLength]
-	if (sBytes[0] & 0x80) != 0 {
-		return nil, nil, fmt.Errorf("S of signature is negative\n")
+	return new(big.Int).SetBytes(rBytes), new(big.Int).SetBytes(sBytes), nil
+}
+
+// parseDERInt splits a minimal, non-negative DER integer of at most 33 bytes
+// off the front of data.
+func parseDERInt(data []byte, name string) (value, rest []byte, err error) {
+	if len(data) < 3 || data[0] != 0x02 {
+		return nil, nil, fmt.Errorf("%s of signature is not an integer\n", name)
 	}
-	s = new(big.Int).SetBytes(sBytes)
-	if offset+sLength != len(sig) {
+	length := int(data[1])
+	if length == 0 || length > 33 || 2+length > len(data) {
 		return nil, nil, fmt.Errorf("Signature length error\n")
 	}
+	value = data[2 : 2+length]
+	if value[0]&0x80 != 0 {
+		return nil, nil, fmt.Errorf("%s of signature is negative\n", name)
+	}
+	if length > 1 && value[0] == 0 && value[1]&0x80 == 0 {
+		return nil, nil, fmt.Errorf("%s of signature is not minimally encoded\n", name)
+	}
+	return value, data[2+length:], nil
+}
 
-	return
+// MarshalCompactSig encodes r and s as two 32-byte big-endian integers.
+func MarshalCompactSig(r, s *big.Int) []byte {
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig
+}
+
+// UnmarshalCompactSig decodes the output of MarshalCompactSig. Both values
+// must lie in [1, N-1].
+func UnmarshalCompactSig(sig []byte) (r, s *big.Int, err error) {
+	if len(sig) != 64 {
+		return nil, nil, fmt.Errorf("Compact signature must be 64 bytes\n")
+	}
+	N := p256k1Curve.params.N
+	r = new(big.Int).SetBytes(sig[:32])
+	s = new(big.Int).SetBytes(sig[32:])
+	if r.Sign() == 0 || r.Cmp(N) >= 0 || s.Sign() == 0 || s.Cmp(N) >= 0 {
+		return nil, nil, fmt.Errorf("Signature out of range\n")
+	}
+	return r, s, nil
 }
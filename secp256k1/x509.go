@@ -0,0 +1,289 @@
+package secp256k1
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// The standard x509 package only knows the NIST curves, so the SEC1, PKCS #8
+// and SubjectPublicKeyInfo structures are handled here with the secp256k1
+// OID. The encodings match the ones of OpenSSL.
+var (
+	oidNamedCurveSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidPublicKeyECDSA      = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+const ecPrivKeyVersion = 1
+
+// ecPrivateKey is the ECPrivateKey structure of RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// pkcs8 is the PrivateKeyInfo structure of RFC 5208.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalPublicKey returns the SEC1 encoding of pub, 33 bytes when compressed
+// and 65 bytes otherwise.
+func MarshalPublicKey(pub *ecdsa.PublicKey, compressed bool) []byte {
+	if compressed {
+		data := make([]byte, 33)
+		data[0] = 0x02 | byte(pub.Y.Bit(0))
+		pub.X.FillBytes(data[1:])
+		return data
+	}
+	data := make([]byte, 65)
+	data[0] = 0x04
+	pub.X.FillBytes(data[1:33])
+	pub.Y.FillBytes(data[33:])
+	return data
+}
+
+// UnmarshalPublicKey decodes a compressed or uncompressed SEC1 point,
+// checking that it is on the curve.
+func UnmarshalPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	switch {
+	case len(data) == 65 && data[0] == 0x04:
+		x := new(big.Int).SetBytes(data[1:33])
+		y := new(big.Int).SetBytes(data[33:])
+		if !p256k1Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("Public key not on curve\n")
+		}
+		return &ecdsa.PublicKey{Curve: p256k1Curve, X: x, Y: y}, nil
+	case len(data) == 33:
+		pt := p256k1Curve.NewPoint().(*Point)
+		if err := pt.UnmarshalCompressed(data); err != nil {
+			return nil, err
+		}
+		x, y := pt.Back()
+		return &ecdsa.PublicKey{Curve: p256k1Curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("Invalid public key encoding of %d bytes\n", len(data))
+}
+
+// MarshalECPrivateKey returns the SEC1 (RFC 5915) DER encoding of key, which
+// OpenSSL writes as "EC PRIVATE KEY".
+func MarshalECPrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	return marshalECPrivateKey(key, oidNamedCurveSecp256k1)
+}
+
+func marshalECPrivateKey(key *ecdsa.PrivateKey, oid asn1.ObjectIdentifier) ([]byte, error) {
+	if key.D.Sign() <= 0 || key.D.Cmp(p256k1Curve.params.N) >= 0 {
+		return nil, fmt.Errorf("Private key out of range\n")
+	}
+	x, y := p256k1Curve.ScalarBaseMult(key.D.Bytes())
+	public := MarshalPublicKey(&ecdsa.PublicKey{Curve: p256k1Curve, X: x, Y: y}, false)
+	return asn1.Marshal(ecPrivateKey{
+		Version:       ecPrivKeyVersion,
+		PrivateKey:    key.D.FillBytes(make([]byte, 32)),
+		NamedCurveOID: oid,
+		PublicKey:     asn1.BitString{Bytes: public, BitLength: 8 * len(public)},
+	})
+}
+
+// ParseECPrivateKey decodes a SEC1 private key. The curve, when given, must
+// be secp256k1, and a stored public key must match the secret.
+func ParseECPrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var priv ecPrivateKey
+	rest, err := asn1.Unmarshal(der, &priv)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("Trailing data after EC private key\n")
+	}
+	if priv.Version != ecPrivKeyVersion {
+		return nil, fmt.Errorf("Unknown EC private key version %d\n", priv.Version)
+	}
+	if len(priv.NamedCurveOID) != 0 && !priv.NamedCurveOID.Equal(oidNamedCurveSecp256k1) {
+		return nil, fmt.Errorf("EC private key is not on secp256k1 but %v\n", priv.NamedCurveOID)
+	}
+	if len(priv.PrivateKey) > 32 {
+		return nil, fmt.Errorf("EC private key length error: %d\n", len(priv.PrivateKey))
+	}
+	d := new(big.Int).SetBytes(priv.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(p256k1Curve.params.N) >= 0 {
+		return nil, fmt.Errorf("Private key out of range\n")
+	}
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = p256k1Curve
+	key.X, key.Y = p256k1Curve.ScalarBaseMult(d.Bytes())
+	if len(priv.PublicKey.Bytes) != 0 {
+		public, err := UnmarshalPublicKey(priv.PublicKey.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if public.X.Cmp(key.X) != 0 || public.Y.Cmp(key.Y) != 0 {
+			return nil, fmt.Errorf("Public key doesn't match secret\n")
+		}
+	}
+	return key, nil
+}
+
+func ecAlgorithm() (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(oidNamedCurveSecp256k1)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, nil
+}
+
+func checkECAlgorithm(algo pkix.AlgorithmIdentifier) error {
+	if !algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return fmt.Errorf("Key algorithm %v is not EC\n", algo.Algorithm)
+	}
+	var oid asn1.ObjectIdentifier
+	if rest, err := asn1.Unmarshal(algo.Parameters.FullBytes, &oid); err != nil || len(rest) != 0 {
+		return fmt.Errorf("EC key without a named curve\n")
+	}
+	if !oid.Equal(oidNamedCurveSecp256k1) {
+		return fmt.Errorf("EC key is not on secp256k1 but %v\n", oid)
+	}
+	return nil
+}
+
+// MarshalPKCS8PrivateKey returns the PKCS #8 DER encoding of key, which
+// OpenSSL writes as "PRIVATE KEY".
+func MarshalPKCS8PrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	algo, err := ecAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	// the curve is in the algorithm parameters already
+	inner, err := marshalECPrivateKey(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{Algo: algo, PrivateKey: inner})
+}
+
+// ParsePKCS8PrivateKey decodes an unencrypted PKCS #8 secp256k1 private key.
+func ParsePKCS8PrivateKey(der []byte) (*ecdsa.PrivateKey, error) {
+	var priv pkcs8
+	rest, err := asn1.Unmarshal(der, &priv)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("Trailing data after PKCS #8 private key\n")
+	}
+	if priv.Version != 0 {
+		return nil, fmt.Errorf("Unknown PKCS #8 version %d\n", priv.Version)
+	}
+	if err = checkECAlgorithm(priv.Algo); err != nil {
+		return nil, err
+	}
+	return ParseECPrivateKey(priv.PrivateKey)
+}
+
+// MarshalPKIXPublicKey returns the DER SubjectPublicKeyInfo of pub with an
+// uncompressed point.
+func MarshalPKIXPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	if !p256k1Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("Public key not on curve\n")
+	}
+	algo, err := ecAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	public := MarshalPublicKey(pub, false)
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{Bytes: public, BitLength: 8 * len(public)},
+	})
+}
+
+// ParsePKIXPublicKey decodes a DER SubjectPublicKeyInfo holding a compressed
+// or uncompressed secp256k1 point.
+func ParsePKIXPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var info publicKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("Trailing data after public key\n")
+	}
+	if err = checkECAlgorithm(info.Algorithm); err != nil {
+		return nil, err
+	}
+	return UnmarshalPublicKey(info.PublicKey.RightAlign())
+}
+
+// EncodePEMPrivateKey wraps the SEC1 encoding of key in an "EC PRIVATE KEY"
+// PEM block.
+func EncodePEMPrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodePEMPublicKey wraps the SubjectPublicKeyInfo of pub in a "PUBLIC KEY"
+// PEM block.
+func EncodePEMPublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// DecodePEMPrivateKey returns the first "EC PRIVATE KEY" or "PRIVATE KEY"
+// block of data. Other blocks, like the "EC PARAMETERS" that
+// openssl ecparam -genkey writes first, are skipped.
+func DecodePEMPrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("No private key PEM block\n")
+		}
+		switch block.Type {
+		case "EC PRIVATE KEY":
+			return ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			return ParsePKCS8PrivateKey(block.Bytes)
+		}
+	}
+}
+
+// DecodePEMPublicKey returns the first "PUBLIC KEY" block of data.
+func DecodePEMPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("No public key PEM block\n")
+		}
+		if block.Type == "PUBLIC KEY" {
+			return ParsePKIXPublicKey(block.Bytes)
+		}
+	}
+}
+
+// IsPEM reports whether data starts with a PEM block.
+func IsPEM(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN "))
+}
@@ -0,0 +1,80 @@
+package sm2
+
+import (
+	"sync"
+	vc "volley/curve"
+)
+
+// baseComb holds d * 16^w * G at [w][d], so k*G is a sum of one entry per
+// window without any doubling. Its 16 entries per window are all read for
+// every window by the secret base multiplication, FastBaseScalar uses the
+// larger NAF tables instead.
+var (
+	baseComb     *[64][16]point
+	baseCombOnce sync.Once
+)
+
+// secretTable fills table with 0*p, 1*p, ..., 15*p.
+func secretTable(table *[16]point, p *point) {
+	table[0].setInfinity()
+	table[1] = *p
+	for i := 2; i < 16; i++ {
+		pointAdd(&table[i], &table[i-1], p)
+	}
+}
+
+func loadBaseComb() *[64][16]point {
+	baseCombOnce.Do(func() {
+		table := new([64][16]point)
+		g := generator
+		entries := make([]*point, 0, 64*16)
+		for w := 0; w < 64; w++ {
+			secretTable(&table[w], &g)
+			for i := 0; i < 4; i++ {
+				pointDouble(&g, &g)
+			}
+			for d := range table[w] {
+				entries = append(entries, &table[w][d])
+			}
+		}
+		batchToAffine(entries, entries)
+		baseComb = table
+	})
+	return baseComb
+}
+
+// FastBaseScalarSecret is the constant-time counterpart of FastBaseScalar.
+func (c *curve) FastBaseScalarSecret(scalar []byte) vc.FastPoint {
+	var k [4]uint64
+	scalarToLimbs(&k, scalar)
+	table := loadBaseComb()
+	r := new(Point)
+	var tmp point
+	r.p.setInfinity()
+	for w := 0; w < 64; w++ {
+		selectPoint(&tmp, table[w][:], int(scalarWindow(&k, w)))
+		pointAdd(&r.p, &r.p, &tmp)
+	}
+	return r
+}
+
+// FastScalarMultSecret is the constant-time counterpart of FastScalarMult.
+// Only the scalar is protected, the point is assumed public.
+func (c *curve) FastScalarMultSecret(fpr, fp vc.FastPoint, scalar []byte) {
+	pr := fpr.(*Point)
+	var k [4]uint64
+	scalarToLimbs(&k, scalar)
+	var table [16]point
+	secretTable(&table, &fp.(*Point).p)
+	var acc, tmp point
+	acc.setInfinity()
+	for w := 63; w >= 0; w-- {
+		for i := 0; i < 4; i++ {
+			pointDouble(&acc, &acc)
+		}
+		selectPoint(&tmp, table[:], int(scalarWindow(&k, w)))
+		pointAdd(&acc, &acc, &tmp)
+	}
+	pr.p = acc
+	pr.clearTable()
+}
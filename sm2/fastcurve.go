@@ -0,0 +1,483 @@
+// Package sm2 implements curve.FastCurve over the SM2 curve of GB/T
+// 32918-2016: complete projective formulas over Montgomery field elements,
+// multiplied in assembly on amd64, the same 8-entry wNAF tables as the
+// secp256k1 package for points, and its Booth window NAF tables for the base
+// point, with a 4-bit comb for constant-time base multiplication.
+package sm2
+
+import (
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	vc "volley/curve"
+)
+
+type curve struct {
+	params *elliptic.CurveParams
+}
+
+var (
+	sm2Curve *curve
+	fp       *primeField
+	fn       *modulus
+	fieldB   [4]uint64
+	// generator is G with Z = 1
+	generator point
+)
+
+func init() {
+	params := &elliptic.CurveParams{Name: "SM2-P-256", BitSize: 256}
+	params.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+	params.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+	params.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+	params.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+	params.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+	sm2Curve = &curve{params: params}
+	fp = &primeField{*newModulus(params.P)}
+	fn = newModulus(params.N)
+	var b [4]uint64
+	fromBig(&b, params.B)
+	fp.toMont(&fieldB, &b)
+	generator.fromBig(params.Gx, params.Gy)
+}
+
+func FastCurve() vc.FastCurve {
+	return sm2Curve
+}
+
+func (p *point) fromBig(x, y *big.Int) {
+	fromBig(&p.x, x)
+	fromBig(&p.y, y)
+	fp.toMont(&p.x, &p.x)
+	fp.toMont(&p.y, &p.y)
+	p.z = fp.one
+}
+
+// toBig returns the affine coordinates of p, (0, 0) for infinity.
+func (p *point) toBig() (*big.Int, *big.Int) {
+	if p.isInfinity() {
+		return big.NewInt(0), big.NewInt(0)
+	}
+	var x, y, zInv [4]uint64
+	if p.isAffine() {
+		x, y = p.x, p.y
+	} else {
+		fp.inverse(&zInv, &p.z)
+		fp.mul(&x, &p.x, &zInv)
+		fp.mul(&y, &p.y, &zInv)
+	}
+	fp.fromMont(&x, &x)
+	fp.fromMont(&y, &y)
+	return toBig(&x), toBig(&y)
+}
+
+func (c *curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// polynomial returns x^3 - 3x + b.
+func (c *curve) polynomial(x *big.Int) *big.Int {
+	y := new(big.Int).Mul(x, x)
+	y.Mul(y, x)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y.Sub(y, threeX)
+	y.Add(y, c.params.B)
+	return y.Mod(y, c.params.P)
+}
+
+func (c *curve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.params.P) >= 0 || y.Sign() < 0 || y.Cmp(c.params.P) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, c.params.P)
+	return c.polynomial(x).Cmp(y2) == 0
+}
+
+// affinePoint decodes big.Int coordinates, taking (0, 0) as infinity like
+// crypto/elliptic.
+func affinePoint(x, y *big.Int) *point {
+	p := new(point)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		p.setInfinity()
+	} else {
+		p.fromBig(x, y)
+	}
+	return p
+}
+
+func (c *curve) Add(x1, y1, x2, y2 *big.Int) (x, y *big.Int) {
+	p := affinePoint(x1, y1)
+	pointAdd(p, p, affinePoint(x2, y2))
+	return p.toBig()
+}
+
+func (c *curve) Double(x1, y1 *big.Int) (x, y *big.Int) {
+	p := affinePoint(x1, y1)
+	pointDouble(p, p)
+	return p.toBig()
+}
+
+func (c *curve) ScalarMult(bigX, bigY *big.Int, scalar []byte) (x, y *big.Int) {
+	r := c.NewPoint().(*Point)
+	c.FastScalarMult(r, &Point{p: *affinePoint(bigX, bigY)}, scalar)
+	return r.Back()
+}
+
+func (c *curve) ScalarBaseMult(scalar []byte) (x, y *big.Int) {
+	return c.FastBaseScalar(scalar).Back()
+}
+
+type Point struct {
+	p     point
+	table *[8]point
+	lazy  *lazyTable
+}
+
+// lazyTable holds an exported table that is decoded on first use, see the
+// secp256k1 package.
+type lazyTable struct {
+	once   sync.Once
+	data   []byte
+	affine bool
+	table  *[8]point
+}
+
+func (lt *lazyTable) load() *[8]point {
+	lt.once.Do(func() {
+		lt.table = new([8]point)
+		decodeTable(lt.table, lt.data, lt.affine)
+		lt.data = nil
+	})
+	return lt.table
+}
+
+func (pt *Point) precomputed() *[8]point {
+	if pt.table == nil && pt.lazy != nil {
+		return pt.lazy.load()
+	}
+	return pt.table
+}
+
+func (pt *Point) clearTable() {
+	pt.table = nil
+	pt.lazy = nil
+}
+
+func (pt *Point) Back() (*big.Int, *big.Int) {
+	return pt.p.toBig()
+}
+
+func (pt *Point) From(x, y *big.Int) {
+	pt.p.fromBig(x, y)
+	pt.clearTable()
+}
+
+func (pt *Point) CopyFrom(p2 vc.FastPoint) {
+	q := p2.(*Point)
+	pt.p = q.p
+	pt.table = q.table
+	pt.lazy = q.lazy
+}
+
+func (pt *Point) IsZero() bool {
+	return pt.p.isInfinity()
+}
+
+func (pt *Point) Neg() {
+	pt.p.neg()
+	pt.clearTable()
+}
+
+// Equal compares the points without converting them to affine coordinates.
+func (pt *Point) Equal(p2 vc.FastPoint) bool {
+	q := p2.(*Point)
+	if pt.IsZero() || q.IsZero() {
+		return pt.IsZero() == q.IsZero()
+	}
+	var a, b [4]uint64
+	fp.mul(&a, &pt.p.x, &q.p.z)
+	fp.mul(&b, &q.p.x, &pt.p.z)
+	if a != b {
+		return false
+	}
+	fp.mul(&a, &pt.p.y, &q.p.z)
+	fp.mul(&b, &q.p.y, &pt.p.z)
+	return a == b
+}
+
+func (pt *Point) Double() {
+	pointDouble(&pt.p, &pt.p)
+	pt.clearTable()
+}
+
+// Sub sets pt = pt - p2.
+func (pt *Point) Sub(p2 vc.FastPoint) {
+	neg := p2.(*Point).p
+	neg.neg()
+	pointAdd(&pt.p, &pt.p, &neg)
+	pt.clearTable()
+}
+
+func (pt *Point) SetZero() {
+	pt.p.setInfinity()
+	pt.clearTable()
+}
+
+// MarshalCompressed returns the 33-byte SEC1 compressed encoding of pt, or
+// the single byte 0x00 for the point at infinity.
+func (pt *Point) MarshalCompressed() []byte {
+	if pt.IsZero() {
+		return []byte{0}
+	}
+	x, y := pt.Back()
+	data := make([]byte, 33)
+	data[0] = 0x02 | byte(y.Bit(0))
+	x.FillBytes(data[1:])
+	return data
+}
+
+// UnmarshalCompressed decodes the output of MarshalCompressed, checking that
+// the point is on the curve.
+func (pt *Point) UnmarshalCompressed(data []byte) error {
+	if len(data) == 1 && data[0] == 0 {
+		pt.SetZero()
+		return nil
+	}
+	if len(data) != 33 {
+		return fmt.Errorf("Invalid compressed point length %d\n", len(data))
+	}
+	if data[0] != 0x02 && data[0] != 0x03 {
+		return fmt.Errorf("Invalid compressed point prefix 0x%02x\n", data[0])
+	}
+	P := sm2Curve.params.P
+	x := new(big.Int).SetBytes(data[1:33])
+	if x.Cmp(P) >= 0 {
+		return fmt.Errorf("Compressed point x not below P\n")
+	}
+	y := sm2Curve.polynomial(x)
+	if y.ModSqrt(y, P) == nil {
+		return fmt.Errorf("Compressed point not on curve\n")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(P, y)
+	}
+	pt.From(x, y)
+	return nil
+}
+
+func (pt *Point) GenTable(affine bool) {
+	table := new([8]point)
+	oddMultiples(table, &pt.p)
+	if affine {
+		entries := make([]*point, 8)
+		for i := range table {
+			entries[i] = &table[i]
+		}
+		batchToAffine(entries, entries)
+	}
+	pt.table = table
+	pt.lazy = nil
+}
+
+// ExportTable writes X, Y and, unless affine is set, Z of every entry as
+// big-endian limbs, least significant limb first, like the secp256k1
+// package.
+func (pt *Point) ExportTable(affine bool) []byte {
+	table := pt.precomputed()
+	if table == nil {
+		pt.GenTable(affine)
+		table = pt.table
+	}
+	if affine {
+		for i := range table {
+			if !table[i].isAffine() {
+				pt.GenTable(true)
+				table = pt.table
+				break
+			}
+		}
+	}
+	size := 96
+	if affine {
+		size = 64
+	}
+	data := make([]byte, 8*size)
+	for i := range table {
+		entry := data[i*size:]
+		for j := 0; j < 4; j++ {
+			binary.BigEndian.PutUint64(entry[j*8:], table[i].x[j])
+			binary.BigEndian.PutUint64(entry[32+j*8:], table[i].y[j])
+			if !affine {
+				binary.BigEndian.PutUint64(entry[64+j*8:], table[i].z[j])
+			}
+		}
+	}
+	return data
+}
+
+func (pt *Point) ImportTable(precomputes []byte, affine bool) {
+	table := new([8]point)
+	decodeTable(table, precomputes, affine)
+	pt.table = table
+	pt.lazy = nil
+}
+
+// MapTable is the lazy counterpart of ImportTable: it keeps a reference to
+// precomputes and decodes the table the first time it is needed. The slice
+// must stay valid and unmodified for the lifetime of the point.
+func (pt *Point) MapTable(precomputes []byte, affine bool) {
+	pt.table = nil
+	pt.lazy = &lazyTable{
+		data:   precomputes,
+		affine: affine,
+	}
+}
+
+func decodeTable(table *[8]point, precomputes []byte, affine bool) {
+	size := 96
+	if affine {
+		size = 64
+	}
+	for i := range table {
+		entry := precomputes[i*size:]
+		for j := 0; j < 4; j++ {
+			table[i].x[j] = binary.BigEndian.Uint64(entry[j*8:])
+			table[i].y[j] = binary.BigEndian.Uint64(entry[32+j*8:])
+			if !affine {
+				table[i].z[j] = binary.BigEndian.Uint64(entry[64+j*8:])
+			}
+		}
+		if affine {
+			table[i].z = fp.one
+		}
+	}
+}
+
+func (c *curve) NewPoint() vc.FastPoint {
+	pt := new(Point)
+	pt.p.setInfinity()
+	return pt
+}
+
+func (c *curve) FastPointAdd(fpr, fp1, fp2 vc.FastPoint) {
+	pr := fpr.(*Point)
+	pointAdd(&pr.p, &fp1.(*Point).p, &fp2.(*Point).p)
+	pr.clearTable()
+}
+
+func (c *curve) FastBaseScalar(scalar []byte) vc.FastPoint {
+	var k [4]uint64
+	scalarToLimbs(&k, scalar)
+	r := new(Point)
+	loadNAFTables(BaseWindow()).baseMult(&r.p, &k)
+	return r
+}
+
+func (c *curve) FastScalarMult(fpr, fp vc.FastPoint, scalar []byte) {
+	pr := fpr.(*Point)
+	var table [8]point
+	oddMultiples(&table, &fp.(*Point).p)
+	wnafPolynomial(&pr.p, []*[8]point{&table}, [][]byte{scalar})
+	pr.clearTable()
+}
+
+// wnafPolynomial sets r to the sum of the scalars times the points whose odd
+// multiples are in tables, interleaving width-5 NAFs.
+func wnafPolynomial(r *point, tables []*[8]point, scalarList [][]byte) {
+	nafList := make([][257]int8, len(scalarList))
+	for i := range scalarList {
+		var k [4]uint64
+		scalarToLimbs(&k, scalarList[i])
+		nafList[i] = nonAdjacentForm(&k, 5)
+	}
+	var acc, tmp point
+	acc.setInfinity()
+	started := false
+	for i := 256; i >= 0; i-- {
+		if started {
+			pointDouble(&acc, &acc)
+		}
+		for n := range nafList {
+			v := nafList[n][i]
+			if v > 0 {
+				pointAdd(&acc, &acc, &tables[n][v/2])
+			} else if v < 0 {
+				tmp = tables[n][-v/2]
+				tmp.neg()
+				pointAdd(&acc, &acc, &tmp)
+			} else {
+				continue
+			}
+			started = true
+		}
+	}
+	*r = acc
+}
+
+func (c *curve) FastPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	tables := make([]*[8]point, len(scalarList))
+	for i := range scalarList {
+		tables[i] = new([8]point)
+		oddMultiples(tables[i], &pList[i].(*Point).p)
+	}
+	pr := result.(*Point)
+	wnafPolynomial(&pr.p, tables, scalarList)
+	pr.clearTable()
+}
+
+// FasterPolynomial is FastPolynomial using the tables of the points. The
+// complete formulas gain nothing from affine tables, so affine only matters
+// for points without a table yet.
+func (c *curve) FasterPolynomial(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte, affine bool) {
+	tables := make([]*[8]point, len(scalarList))
+	for i := range scalarList {
+		p := pList[i].(*Point)
+		if tables[i] = p.precomputed(); tables[i] == nil {
+			p.GenTable(affine)
+			tables[i] = p.table
+		}
+	}
+	pr := result.(*Point)
+	wnafPolynomial(&pr.p, tables, scalarList)
+	pr.clearTable()
+}
+
+// MultiScalarMult has no bucket method yet and interleaves NAFs like
+// FastPolynomial.
+func (c *curve) MultiScalarMult(result vc.FastPoint, pList []vc.FastPoint, scalarList [][]byte) {
+	c.FastPolynomial(result, pList, scalarList)
+}
+
+// affineCopies returns the points scaled to Z = 1 without touching pList,
+// which may hold the same point more than once.
+func affineCopies(pList []vc.FastPoint) []point {
+	points := make([]point, len(pList))
+	ptrs := make([]*point, len(pList))
+	for i := range pList {
+		points[i] = pList[i].(*Point).p
+		ptrs[i] = &points[i]
+	}
+	batchToAffine(ptrs, ptrs)
+	return points
+}
+
+// BatchNormalize scales every point to Z = 1 with a single inversion. The
+// tables stay valid.
+func (c *curve) BatchNormalize(pList []vc.FastPoint) {
+	for i, p := range affineCopies(pList) {
+		pList[i].(*Point).p = p
+	}
+}
+
+func (c *curve) BatchBack(pList []vc.FastPoint) ([]*big.Int, []*big.Int) {
+	xs := make([]*big.Int, len(pList))
+	ys := make([]*big.Int, len(pList))
+	for i, p := range affineCopies(pList) {
+		xs[i], ys[i] = p.toBig()
+	}
+	return xs, ys
+}
@@ -0,0 +1,152 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestCurveConstants(t *testing.T) {
+	params := sm2Curve.params
+	if !sm2Curve.IsOnCurve(params.Gx, params.Gy) {
+		t.Fatal("generator is not on the curve")
+	}
+	if !sm2Curve.FastBaseScalar(params.N.Bytes()).IsZero() {
+		t.Fatal("N*G is not the point at infinity")
+	}
+	r := sm2Curve.NewPoint()
+	sm2Curve.FastScalarMultSecret(r, sm2Curve.FastBaseScalar([]byte{1}), params.N.Bytes())
+	if !r.IsZero() {
+		t.Fatal("N*G is not the point at infinity")
+	}
+	// the generic crypto/elliptic code is an independent implementation
+	k, err := rand.Int(rand.Reader, params.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := params.ScalarBaseMult(k.Bytes())
+	gx, gy := sm2Curve.ScalarBaseMult(k.Bytes())
+	if gx.Cmp(x) != 0 || gy.Cmp(y) != 0 {
+		t.Fatal("ScalarBaseMult differs from crypto/elliptic")
+	}
+
+	for _, md := range []*modulus{&fp.modulus, fn} {
+		var a, inv, one [4]uint64
+		fromBig(&a, k)
+		md.reduceOnce(&a)
+		md.toMont(&a, &a)
+		md.inverse(&inv, &a)
+		md.mul(&one, &inv, &a)
+		if one != md.one {
+			t.Fatal("a * a^-1 != 1")
+		}
+	}
+	big1 := new(big.Int).Add(params.N, k).Bytes()
+	if !sm2Curve.FastBaseScalar(big1).Equal(sm2Curve.FastBaseScalar(k.Bytes())) {
+		t.Fatal("(N+k)*G != k*G")
+	}
+}
+
+// TestFieldMul checks the products modulo P, in assembly on amd64, against
+// math/big, with values next to 0 and P among the random ones.
+func TestFieldMul(t *testing.T) {
+	P := sm2Curve.params.P
+	rInv := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), 256), P)
+	values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), new(big.Int).Sub(P, big.NewInt(1)),
+		new(big.Int).Sub(P, big.NewInt(2)), new(big.Int).Rsh(P, 1), new(big.Int).Lsh(big.NewInt(1), 224)}
+	for i := 0; i < 64; i++ {
+		v, err := rand.Int(rand.Reader, P)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	for _, a := range values {
+		for _, b := range values {
+			var x, y, r [4]uint64
+			fromBig(&x, a)
+			fromBig(&y, b)
+			fieldMul(&r, &x, &y)
+			want := new(big.Int).Mul(a, b)
+			want.Mul(want, rInv)
+			want.Mod(want, P)
+			if toBig(&r).Cmp(want) != 0 {
+				t.Fatalf("fieldMul(%x, %x) = %x, want %x", a, b, toBig(&r), want)
+			}
+			if a == b {
+				fieldSqr(&r, &x)
+				if toBig(&r).Cmp(want) != 0 {
+					t.Fatalf("fieldSqr(%x) = %x, want %x", a, toBig(&r), want)
+				}
+			}
+		}
+		if a.Sign() == 0 {
+			continue
+		}
+		var x, inv, one [4]uint64
+		fromBig(&x, a)
+		fp.inverse(&inv, &x)
+		fp.mul(&one, &inv, &x)
+		if one != fp.one {
+			t.Fatalf("%x * %x^-1 != 1", a, a)
+		}
+	}
+}
+
+func TestNAFTables(t *testing.T) {
+	defer InitNAFTables(DefaultWindow)
+	params := sm2Curve.params
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	scalars := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), new(big.Int).Sub(params.N, big.NewInt(1)),
+		params.N, max, new(big.Int).Lsh(big.NewInt(1), 255)}
+	for i := 0; i < 16; i++ {
+		k, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scalars = append(scalars, k)
+	}
+	for w := 6; w <= 9; w++ {
+		InitNAFTables(w)
+		if BaseWindow() != w {
+			t.Fatalf("window %d not in use", w)
+		}
+		for _, k := range scalars {
+			x, y := params.ScalarBaseMult(k.Bytes())
+			gx, gy := sm2Curve.FastBaseScalar(k.Bytes()).Back()
+			if gx.Cmp(x) != 0 || gy.Cmp(y) != 0 {
+				t.Fatalf("window %d: FastBaseScalar(%x) differs from crypto/elliptic", w, k)
+			}
+		}
+	}
+	if WindowForMemory(NAFTablesSize(8)) != 8 || WindowForMemory(0) != 6 {
+		t.Fatal("WindowForMemory picked the wrong window")
+	}
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	k, _ := rand.Int(rand.Reader, sm2Curve.params.N)
+	kb := k.Bytes()
+	p := sm2Curve.FastBaseScalar(kb)
+	r := sm2Curve.NewPoint()
+	b.Run("base/fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sm2Curve.FastBaseScalar(kb)
+		}
+	})
+	b.Run("base/secret", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sm2Curve.FastBaseScalarSecret(kb)
+		}
+	})
+	b.Run("point/fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sm2Curve.FastScalarMult(r, p, kb)
+		}
+	})
+	b.Run("point/secret", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sm2Curve.FastScalarMultSecret(r, p, kb)
+		}
+	})
+}
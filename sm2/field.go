@@ -0,0 +1,205 @@
+package sm2
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// modulus holds the constants for Montgomery arithmetic modulo an odd 256-bit
+// m, with R = 2^256. All operations are branch-free; values are little-endian
+// limbs below m.
+type modulus struct {
+	m   [4]uint64
+	k0  uint64    // -m^-1 mod 2^64
+	rr  [4]uint64 // R^2 mod m
+	one [4]uint64 // R mod m
+}
+
+func newModulus(m *big.Int) *modulus {
+	md := new(modulus)
+	fromBig(&md.m, m)
+	// Newton iteration for m^-1 mod 2^64
+	inv := md.m[0]
+	for i := 0; i < 5; i++ {
+		inv *= 2 - md.m[0]*inv
+	}
+	md.k0 = -inv
+	r := new(big.Int).Lsh(big.NewInt(1), 256)
+	fromBig(&md.one, new(big.Int).Mod(r, m))
+	fromBig(&md.rr, new(big.Int).Mod(new(big.Int).Mul(r, r), m))
+	return md
+}
+
+// fromBig sets out to the low 256 bits of in.
+func fromBig(out *[4]uint64, in *big.Int) {
+	var buf [32]byte
+	new(big.Int).And(in, mask256).FillBytes(buf[:])
+	for i := 0; i < 4; i++ {
+		out[i] = beUint64(buf[24-8*i:])
+	}
+}
+
+func toBig(in *[4]uint64) *big.Int {
+	var buf [32]byte
+	for i := 0; i < 4; i++ {
+		bePutUint64(buf[24-8*i:], in[i])
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+var mask256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+func beUint64(b []byte) uint64 {
+	return uint64(b[7]) | uint64(b[6])<<8 | uint64(b[5])<<16 | uint64(b[4])<<24 |
+		uint64(b[3])<<32 | uint64(b[2])<<40 | uint64(b[1])<<48 | uint64(b[0])<<56
+}
+
+func bePutUint64(b []byte, v uint64) {
+	for i := 7; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
+
+// madd returns a*b + c + d as (hi, lo), which cannot overflow 128 bits.
+func madd(a, b, c, d uint64) (hi, lo uint64) {
+	var carry uint64
+	hi, lo = bits.Mul64(a, b)
+	lo, carry = bits.Add64(lo, c, 0)
+	hi += carry
+	lo, carry = bits.Add64(lo, d, 0)
+	hi += carry
+	return
+}
+
+// mul sets r = a * b * R^-1 mod m.
+func (md *modulus) mul(r, a, b *[4]uint64) {
+	var t0, t1, t2, t3, t4, t5, c, u uint64
+	for i := 0; i < 4; i++ {
+		c, t0 = madd(a[0], b[i], t0, 0)
+		c, t1 = madd(a[1], b[i], t1, c)
+		c, t2 = madd(a[2], b[i], t2, c)
+		c, t3 = madd(a[3], b[i], t3, c)
+		t4, t5 = bits.Add64(t4, c, 0)
+
+		u = t0 * md.k0
+		c, _ = madd(u, md.m[0], t0, 0)
+		c, t0 = madd(u, md.m[1], t1, c)
+		c, t1 = madd(u, md.m[2], t2, c)
+		c, t2 = madd(u, md.m[3], t3, c)
+		t3, c = bits.Add64(t4, c, 0)
+		t4 = t5 + c
+	}
+	md.reduce(r, &[4]uint64{t0, t1, t2, t3}, t4)
+}
+
+// reduce sets r = t - m if the 257-bit value (carry, t) is at least m, and
+// r = t otherwise.
+func (md *modulus) reduce(r, t *[4]uint64, carry uint64) {
+	var s [4]uint64
+	var borrow uint64
+	s[0], borrow = bits.Sub64(t[0], md.m[0], 0)
+	s[1], borrow = bits.Sub64(t[1], md.m[1], borrow)
+	s[2], borrow = bits.Sub64(t[2], md.m[2], borrow)
+	s[3], borrow = bits.Sub64(t[3], md.m[3], borrow)
+	// keep t only if it didn't overflow and subtracting m borrowed
+	mask := -(borrow &^ carry)
+	r[0] = t[0]&mask | s[0]&^mask
+	r[1] = t[1]&mask | s[1]&^mask
+	r[2] = t[2]&mask | s[2]&^mask
+	r[3] = t[3]&mask | s[3]&^mask
+}
+
+func (md *modulus) add(r, a, b *[4]uint64) {
+	var t [4]uint64
+	var carry uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	md.reduce(r, &t, carry)
+}
+
+func (md *modulus) sub(r, a, b *[4]uint64) {
+	var t [4]uint64
+	var borrow, carry uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	mask := -borrow
+	r[0], carry = bits.Add64(t[0], md.m[0]&mask, 0)
+	r[1], carry = bits.Add64(t[1], md.m[1]&mask, carry)
+	r[2], carry = bits.Add64(t[2], md.m[2]&mask, carry)
+	r[3], _ = bits.Add64(t[3], md.m[3]&mask, carry)
+}
+
+// reduceOnce brings any 256-bit value below m, which needs m > 2^255.
+func (md *modulus) reduceOnce(a *[4]uint64) {
+	md.reduce(a, a, 0)
+}
+
+func (md *modulus) toMont(r, a *[4]uint64) {
+	md.mul(r, a, &md.rr)
+}
+
+func (md *modulus) fromMont(r, a *[4]uint64) {
+	md.mul(r, a, &[4]uint64{1})
+}
+
+// inverse sets r = a^-1 in the Montgomery domain using Fermat's little
+// theorem, so the inverse of zero is zero. The exponent is public.
+func (md *modulus) inverse(r, a *[4]uint64) {
+	e := md.m
+	e[0] -= 2 // m is odd and far above 2, no borrow
+	x := *a
+	acc := md.one
+	for i := 255; i >= 0; i-- {
+		md.mul(&acc, &acc, &acc)
+		if (e[i/64]>>(i%64))&1 == 1 {
+			md.mul(&acc, &acc, &x)
+		}
+	}
+	*r = acc
+}
+
+// primeField is the modulus P of the coordinates. Its products go through
+// fieldMul and fieldSqr, in assembly on amd64.
+type primeField struct {
+	modulus
+}
+
+func (f *primeField) mul(r, a, b *[4]uint64) {
+	fieldMul(r, a, b)
+}
+
+func (f *primeField) sqr(r, a *[4]uint64) {
+	fieldSqr(r, a)
+}
+
+func (f *primeField) toMont(r, a *[4]uint64) {
+	fieldMul(r, a, &f.rr)
+}
+
+func (f *primeField) fromMont(r, a *[4]uint64) {
+	fieldMul(r, a, &[4]uint64{1})
+}
+
+// inverse is modulus.inverse with the faster products.
+func (f *primeField) inverse(r, a *[4]uint64) {
+	e := f.m
+	e[0] -= 2
+	x := *a
+	acc := f.one
+	for i := 255; i >= 0; i-- {
+		fieldSqr(&acc, &acc)
+		if (e[i/64]>>(i%64))&1 == 1 {
+			fieldMul(&acc, &acc, &x)
+		}
+	}
+	*r = acc
+}
+
+func isZero(a *[4]uint64) bool {
+	return a[0]|a[1]|a[2]|a[3] == 0
+}
@@ -0,0 +1,14 @@
+//go:build amd64
+// +build amd64
+
+package sm2
+
+// fieldMul sets res = in1 * in2 * R^-1 mod P, with inputs and output below P.
+//
+//go:noescape
+func fieldMul(res, in1, in2 *[4]uint64)
+
+// fieldSqr sets res = in * in * R^-1 mod P.
+//
+//go:noescape
+func fieldSqr(res, in *[4]uint64)
@@ -0,0 +1,120 @@
+//go:build amd64
+// +build amd64
+
+// Montgomery multiplication modulo the SM2 prime
+// P = 2^256 - 2^224 - 2^96 + 2^64 - 1, with R = 2^256. The product is
+// interleaved with the reduction one limb of in2 at a time, as in the
+// portable modulus.mul. P = -1 mod 2^64, so the factor of each reduction
+// step is the low accumulator limb itself.
+
+#include "textflag.h"
+
+#define res_ptr DI
+#define x_ptr SI
+#define y_ptr CX
+
+#define acc0 R8
+#define acc1 R9
+#define acc2 R10
+#define acc3 R11
+#define acc4 R12
+#define acc5 R13
+#define yi R14
+#define carry R15
+#define u BX
+
+#define P1 $0xffffffff00000000
+#define P3 $0xfffffffeffffffff
+
+// mulLimb adds x[j] * yi + carry to acc, leaving the high word in carry.
+#define mulLimb(j, acc) \
+	MOVQ (8*j)(x_ptr), AX;\
+	MULQ yi;\
+	ADDQ acc, AX;\
+	ADCQ $0, DX;\
+	ADDQ carry, AX;\
+	ADCQ $0, DX;\
+	MOVQ AX, acc;\
+	MOVQ DX, carry
+
+// redLimb adds u * p + carry to src and stores the low word to dst, one limb
+// down, leaving the high word in carry.
+#define redLimb(p, src, dst) \
+	MOVQ p, AX;\
+	MULQ u;\
+	ADDQ src, AX;\
+	ADCQ $0, DX;\
+	ADDQ carry, AX;\
+	ADCQ $0, DX;\
+	MOVQ AX, dst;\
+	MOVQ DX, carry
+
+// round adds x * y[i] to the accumulator and divides it by 2^64 modulo P.
+#define round(i) \
+	MOVQ (8*i)(y_ptr), yi;\
+	XORQ carry, carry;\
+	mulLimb(0, acc0);\
+	mulLimb(1, acc1);\
+	mulLimb(2, acc2);\
+	mulLimb(3, acc3);\
+	XORQ acc5, acc5;\
+	ADDQ carry, acc4;\
+	ADCQ $0, acc5;\
+	MOVQ acc0, u;\
+	XORQ carry, carry;\
+	redLimb($-1, acc0, acc0);\
+	redLimb(P1, acc1, acc0);\
+	redLimb($-1, acc2, acc1);\
+	redLimb(P3, acc3, acc2);\
+	MOVQ acc4, acc3;\
+	ADDQ carry, acc3;\
+	MOVQ acc5, acc4;\
+	ADCQ $0, acc4
+
+// montMul computes x * y * R^-1 and stores it to res_ptr, subtracting P
+// once if the result is not below it.
+#define montMul \
+	XORQ acc0, acc0;\
+	XORQ acc1, acc1;\
+	XORQ acc2, acc2;\
+	XORQ acc3, acc3;\
+	XORQ acc4, acc4;\
+	round(0);\
+	round(1);\
+	round(2);\
+	round(3);\
+	MOVQ acc0, AX;\
+	MOVQ acc1, BX;\
+	MOVQ acc2, CX;\
+	MOVQ acc3, DX;\
+	MOVQ P1, yi;\
+	MOVQ P3, carry;\
+	SUBQ $-1, AX;\
+	SBBQ yi, BX;\
+	SBBQ $-1, CX;\
+	SBBQ carry, DX;\
+	SBBQ $0, acc4;\
+	CMOVQCS acc0, AX;\
+	CMOVQCS acc1, BX;\
+	CMOVQCS acc2, CX;\
+	CMOVQCS acc3, DX;\
+	MOVQ AX, (8*0)(res_ptr);\
+	MOVQ BX, (8*1)(res_ptr);\
+	MOVQ CX, (8*2)(res_ptr);\
+	MOVQ DX, (8*3)(res_ptr)
+
+// func fieldMul(res, in1, in2 *[4]uint64)
+TEXT ·fieldMul(SB), NOSPLIT, $0-24
+	MOVQ res+0(FP), res_ptr
+	MOVQ in1+8(FP), x_ptr
+	MOVQ in2+16(FP), y_ptr
+	montMul
+	RET
+
+// func fieldSqr(res, in *[4]uint64)
+TEXT ·fieldSqr(SB), NOSPLIT, $0-16
+	MOVQ res+0(FP), res_ptr
+	MOVQ in+8(FP), x_ptr
+	MOVQ x_ptr, y_ptr
+	montMul
+	RET
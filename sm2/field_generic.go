@@ -0,0 +1,12 @@
+//go:build !amd64
+// +build !amd64
+
+package sm2
+
+func fieldMul(res, in1, in2 *[4]uint64) {
+	fp.modulus.mul(res, in1, in2)
+}
+
+func fieldSqr(res, in *[4]uint64) {
+	fp.modulus.mul(res, in, in)
+}
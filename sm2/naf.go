@@ -0,0 +1,157 @@
+package sm2
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultWindow is the window of the base point tables until InitNAFTables
+// picks another one.
+const DefaultWindow = 9
+
+// nafTables are the Booth window tables of G for one window w, laid out like
+// the ones of the secp256k1 package: entry j-1 of row i is j*2^(w*i)*G for j
+// from 1 to 2^(w-1). A base multiplication adds one signed entry per row and
+// doubles nothing.
+type nafTables struct {
+	window uint
+	rows   [][]affineEntry
+}
+
+// The base point tables are built on first use, once per window, so nothing
+// has to be initialized before multiplying.
+var (
+	baseTables     [4]*nafTables
+	baseTablesOnce [4]sync.Once
+)
+
+// baseWindow is the window picked by InitNAFTables, 0 until it is called.
+var baseWindow int32
+
+func nafRows(w int) int {
+	return 256/w + 1
+}
+
+func newNAFTables(w int) *nafTables {
+	size := 1 << (w - 1)
+	projective := make([]point, nafRows(w)*size)
+	entries := make([]*point, len(projective))
+	base := generator
+	for i := 0; i < nafRows(w); i++ {
+		row := projective[i*size : (i+1)*size]
+		row[0] = base
+		for j := 1; j < size; j++ {
+			pointAdd(&row[j], &row[j-1], &base)
+		}
+		for k := 0; k < w; k++ {
+			pointDouble(&base, &base)
+		}
+	}
+	for i := range projective {
+		entries[i] = &projective[i]
+	}
+	batchToAffine(entries, entries)
+
+	tables := &nafTables{window: uint(w), rows: make([][]affineEntry, nafRows(w))}
+	for i := range tables.rows {
+		tables.rows[i] = make([]affineEntry, size)
+		for j := range tables.rows[i] {
+			p := &projective[i*size+j]
+			tables.rows[i][j] = affineEntry{x: p.x, y: p.y}
+		}
+	}
+	return tables
+}
+
+func loadNAFTables(w int) *nafTables {
+	baseTablesOnce[w-6].Do(func() {
+		baseTables[w-6] = newNAFTables(w)
+	})
+	return baseTables[w-6]
+}
+
+// NAFTablesSize returns the memory taken by the base point tables of window
+// w in bytes, or 0 if w is not supported.
+func NAFTablesSize(w int) int {
+	if w < 6 || w > 9 {
+		return 0
+	}
+	return nafRows(w) << (w - 1) * 64
+}
+
+// WindowForMemory returns the largest window whose base point tables fit in
+// budget bytes, or 6 if none does.
+func WindowForMemory(budget int) int {
+	for w := 9; w > 6; w-- {
+		if NAFTablesSize(w) <= budget {
+			return w
+		}
+	}
+	return 6
+}
+
+// BaseWindow returns the window of the base point tables in use.
+func BaseWindow() int {
+	if w := atomic.LoadInt32(&baseWindow); w != 0 {
+		return int(w)
+	}
+	return DefaultWindow
+}
+
+// InitNAFTables builds the base point tables of window w, from 6 to 9, and
+// uses them from then on. Calling it is optional and safe from several
+// goroutines; without it the DefaultWindow tables are built on first use.
+// Tables of a window picked before stay in memory.
+func InitNAFTables(w int) {
+	if w < 6 || w > 9 {
+		panic(fmt.Sprintf("Unsupported NAF number %d", w))
+	}
+	loadNAFTables(w)
+	atomic.StoreInt32(&baseWindow, int32(w))
+}
+
+// boothWindow returns bits start-1 to start+w-1 of k, bit -1 being 0.
+func boothWindow(k *[4]uint64, start, w uint) uint {
+	mask := uint64(1)<<(w+1) - 1
+	if start == 0 {
+		return uint(k[0] << 1 & mask)
+	}
+	pos := start - 1
+	if pos >= 256 {
+		return 0
+	}
+	v := k[pos/64] >> (pos % 64)
+	if pos%64 != 0 && pos/64 < 3 {
+		v |= k[pos/64+1] << (64 - pos%64)
+	}
+	return uint(v & mask)
+}
+
+// boothDigit recodes a window of w+1 bits to a signed digit, returned as its
+// magnitude, at most 2^(w-1), and whether it is negative, like the boothW
+// functions of the secp256k1 package.
+func boothDigit(in, w uint) (uint, uint) {
+	s := ^((in >> w) - 1)
+	d := (uint(1) << (w + 1)) - in - 1
+	d = (d & s) | (in &^ s)
+	d = (d >> 1) + (d & 1)
+	return d, s & 1
+}
+
+// baseMult sets r = k*G.
+func (t *nafTables) baseMult(r *point, k *[4]uint64) {
+	r.setInfinity()
+	var entry affineEntry
+	for i, row := range t.rows {
+		d, negative := boothDigit(boothWindow(k, uint(i)*t.window, t.window), t.window)
+		if d == 0 {
+			continue
+		}
+		entry = row[d-1]
+		if negative == 1 {
+			fp.sub(&entry.y, &[4]uint64{}, &entry.y)
+		}
+		pointAddAffine(r, r, &entry)
+	}
+}
@@ -0,0 +1,219 @@
+package sm2
+
+import "crypto/subtle"
+
+// point is in homogeneous projective coordinates (X : Y : Z) with x = X/Z
+// and y = Y/Z, all in the Montgomery domain. The point at infinity is
+// (0 : 1 : 0). The complete formulas below handle every input, including
+// infinity and doubling, without branches.
+type point struct {
+	x, y, z [4]uint64
+}
+
+func (p *point) setInfinity() {
+	p.x = [4]uint64{}
+	p.y = fp.one
+	p.z = [4]uint64{}
+}
+
+func (p *point) isInfinity() bool {
+	return isZero(&p.z)
+}
+
+// isAffine reports whether p has Z = 1.
+func (p *point) isAffine() bool {
+	return p.z == fp.one
+}
+
+func (p *point) neg() {
+	fp.sub(&p.y, &[4]uint64{}, &p.y)
+}
+
+// pointAdd sets r = p + q using algorithm 4 of Renes, Costello and Batina,
+// "Complete addition formulas for prime order elliptic curves", for a = -3.
+// r may alias p or q.
+func pointAdd(r, p, q *point) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 [4]uint64
+	fp.mul(&t0, &p.x, &q.x)
+	fp.mul(&t1, &p.y, &q.y)
+	fp.mul(&t2, &p.z, &q.z)
+	fp.add(&t3, &p.x, &p.y)
+	fp.add(&t4, &q.x, &q.y)
+	fp.mul(&t3, &t3, &t4)
+	fp.add(&t4, &t0, &t1)
+	fp.sub(&t3, &t3, &t4)
+	fp.add(&t4, &p.y, &p.z)
+	fp.add(&x3, &q.y, &q.z)
+	fp.mul(&t4, &t4, &x3)
+	fp.add(&x3, &t1, &t2)
+	fp.sub(&t4, &t4, &x3)
+	fp.add(&x3, &p.x, &p.z)
+	fp.add(&y3, &q.x, &q.z)
+	fp.mul(&x3, &x3, &y3)
+	fp.add(&y3, &t0, &t2)
+	fp.sub(&y3, &x3, &y3)
+	fp.mul(&z3, &fieldB, &t2)
+	fp.sub(&x3, &y3, &z3)
+	fp.add(&z3, &x3, &x3)
+	fp.add(&x3, &x3, &z3)
+	fp.sub(&z3, &t1, &x3)
+	fp.add(&x3, &t1, &x3)
+	fp.mul(&y3, &fieldB, &y3)
+	fp.add(&t1, &t2, &t2)
+	fp.add(&t2, &t1, &t2)
+	fp.sub(&y3, &y3, &t2)
+	fp.sub(&y3, &y3, &t0)
+	fp.add(&t1, &y3, &y3)
+	fp.add(&y3, &t1, &y3)
+	fp.add(&t1, &t0, &t0)
+	fp.add(&t0, &t1, &t0)
+	fp.sub(&t0, &t0, &t2)
+	fp.mul(&t1, &t4, &y3)
+	fp.mul(&t2, &t0, &y3)
+	fp.mul(&y3, &x3, &z3)
+	fp.add(&y3, &y3, &t2)
+	fp.mul(&x3, &t3, &x3)
+	fp.sub(&x3, &x3, &t1)
+	fp.mul(&z3, &t4, &z3)
+	fp.mul(&t1, &t3, &t0)
+	fp.add(&z3, &z3, &t1)
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+// affineEntry is a table entry with Z = 1 left out, never infinity.
+type affineEntry struct {
+	x, y [4]uint64
+}
+
+// pointAddAffine sets r = p + q, algorithm 4 with Z2 = 1, which is algorithm 5
+// of the same paper. r may alias p.
+func pointAddAffine(r, p *point, q *affineEntry) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 [4]uint64
+	fp.mul(&t0, &p.x, &q.x)
+	fp.mul(&t1, &p.y, &q.y)
+	t2 = p.z
+	fp.add(&t3, &p.x, &p.y)
+	fp.add(&t4, &q.x, &q.y)
+	fp.mul(&t3, &t3, &t4)
+	fp.add(&t4, &t0, &t1)
+	fp.sub(&t3, &t3, &t4)
+	fp.mul(&t4, &q.y, &p.z)
+	fp.add(&t4, &t4, &p.y)
+	fp.mul(&y3, &q.x, &p.z)
+	fp.add(&y3, &y3, &p.x)
+	fp.mul(&z3, &fieldB, &t2)
+	fp.sub(&x3, &y3, &z3)
+	fp.add(&z3, &x3, &x3)
+	fp.add(&x3, &x3, &z3)
+	fp.sub(&z3, &t1, &x3)
+	fp.add(&x3, &t1, &x3)
+	fp.mul(&y3, &fieldB, &y3)
+	fp.add(&t1, &t2, &t2)
+	fp.add(&t2, &t1, &t2)
+	fp.sub(&y3, &y3, &t2)
+	fp.sub(&y3, &y3, &t0)
+	fp.add(&t1, &y3, &y3)
+	fp.add(&y3, &t1, &y3)
+	fp.add(&t1, &t0, &t0)
+	fp.add(&t0, &t1, &t0)
+	fp.sub(&t0, &t0, &t2)
+	fp.mul(&t1, &t4, &y3)
+	fp.mul(&t2, &t0, &y3)
+	fp.mul(&y3, &x3, &z3)
+	fp.add(&y3, &y3, &t2)
+	fp.mul(&x3, &t3, &x3)
+	fp.sub(&x3, &x3, &t1)
+	fp.mul(&z3, &t4, &z3)
+	fp.mul(&t1, &t3, &t0)
+	fp.add(&z3, &z3, &t1)
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+// pointDouble sets r = 2p using algorithm 6 of the same paper. r may alias p.
+func pointDouble(r, p *point) {
+	var t0, t1, t2, t3, x3, y3, z3 [4]uint64
+	fp.sqr(&t0, &p.x)
+	fp.sqr(&t1, &p.y)
+	fp.sqr(&t2, &p.z)
+	fp.mul(&t3, &p.x, &p.y)
+	fp.add(&t3, &t3, &t3)
+	fp.mul(&z3, &p.x, &p.z)
+	fp.add(&z3, &z3, &z3)
+	fp.mul(&y3, &fieldB, &t2)
+	fp.sub(&y3, &y3, &z3)
+	fp.add(&x3, &y3, &y3)
+	fp.add(&y3, &x3, &y3)
+	fp.sub(&x3, &t1, &y3)
+	fp.add(&y3, &t1, &y3)
+	fp.mul(&y3, &x3, &y3)
+	fp.mul(&x3, &x3, &t3)
+	fp.add(&t3, &t2, &t2)
+	fp.add(&t2, &t2, &t3)
+	fp.mul(&z3, &fieldB, &z3)
+	fp.sub(&z3, &z3, &t2)
+	fp.sub(&z3, &z3, &t0)
+	fp.add(&t3, &z3, &z3)
+	fp.add(&z3, &z3, &t3)
+	fp.add(&t3, &t0, &t0)
+	fp.add(&t0, &t3, &t0)
+	fp.sub(&t0, &t0, &t2)
+	fp.mul(&t0, &t0, &z3)
+	fp.add(&y3, &y3, &t0)
+	fp.mul(&t0, &p.y, &p.z)
+	fp.add(&t0, &t0, &t0)
+	fp.mul(&z3, &t0, &z3)
+	fp.sub(&x3, &x3, &z3)
+	fp.mul(&z3, &t0, &t1)
+	fp.add(&z3, &z3, &z3)
+	fp.add(&z3, &z3, &z3)
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+// batchToAffine scales every point of in to Z = 1 with a single inversion
+// and writes the result to out, which may alias in. Infinity is left alone.
+func batchToAffine(out, in []*point) {
+	prefix := make([][4]uint64, len(in))
+	acc := fp.one
+	for i, p := range in {
+		prefix[i] = acc
+		if !p.isInfinity() {
+			fp.mul(&acc, &acc, &p.z)
+		}
+	}
+	fp.inverse(&acc, &acc)
+	for i := len(in) - 1; i >= 0; i-- {
+		p := in[i]
+		if p.isInfinity() {
+			*out[i] = *p
+			continue
+		}
+		var zInv [4]uint64
+		fp.mul(&zInv, &acc, &prefix[i])
+		fp.mul(&acc, &acc, &p.z)
+		fp.mul(&out[i].x, &p.x, &zInv)
+		fp.mul(&out[i].y, &p.y, &zInv)
+		out[i].z = fp.one
+	}
+}
+
+// oddMultiples fills table with p, 3p, ..., 15p.
+func oddMultiples(table *[8]point, p *point) {
+	var twice point
+	pointDouble(&twice, p)
+	table[0] = *p
+	for i := 1; i < 8; i++ {
+		pointAdd(&table[i], &table[i-1], &twice)
+	}
+}
+
+// selectPoint sets r = table[i] for a secret i, reading every entry.
+func selectPoint(r *point, table []point, i int) {
+	for j := range table {
+		mask := -uint64(subtle.ConstantTimeEq(int32(j), int32(i)))
+		for k := 0; k < 4; k++ {
+			r.x[k] = r.x[k]&^mask | table[j].x[k]&mask
+			r.y[k] = r.y[k]&^mask | table[j].y[k]&mask
+			r.z[k] = r.z[k]&^mask | table[j].z[k]&mask
+		}
+	}
+}
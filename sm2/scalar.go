@@ -0,0 +1,197 @@
+package sm2
+
+import (
+	"math/big"
+	vc "volley/curve"
+)
+
+// Bn is an element of the scalar field in the Montgomery domain.
+type Bn struct {
+	data [4]uint64
+}
+
+// scalarToLimbs converts a big-endian scalar to little-endian limbs. Inputs
+// longer than 32 bytes are reduced modulo N.
+func scalarToLimbs(out *[4]uint64, in []byte) {
+	if len(in) > 32 {
+		fromBig(out, new(big.Int).Mod(new(big.Int).SetBytes(in), sm2Curve.params.N))
+		return
+	}
+	var buf [32]byte
+	copy(buf[32-len(in):], in)
+	for i := 0; i < 4; i++ {
+		out[i] = beUint64(buf[24-8*i:])
+	}
+}
+
+// scalarWindow returns the w-th 4-bit window of k.
+func scalarWindow(k *[4]uint64, w int) uint64 {
+	return k[w/16] >> (uint(w%16) * 4) & 15
+}
+
+// nonAdjacentForm returns the width-w NAF of k, least significant digit
+// first. It follows the curve25519-dalek implementation, like the one in the
+// secp256k1 package.
+func nonAdjacentForm(k *[4]uint64, w uint) [257]int8 {
+	var naf [257]int8
+	digits := [5]uint64{k[0], k[1], k[2], k[3], 0}
+	width := uint64(1) << w
+	windowMask := width - 1
+
+	pos := uint(0)
+	carry := uint64(0)
+	for pos < 257 {
+		indexU64 := pos / 64
+		indexBit := pos % 64
+		var bitBuf uint64
+		if indexBit < 64-w {
+			bitBuf = digits[indexU64] >> indexBit
+		} else {
+			bitBuf = (digits[indexU64] >> indexBit) | (digits[1+indexU64] << (64 - indexBit))
+		}
+
+		window := carry + (bitBuf & windowMask)
+		if window&1 == 0 {
+			pos++
+			continue
+		}
+		if window < width/2 {
+			carry = 0
+			naf[pos] = int8(window)
+		} else {
+			carry = 1
+			naf[pos] = int8(window) - int8(width)
+		}
+		pos += w
+	}
+	return naf
+}
+
+func (c *curve) NewBn() vc.FastBn {
+	return &Bn{data: fn.one}
+}
+
+func (bn *Bn) From(n *big.Int) {
+	var t [4]uint64
+	fromBig(&t, new(big.Int).Mod(n, sm2Curve.params.N))
+	fn.toMont(&bn.data, &t)
+}
+
+func (bn *Bn) Back() []byte {
+	var t [4]uint64
+	fn.fromMont(&t, &bn.data)
+	data := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		bePutUint64(data[24-8*i:], t[i])
+	}
+	return data
+}
+
+func (bn *Bn) CopyFrom(b vc.FastBn) {
+	bn.data = b.(*Bn).data
+}
+
+// Big returns the value of bn as a new big.Int.
+func (bn *Bn) Big() *big.Int {
+	return new(big.Int).SetBytes(bn.Back())
+}
+
+func (bn *Bn) SetInt64(v int64) vc.FastBn {
+	u := uint64(v)
+	if v < 0 {
+		u = uint64(-v)
+	}
+	fn.toMont(&bn.data, &[4]uint64{u})
+	if v < 0 {
+		fn.sub(&bn.data, &[4]uint64{}, &bn.data)
+	}
+	return bn
+}
+
+// SetBytes sets bn to the big-endian value in, reduced modulo N.
+func (bn *Bn) SetBytes(in []byte) vc.FastBn {
+	var t [4]uint64
+	scalarToLimbs(&t, in)
+	fn.reduceOnce(&t)
+	fn.toMont(&bn.data, &t)
+	return bn
+}
+
+func (bn *Bn) Add(a, b vc.FastBn) vc.FastBn {
+	fn.add(&bn.data, &a.(*Bn).data, &b.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Sub(a, b vc.FastBn) vc.FastBn {
+	fn.sub(&bn.data, &a.(*Bn).data, &b.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Neg(a vc.FastBn) vc.FastBn {
+	fn.sub(&bn.data, &[4]uint64{}, &a.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Mul(a, b vc.FastBn) vc.FastBn {
+	fn.mul(&bn.data, &a.(*Bn).data, &b.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) Square(a vc.FastBn) vc.FastBn {
+	fn.mul(&bn.data, &a.(*Bn).data, &a.(*Bn).data)
+	return bn
+}
+
+// Inverse sets bn to a^-1 mod N. The inverse of zero is zero.
+func (bn *Bn) Inverse(a vc.FastBn) vc.FastBn {
+	fn.inverse(&bn.data, &a.(*Bn).data)
+	return bn
+}
+
+func (bn *Bn) IsZero() bool {
+	return isZero(&bn.data)
+}
+
+func (bn *Bn) Equal(b vc.FastBn) bool {
+	d := &b.(*Bn).data
+	return (bn.data[0]^d[0])|(bn.data[1]^d[1])|(bn.data[2]^d[2])|(bn.data[3]^d[3]) == 0
+}
+
+// BatchInverse replaces every element of list by its inverse using
+// Montgomery's trick. Zero elements are left as zero.
+func (c *curve) BatchInverse(list []vc.FastBn) {
+	if len(list) == 0 {
+		return
+	}
+	prefix := make([][4]uint64, len(list))
+	acc := fn.one
+	for i, b := range list {
+		prefix[i] = acc
+		d := &b.(*Bn).data
+		if !isZero(d) {
+			fn.mul(&acc, &acc, d)
+		}
+	}
+	fn.inverse(&acc, &acc)
+	for i := len(list) - 1; i >= 0; i-- {
+		d := &list[i].(*Bn).data
+		if isZero(d) {
+			continue
+		}
+		var inv [4]uint64
+		fn.mul(&inv, &acc, &prefix[i])
+		fn.mul(&acc, &acc, d)
+		*d = inv
+	}
+}
+
+func (c *curve) FastOrderMul(r, a, b vc.FastBn) {
+	r.Mul(a, b)
+}
+
+// Inverse returns in^-1 mod N, or zero for zero.
+func (c *curve) Inverse(in *big.Int) *big.Int {
+	bn := new(Bn)
+	bn.From(in)
+	return bn.Inverse(bn).(*Bn).Big()
+}
@@ -0,0 +1,45 @@
+package sm3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// vectors from GB/T 32905-2016, appendix A
+var vectors = []struct {
+	in, out string
+}{
+	{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+}
+
+func TestSM3(t *testing.T) {
+	for _, v := range vectors {
+		want, _ := hex.DecodeString(v.out)
+		sum := Sum([]byte(v.in))
+		if !bytes.Equal(sum[:], want) {
+			t.Fatalf("Sum(%q) = %x, want %s", v.in, sum, v.out)
+		}
+		// feed the input byte by byte to exercise the buffering
+		h := New()
+		for i := 0; i < len(v.in); i++ {
+			h.Write([]byte{v.in[i]})
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Fatalf("incremental %q = %x, want %s", v.in, got, v.out)
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Fatal("Sum changed the state")
+		}
+	}
+}
+
+func BenchmarkSM3(b *testing.B) {
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		Sum(data)
+	}
+}
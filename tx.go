@@ -0,0 +1,51 @@
+package main
+
+import (
+	"volley/protocol"
+	"volley/secp256k1"
+	"volley/secp256k1/taproot"
+)
+
+// Amounts, in satoshi, of the two payments of a protocol run.
+const (
+	escrowAmount = 100000
+	paymentFee   = 500
+)
+
+// spendDigest returns the BIP-341 key path signature hash of a transaction
+// spending the Taproot output of payer to a Taproot output of payee. The
+// funding outpoint is derived from funding, since a protocol run doesn't
+// look at a chain.
+func spendDigest(payerPath, payeePath, funding string) ([]byte, error) {
+	payer, err := taprootScript(payerPath)
+	if err != nil {
+		return nil, err
+	}
+	payee, err := taprootScript(payeePath)
+	if err != nil {
+		return nil, err
+	}
+	tx := &taproot.Transaction{
+		Version: 2,
+		Inputs: []taproot.Input{{
+			PrevOut:  taproot.OutPoint{Hash: taproot.TaggedHash("volley/funding", []byte(funding))},
+			Sequence: 0xffffffff,
+		}},
+		Outputs: []taproot.Output{{Value: escrowAmount - paymentFee, Script: payee}},
+	}
+	prevouts := []taproot.Output{{Value: escrowAmount, Script: payer}}
+	digest, err := taproot.KeySpendSigHash(tx, prevouts, 0, taproot.SigHashDefault)
+	return digest[:], err
+}
+
+// taprootScript returns the scriptPubKey of the key path only Taproot output
+// of the public key in path. LoadPublicKey already returns the output key, the
+// one Step1y and Step3 sign with, since main sets protocol.SetTaprootKeys.
+func taprootScript(path string) ([]byte, error) {
+	point, err := protocol.LoadPublicKey(path)
+	if err != nil {
+		return nil, err
+	}
+	x, y := point.Back()
+	return taproot.OutputScript(taproot.XOnly(&secp256k1.PublicKey{X: x, Y: y})), nil
+}